@@ -33,10 +33,14 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/olekukonko/tablewriter"
 	"gopkg.in/urfave/cli.v1"
@@ -72,6 +76,7 @@ Remove blockchain and state databases`,
 			dbImportCmd,
 			dbExportCmd,
 			dbMetadataCmd,
+			dbBuildLogIndexCmd,
 		},
 	}
 	dbInspectCmd = cli.Command{
@@ -251,6 +256,24 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		},
 		Description: "Shows metadata about the chain status.",
 	}
+	dbBuildLogIndexCmd = cli.Command{
+		Action: utils.MigrateFlags(buildLogIndex),
+		Name:   "build-logindex",
+		Usage:  "Builds the inverted log index of the chain",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.RopstenFlag,
+			utils.SepoliaFlag,
+			utils.RinkebyFlag,
+			utils.GoerliFlag,
+		},
+		Description: `This command builds the inverted address and topic index of the logs
+of the blocks already in the database, without the disk throttling of a running
+node. The node must be stopped. Once built, the index is kept up to date by the
+node started with --logindex.`,
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -750,3 +773,79 @@ func showMetaData(ctx *cli.Context) error {
 	table.Render()
 	return nil
 }
+
+// logIndexStallTimeout is the time after which building the log index without
+// progress is considered failed, the indexer not retrying failed sections.
+const logIndexStallTimeout = time.Minute
+
+func buildLogIndex(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	head := rawdb.ReadHeadHeader(db)
+	if head == nil {
+		return errors.New("no chain head in the database")
+	}
+	var target uint64
+	if number := head.Number.Uint64(); number >= params.BloomConfirms {
+		target = (number + 1 - params.BloomConfirms) / params.BloomBitsBlocks
+	}
+	indexer := core.NewLogIndexer(db, params.BloomBitsBlocks, params.BloomConfirms, 0)
+	defer indexer.Close()
+	indexer.Start(&staticChain{head: head})
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	var (
+		start    = time.Now()
+		logged   = time.Now()
+		progress = time.Now()
+		last     uint64
+		ticker   = time.NewTicker(100 * time.Millisecond)
+	)
+	defer ticker.Stop()
+	for {
+		sections, _, _ := indexer.Sections()
+		if sections >= target {
+			log.Info("Built log index", "sections", sections, "blocks", sections*params.BloomBitsBlocks, "elapsed", common.PrettyDuration(time.Since(start)))
+			return nil
+		}
+		if sections != last {
+			last, progress = sections, time.Now()
+		} else if time.Since(progress) > logIndexStallTimeout {
+			return fmt.Errorf("log index stalled at section %d of %d", sections, target)
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Building log index", "sections", sections, "total", target, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		select {
+		case <-interrupt:
+			log.Info("Interrupted building log index", "sections", sections, "total", target)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// staticChain is the chain of a database not being updated, feeding the head to
+// a chain indexer.
+type staticChain struct {
+	head *types.Header
+}
+
+func (c *staticChain) CurrentHeader() *types.Header {
+	return c.head
+}
+
+func (c *staticChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
@@ -91,6 +91,8 @@ var (
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolRemoteJournalFlag,
+		utils.TxPoolRemoteRejournalFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -98,11 +100,18 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolSenderRateFlag,
+		utils.TxPoolSenderBurstFlag,
+		utils.TxPoolPeerRateFlag,
+		utils.TxPoolPeerBurstFlag,
+		utils.TxPoolRatePenaltyFlag,
 		utils.SyncModeFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
+		utils.LogIndexFlag,
+		utils.BalanceHistoryFlag,
 		utils.LightServeFlag,
 		utils.LightIngressFlag,
 		utils.LightEgressFlag,
@@ -136,6 +145,8 @@ var (
 		utils.MinerEtherbaseFlag,
 		utils.MinerExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
+		utils.MinerTxOrderingFlag,
+		utils.MinerTxSenderCapFlag,
 		utils.MinerNoVerifyFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
@@ -177,6 +188,7 @@ var (
 		utils.GraphQLVirtualHostsFlag,
 		utils.HTTPApiFlag,
 		utils.HTTPPathPrefixFlag,
+		utils.HTTPStreamFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
@@ -189,6 +201,14 @@ var (
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalEVMTimeoutFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.RPCAPIKeysFlag,
+		utils.RPCAPIKeysRequiredFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCRateLimitMethodsFlag,
+		utils.RPCRateLimitKeyFlag,
+		utils.RPCBatchLimitFlag,
+		utils.RPCResponseLimitFlag,
+		utils.RPCMethodTimeoutsFlag,
 		utils.AllowUnprotectedTxs,
 	}
 
@@ -218,8 +238,11 @@ var (
 		utils.NonceLimit,
 		utils.UseRocksDb,
 		utils.PrefetchCount,
+		utils.ParallelExecCount,
 		utils.LogFlag,
 		utils.MaxTxsPerBlock,
+		utils.PartnerTxExGossip,
+		utils.PartnerTxExSampleRate,
 		utils.Hub,
 		utils.BlockInterval,
 		utils.BlockTimeAdjBlocks,
@@ -266,6 +289,8 @@ func init() {
 		// See snapshot.go
 		snapshotCommand,
 		wemixCommand,
+		// See tracecmd.go
+		traceCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
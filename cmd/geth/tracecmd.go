@@ -0,0 +1,290 @@
+// tracecmd.go
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	traceFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block to trace",
+		Value: 1,
+	}
+	traceToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block to trace (default = head block)",
+	}
+	traceTracerFlag = cli.StringFlag{
+		Name:  "tracer",
+		Usage: "Tracer to run, a native or built-in JavaScript tracer",
+		Value: "callTracer",
+	}
+	traceOutFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "Directory to write the trace files to",
+		Value: "traces",
+	}
+	traceRangeFlag = cli.Uint64Flag{
+		Name:  "range",
+		Usage: "Number of blocks per trace file",
+		Value: 1000,
+	}
+	traceWorkersFlag = cli.IntFlag{
+		Name:  "workers",
+		Usage: "Number of ranges traced in parallel",
+		Value: runtime.NumCPU(),
+	}
+	traceReexecFlag = cli.Uint64Flag{
+		Name:  "reexec",
+		Usage: "Max number of blocks reexecuted to regenerate the state of a range",
+		Value: 128,
+	}
+	traceTimeoutFlag = cli.StringFlag{
+		Name:  "timeout",
+		Usage: "Timeout of the trace of a transaction",
+		Value: "5s",
+	}
+
+	traceCommand = cli.Command{
+		Name:      "trace",
+		Usage:     "Offline block tracing commands",
+		ArgsUsage: "",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:   "export",
+				Usage:  "Trace a range of blocks and export the traces to files",
+				Action: utils.MigrateFlags(exportTraces),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.CacheFlag,
+					utils.GCModeFlag,
+					utils.MainnetFlag,
+					utils.WemixTestnetFlag,
+					traceFromFlag,
+					traceToFlag,
+					traceTracerFlag,
+					traceOutFlag,
+					traceRangeFlag,
+					traceWorkersFlag,
+					traceReexecFlag,
+					traceTimeoutFlag,
+				},
+				Description: `
+    gwemix trace export --from <block> --to <block> --tracer callTracer --out <dir>
+
+Traces the blocks in the given range from the database, opened read-only, and
+writes the traces to gzipped JSON lines files, one per range of --range blocks,
+one line per block in the format of debug_traceChain. The files are named
+traces-<tracer>-<first block>-<last block>.jsonl.gz, the tracer being named by
+the hash of its code if it is not a built-in one. Ranges are traced in
+parallel, each on a state regenerated by reexecuting at most --reexec blocks,
+so the state of the blocks the ranges start from, or of recent enough
+ancestors, has to be in the database, which an archive node guarantees.
+
+A range file is only written once the range is traced. The files of the same
+tracer already in the output directory are skipped, so an interrupted export
+resumes where it left off when run again.
+`,
+			},
+		},
+	}
+)
+
+// exportTraces traces ranges of blocks in parallel, and writes the traces of
+// each range to a file.
+func exportTraces(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack, true)
+
+	var (
+		from    = ctx.Uint64(traceFromFlag.Name)
+		to      = chain.CurrentBlock().NumberU64()
+		size    = ctx.Uint64(traceRangeFlag.Name)
+		workers = ctx.Int(traceWorkersFlag.Name)
+		out     = ctx.String(traceOutFlag.Name)
+		tracer  = ctx.String(traceTracerFlag.Name)
+		reexec  = ctx.Uint64(traceReexecFlag.Name)
+		timeout = ctx.String(traceTimeoutFlag.Name)
+	)
+	if ctx.IsSet(traceToFlag.Name) {
+		if last := ctx.Uint64(traceToFlag.Name); last > to {
+			utils.Fatalf("Block %d is past the head block %d", last, to)
+		} else {
+			to = last
+		}
+	}
+	if from > to {
+		utils.Fatalf("Empty block range %d-%d", from, to)
+	}
+	if size == 0 || workers <= 0 {
+		utils.Fatalf("Invalid --%s or --%s", traceRangeFlag.Name, traceWorkersFlag.Name)
+	}
+	if err := os.MkdirAll(out, 0755); err != nil {
+		utils.Fatalf("Failed to create output directory: %v", err)
+	}
+	exporter := tracers.NewRangeTracer(eth.NewTraceBackend(chain, db), &tracers.TraceConfig{
+		Tracer:  &tracer,
+		Timeout: &timeout,
+		Reexec:  &reexec,
+	})
+
+	// Stop tracing on interrupt, the completed ranges being kept
+	tctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+	go func() {
+		select {
+		case <-sigc:
+			log.Info("Interrupted, stopping trace export")
+			cancel()
+		case <-tctx.Done():
+		}
+	}()
+
+	begin := time.Now()
+	blocks, skipped, err := exportTraceRanges(tctx, exporter, out, tracer, from, to, size, workers)
+	if err != nil {
+		return err
+	}
+	log.Info("Trace export done", "from", from, "to", to, "traced", blocks, "skipped", skipped, "elapsed", time.Since(begin))
+	return nil
+}
+
+// exportTraceRanges traces the ranges of blocks from one block to another with
+// a number of workers, skipping the ranges whose file exists. It returns the
+// number of blocks traced and skipped.
+func exportTraceRanges(ctx context.Context, exporter *tracers.RangeTracer, out, tracer string, from, to, size uint64, workers int) (uint64, uint64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Ranges are aligned on multiples of the range size, for the files of
+	// different runs to match
+	ranges := make(chan [2]uint64)
+	go func() {
+		defer close(ranges)
+		for start := from; start <= to; {
+			end := (start/size+1)*size - 1
+			if end > to {
+				end = to
+			}
+			select {
+			case ranges <- [2]uint64{start, end}:
+			case <-ctx.Done():
+				return
+			}
+			start = end + 1
+		}
+	}()
+	var (
+		begin   = time.Now()
+		tag     = traceFileTag(tracer)
+		blocks  uint64
+		skipped uint64
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		failed  error
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range ranges {
+				path := filepath.Join(out, fmt.Sprintf("traces-%s-%09d-%09d.jsonl.gz", tag, r[0], r[1]))
+				if _, err := os.Stat(path); err == nil {
+					atomic.AddUint64(&skipped, r[1]-r[0]+1)
+					continue
+				}
+				if err := exportTraceRange(ctx, exporter, path, r[0], r[1]); err != nil {
+					errOnce.Do(func() {
+						failed = fmt.Errorf("tracing blocks %d-%d failed: %v", r[0], r[1], err)
+						cancel()
+					})
+					return
+				}
+				done := atomic.AddUint64(&blocks, r[1]-r[0]+1)
+				log.Info("Exported block traces", "from", r[0], "to", r[1], "traced", done, "elapsed", time.Since(begin))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if failed != nil {
+		return blocks, skipped, failed
+	}
+	return blocks, skipped, ctx.Err()
+}
+
+// traceFileTag returns the name of a tracer in the trace files: the tracer
+// name, or the hash of the code of a custom JavaScript tracer.
+func traceFileTag(tracer string) string {
+	for _, c := range tracer {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return fmt.Sprintf("%x", crypto.Keccak256([]byte(tracer))[:8])
+		}
+	}
+	return tracer
+}
+
+// exportTraceRange traces a range of blocks to a temporary file, and moves it
+// to the given path once complete.
+func exportTraceRange(ctx context.Context, exporter *tracers.RangeTracer, path string, start, end uint64) error {
+	tmp := path + ".tmp"
+	fh, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	defer fh.Close()
+
+	var (
+		gz = gzip.NewWriter(fh)
+		w  = bufio.NewWriter(gz)
+	)
+	err = exporter.TraceRange(ctx, start, end, func(number uint64, traces []byte) error {
+		if _, err := w.Write(traces); err != nil {
+			return err
+		}
+		return w.WriteByte('\n')
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := fh.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// EOF
@@ -0,0 +1,130 @@
+// tracecmd_test.go
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the traces of a range of blocks are exported to a file per range
+// and tracer, one line per block, and that the files already exported by the
+// same tracer are skipped.
+func TestExportTraceRanges(t *testing.T) {
+	defer func(method int) { params.ConsensusMethod = method }(params.ConsensusMethod)
+	params.ConsensusMethod = params.ConsensusPoW
+
+	// Five blocks with a transfer each
+	var (
+		key, _  = crypto.GenerateKey()
+		from    = crypto.PubkeyToAddress(key.PublicKey)
+		to      = common.HexToAddress("0x1001")
+		db      = rawdb.NewMemoryDatabase()
+		genesis = (&core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}}}).MustCommit(db)
+		signer  = types.LatestSigner(params.TestChainConfig)
+	)
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 5, func(i int, b *core.BlockGen) {
+		b.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: uint64(i), To: &to, Value: common.Big1, Gas: params.TxGas, GasPrice: b.BaseFee()}))
+	})
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	out, err := ioutil.TempDir("", "trace-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+
+	export := func(tracer string) (uint64, uint64) {
+		t.Helper()
+		exporter := tracers.NewRangeTracer(eth.NewTraceBackend(chain, db), &tracers.TraceConfig{Tracer: &tracer})
+		traced, skipped, err := exportTraceRanges(context.Background(), exporter, out, tracer, 1, 5, 2, 2)
+		if err != nil {
+			t.Fatalf("%s: failed to export traces: %v", tracer, err)
+		}
+		return traced, skipped
+	}
+	if traced, skipped := export("callTracer"); traced != 5 || skipped != 0 {
+		t.Fatalf("blocks traced mismatch: have %d traced, %d skipped, want 5 traced", traced, skipped)
+	}
+	// The ranges are aligned on multiples of the range size
+	for _, r := range [][2]uint64{{1, 1}, {2, 3}, {4, 5}} {
+		path := filepath.Join(out, fmt.Sprintf("traces-callTracer-%09d-%09d.jsonl.gz", r[0], r[1]))
+		fh, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("range %d-%d: %v", r[0], r[1], err)
+		}
+		gz, err := gzip.NewReader(fh)
+		if err != nil {
+			t.Fatalf("range %d-%d: %v", r[0], r[1], err)
+		}
+		scanner := bufio.NewScanner(gz)
+		number := r[0]
+		for ; scanner.Scan(); number++ {
+			var result struct {
+				Block  hexutil.Uint64
+				Hash   common.Hash
+				Traces []json.RawMessage
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+				t.Fatalf("block %d: invalid traces %s: %v", number, scanner.Bytes(), err)
+			}
+			if uint64(result.Block) != number || result.Hash != blocks[number-1].Hash() || len(result.Traces) != 1 {
+				t.Errorf("block %d: traces mismatch: have block %d [%x] with %d traces", number, result.Block, result.Hash, len(result.Traces))
+			}
+		}
+		if number != r[1]+1 {
+			t.Errorf("range %d-%d: have %d lines, want %d", r[0], r[1], number-r[0], r[1]-r[0]+1)
+		}
+		fh.Close()
+	}
+	// Running again skips the ranges of the tracer only
+	if traced, skipped := export("callTracer"); traced != 0 || skipped != 5 {
+		t.Errorf("blocks traced again mismatch: have %d traced, %d skipped, want 5 skipped", traced, skipped)
+	}
+	if traced, skipped := export("4byteTracer"); traced != 5 || skipped != 0 {
+		t.Errorf("blocks traced by another tracer mismatch: have %d traced, %d skipped, want 5 traced", traced, skipped)
+	}
+	if _, err := os.Stat(filepath.Join(out, "traces-4byteTracer-000000004-000000005.jsonl.gz")); err != nil {
+		t.Errorf("range of another tracer missing: %v", err)
+	}
+}
+
+// Tests that custom tracers are named by the hash of their code in the trace
+// files.
+func TestTraceFileTag(t *testing.T) {
+	if tag := traceFileTag("callTracer"); tag != "callTracer" {
+		t.Errorf("built-in tracer tag mismatch: have %s, want callTracer", tag)
+	}
+	code := "{result: function() { return 1 }, fault: function() {}}"
+	if tag := traceFileTag(code); len(tag) != 16 || tag != traceFileTag(code) || tag == traceFileTag(code+" ") {
+		t.Errorf("custom tracer tag mismatch: have %s", tag)
+	}
+}
+
+// EOF
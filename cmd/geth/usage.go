@@ -51,6 +51,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.ExitWhenSyncedFlag,
 			utils.GCModeFlag,
 			utils.TxLookupLimitFlag,
+			utils.LogIndexFlag,
+			utils.BalanceHistoryFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightKDFFlag,
@@ -99,6 +101,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.TxPoolNoLocalsFlag,
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
+			utils.TxPoolRemoteJournalFlag,
+			utils.TxPoolRemoteRejournalFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
@@ -106,6 +110,11 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolSenderRateFlag,
+			utils.TxPoolSenderBurstFlag,
+			utils.TxPoolPeerRateFlag,
+			utils.TxPoolPeerBurstFlag,
+			utils.TxPoolRatePenaltyFlag,
 		},
 	},
 	{
@@ -141,6 +150,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.HTTPPortFlag,
 			utils.HTTPApiFlag,
 			utils.HTTPPathPrefixFlag,
+			utils.HTTPStreamFlag,
 			utils.HTTPCORSDomainFlag,
 			utils.HTTPVirtualHostsFlag,
 			utils.WSEnabledFlag,
@@ -155,6 +165,14 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.RPCGlobalGasCapFlag,
 			utils.RPCGlobalEVMTimeoutFlag,
 			utils.RPCGlobalTxFeeCapFlag,
+			utils.RPCAPIKeysFlag,
+			utils.RPCAPIKeysRequiredFlag,
+			utils.RPCRateLimitFlag,
+			utils.RPCRateLimitMethodsFlag,
+			utils.RPCRateLimitKeyFlag,
+			utils.RPCBatchLimitFlag,
+			utils.RPCResponseLimitFlag,
+			utils.RPCMethodTimeoutsFlag,
 			utils.AllowUnprotectedTxs,
 			utils.JSpathFlag,
 			utils.ExecFlag,
@@ -189,6 +207,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.MinerEtherbaseFlag,
 			utils.MinerExtraDataFlag,
 			utils.MinerRecommitIntervalFlag,
+			utils.MinerTxOrderingFlag,
+			utils.MinerTxSenderCapFlag,
 			utils.MinerNoVerifyFlag,
 		},
 	},
@@ -229,8 +249,11 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.NonceLimit,
 			utils.UseRocksDb,
 			utils.PrefetchCount,
+			utils.ParallelExecCount,
 			utils.LogFlag,
 			utils.MaxTxsPerBlock,
+			utils.PartnerTxExGossip,
+			utils.PartnerTxExSampleRate,
 			utils.Hub,
 			utils.BlockInterval,
 			utils.BlockTimeAdjBlocks,
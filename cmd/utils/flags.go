@@ -68,6 +68,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/nat"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 	pcsclite "github.com/gballet/go-libpcsclite"
 	gopsutil "github.com/shirou/gopsutil/mem"
 	"gopkg.in/urfave/cli.v1"
@@ -237,6 +238,14 @@ var (
 		Usage: "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
 		Value: ethconfig.Defaults.TxLookupLimit,
 	}
+	LogIndexFlag = cli.BoolFlag{
+		Name:  "logindex",
+		Usage: "Maintain an inverted address and topic index of the logs for fast log searches",
+	}
+	BalanceHistoryFlag = cli.BoolFlag{
+		Name:  "balancehistory",
+		Usage: "Index the balance and nonce changes of the accounts for eth_getBalanceHistory",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -358,6 +367,16 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolRemoteJournalFlag = cli.StringFlag{
+		Name:  "txpool.remotejournal",
+		Usage: "Disk snapshot of remote transactions to survive node restarts (empty = disabled)",
+		Value: core.DefaultTxPoolConfig.RemoteJournal,
+	}
+	TxPoolRemoteRejournalFlag = cli.DurationFlag{
+		Name:  "txpool.remoterejournal",
+		Usage: "Time interval to regenerate the remote transaction snapshot",
+		Value: core.DefaultTxPoolConfig.RemoteRejournal,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -393,6 +412,31 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: ethconfig.Defaults.TxPool.Lifetime,
 	}
+	TxPoolSenderRateFlag = cli.Float64Flag{
+		Name:  "txpool.senderrate",
+		Usage: "Maximum transactions per second admitted from peers per sender account (0 = unlimited)",
+		Value: ethconfig.Defaults.TxPool.SenderRate,
+	}
+	TxPoolSenderBurstFlag = cli.IntFlag{
+		Name:  "txpool.senderburst",
+		Usage: "Maximum transactions admitted from peers per sender account at once",
+		Value: ethconfig.Defaults.TxPool.SenderBurst,
+	}
+	TxPoolPeerRateFlag = cli.Float64Flag{
+		Name:  "txpool.peerrate",
+		Usage: "Maximum transactions per second admitted per peer (0 = unlimited)",
+		Value: ethconfig.Defaults.TxPool.PeerRate,
+	}
+	TxPoolPeerBurstFlag = cli.IntFlag{
+		Name:  "txpool.peerburst",
+		Usage: "Maximum transactions admitted per peer at once",
+		Value: ethconfig.Defaults.TxPool.PeerBurst,
+	}
+	TxPoolRatePenaltyFlag = cli.DurationFlag{
+		Name:  "txpool.ratepenalty",
+		Usage: "Time senders and peers exceeding their rate are throttled for, doubled on repeats",
+		Value: ethconfig.Defaults.TxPool.RatePenalty,
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -479,6 +523,14 @@ var (
 		Usage: "Time interval to recreate the block being mined",
 		Value: ethconfig.Defaults.Miner.Recommit,
 	}
+	MinerTxOrderingFlag = cli.StringFlag{
+		Name:  "miner.txordering",
+		Usage: "Transaction ordering policy (roundrobin, price, fifo)",
+	}
+	MinerTxSenderCapFlag = cli.IntFlag{
+		Name:  "miner.txsendercap",
+		Usage: "Maximum number of transactions per sender in a block (0 = no limit)",
+	}
 	MinerNoVerifyFlag = cli.BoolFlag{
 		Name:  "miner.noverify",
 		Usage: "Disable remote sealing verification",
@@ -522,6 +574,38 @@ var (
 		Usage: "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
 		Value: ethconfig.Defaults.RPCTxFeeCap,
 	}
+	RPCAPIKeysFlag = cli.StringFlag{
+		Name:  "rpc.apikeys",
+		Usage: "File of the API keys accepted by the HTTP and WS-RPC servers, one \"<key> <name>\" per line",
+	}
+	RPCAPIKeysRequiredFlag = cli.BoolFlag{
+		Name:  "rpc.apikeys.required",
+		Usage: "Reject the HTTP and WS-RPC clients without API key",
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpc.ratelimit",
+		Usage: "Requests per second per IP address served by the HTTP and WS-RPC servers (0 = no limit)",
+	}
+	RPCRateLimitMethodsFlag = cli.StringFlag{
+		Name:  "rpc.ratelimit.methods",
+		Usage: "Comma separated requests per second per IP address of methods or namespaces, e.g. \"eth_getLogs=5,debug=1\"",
+	}
+	RPCRateLimitKeyFlag = cli.Float64Flag{
+		Name:  "rpc.ratelimit.key",
+		Usage: "Requests per second per API key served by the HTTP and WS-RPC servers (0 = no limit)",
+	}
+	RPCBatchLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchlimit",
+		Usage: "Max number of requests in a batch served by the HTTP and WS-RPC servers (0 = no limit)",
+	}
+	RPCResponseLimitFlag = cli.IntFlag{
+		Name:  "rpc.responselimit",
+		Usage: "Max size in bytes of the responses to a request or batch served by the HTTP and WS-RPC servers (0 = no limit)",
+	}
+	RPCMethodTimeoutsFlag = cli.StringFlag{
+		Name:  "rpc.methodtimeouts",
+		Usage: "Comma separated max execution times of methods, namespaces or *, e.g. \"eth_getLogs=10s,debug=1m,*=30s\"",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -578,6 +662,10 @@ var (
 		Usage: "HTTP path path prefix on which JSON-RPC is served. Use '/' to serve on all paths.",
 		Value: "",
 	}
+	HTTPStreamFlag = cli.BoolFlag{
+		Name:  "http.stream",
+		Usage: "Enable JSON-RPC streams over unencrypted HTTP/2 (h2c) on the HTTP-RPC server",
+	}
 	GraphQLEnabledFlag = cli.BoolFlag{
 		Name:  "graphql",
 		Usage: "Enable GraphQL on the HTTP-RPC server. Note that GraphQL can only be started if an HTTP server is started as well.",
@@ -836,6 +924,11 @@ var (
 		Usage: "Transaction prefetch count for faster db read",
 		Value: params.PrefetchCount,
 	}
+	ParallelExecCount = cli.IntFlag{
+		Name:  "parallelexeccount",
+		Usage: "Number of workers executing transactions in parallel on block import (0 = sequential)",
+		Value: params.ParallelExecCount,
+	}
 	LogFlag = cli.StringFlag{
 		Name:  "log",
 		Usage: "Rotating log file: <file-name>,<count>,<size>",
@@ -846,6 +939,15 @@ var (
 		Usage: "Max # of transactions in a block",
 		Value: params.MaxTxsPerBlock,
 	}
+	PartnerTxExGossip = cli.BoolFlag{
+		Name:  "wemix.partnertxex",
+		Usage: "Send transactions to partners in signed batches carrying the senders (all partners need to support it)",
+	}
+	PartnerTxExSampleRate = cli.Float64Flag{
+		Name:  "wemix.partnertxex.samplerate",
+		Usage: "Fraction of the senders in signed partner batches to re-verify, peers lying are disconnected (1 = verify all)",
+		Value: params.PartnerTxExSampleRate,
+	}
 	Hub = cli.StringFlag{
 		Name:  "hub",
 		Usage: "Id of message hub",
@@ -1054,11 +1156,122 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(HTTPPathPrefixFlag.Name) {
 		cfg.HTTPPathPrefix = ctx.GlobalString(HTTPPathPrefixFlag.Name)
 	}
+	if ctx.GlobalIsSet(HTTPStreamFlag.Name) {
+		cfg.HTTPStream = ctx.GlobalBool(HTTPStreamFlag.Name)
+	}
 	if ctx.GlobalIsSet(AllowUnprotectedTxs.Name) {
 		cfg.AllowUnprotectedTxs = ctx.GlobalBool(AllowUnprotectedTxs.Name)
 	}
 }
 
+// setRPCLimits sets the API keys and limits of the HTTP and WebSocket RPC
+// servers from the command line flags.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	limits := cfg.RPCLimits
+	if limits == nil {
+		set := false
+		for _, flag := range []cli.Flag{RPCAPIKeysFlag, RPCAPIKeysRequiredFlag, RPCRateLimitFlag, RPCRateLimitMethodsFlag, RPCRateLimitKeyFlag, RPCBatchLimitFlag, RPCResponseLimitFlag, RPCMethodTimeoutsFlag} {
+			set = set || ctx.GlobalIsSet(flag.GetName())
+		}
+		if !set {
+			return
+		}
+		limits = new(rpc.LimitConfig)
+	}
+	if ctx.GlobalIsSet(RPCAPIKeysFlag.Name) {
+		keys, err := loadAPIKeys(ctx.GlobalString(RPCAPIKeysFlag.Name))
+		if err != nil {
+			Fatalf("Failed to load API keys: %v", err)
+		}
+		limits.APIKeys = keys
+	}
+	if ctx.GlobalIsSet(RPCAPIKeysRequiredFlag.Name) {
+		limits.RequireAPIKey = ctx.GlobalBool(RPCAPIKeysRequiredFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		limits.IPLimits = setRateLimit(limits.IPLimits, rpc.AnyMethod, ctx.GlobalFloat64(RPCRateLimitFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCRateLimitMethodsFlag.Name) {
+		for _, entry := range SplitAndTrim(ctx.GlobalString(RPCRateLimitMethodsFlag.Name)) {
+			parts := strings.Split(entry, "=")
+			if len(parts) != 2 {
+				Fatalf("Invalid --%s entry: %q", RPCRateLimitMethodsFlag.Name, entry)
+			}
+			rate, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil {
+				Fatalf("Invalid --%s entry: %q", RPCRateLimitMethodsFlag.Name, entry)
+			}
+			limits.IPLimits = setRateLimit(limits.IPLimits, parts[0], rate)
+		}
+	}
+	if ctx.GlobalIsSet(RPCRateLimitKeyFlag.Name) {
+		limits.KeyLimits = setRateLimit(limits.KeyLimits, rpc.AnyMethod, ctx.GlobalFloat64(RPCRateLimitKeyFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCBatchLimitFlag.Name) {
+		limits.MaxBatchSize = ctx.GlobalInt(RPCBatchLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCResponseLimitFlag.Name) {
+		limits.MaxResponseSize = ctx.GlobalInt(RPCResponseLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCMethodTimeoutsFlag.Name) {
+		limits.MethodTimeouts = make(map[string]time.Duration)
+		for _, entry := range SplitAndTrim(ctx.GlobalString(RPCMethodTimeoutsFlag.Name)) {
+			parts := strings.Split(entry, "=")
+			if len(parts) != 2 {
+				Fatalf("Invalid --%s entry: %q", RPCMethodTimeoutsFlag.Name, entry)
+			}
+			timeout, err := time.ParseDuration(parts[1])
+			if err != nil {
+				Fatalf("Invalid --%s entry: %q", RPCMethodTimeoutsFlag.Name, entry)
+			}
+			limits.MethodTimeouts[parts[0]] = timeout
+		}
+	}
+	if limits.RequireAPIKey && len(limits.APIKeys) == 0 {
+		Fatalf("--%s requires --%s", RPCAPIKeysRequiredFlag.Name, RPCAPIKeysFlag.Name)
+	}
+	cfg.RPCLimits = limits
+}
+
+// setRateLimit sets the rate limit of a method or namespace, removing it if
+// the rate is zero.
+func setRateLimit(limits map[string]rpc.RateLimit, rule string, rate float64) map[string]rpc.RateLimit {
+	if limits == nil {
+		limits = make(map[string]rpc.RateLimit)
+	}
+	if rate <= 0 {
+		delete(limits, rule)
+	} else {
+		limits[rule] = rpc.RateLimit{Rate: rate}
+	}
+	return limits
+}
+
+// loadAPIKeys reads a file of API keys, one "<key> <name>" per line, the name
+// defaulting to the key. Empty lines and lines starting with # are ignored.
+func loadAPIKeys(file string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: invalid API key entry", i+1)
+		}
+		name := fields[0]
+		if len(fields) == 2 {
+			name = fields[1]
+		}
+		keys[fields[0]] = name
+	}
+	return keys, nil
+}
+
 // setGraphQL creates the GraphQL listener interface string from the set
 // command line flags, returning empty if the GraphQL endpoint is disabled.
 func setGraphQL(ctx *cli.Context, cfg *node.Config) {
@@ -1302,6 +1515,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCLimits(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
@@ -1417,6 +1631,12 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalFlag.Name) {
+		cfg.RemoteJournal = ctx.GlobalString(TxPoolRemoteJournalFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRemoteRejournalFlag.Name) {
+		cfg.RemoteRejournal = ctx.GlobalDuration(TxPoolRemoteRejournalFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1438,6 +1658,21 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolSenderRateFlag.Name) {
+		cfg.SenderRate = ctx.GlobalFloat64(TxPoolSenderRateFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolSenderBurstFlag.Name) {
+		cfg.SenderBurst = ctx.GlobalInt(TxPoolSenderBurstFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolPeerRateFlag.Name) {
+		cfg.PeerRate = ctx.GlobalFloat64(TxPoolPeerRateFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolPeerBurstFlag.Name) {
+		cfg.PeerBurst = ctx.GlobalInt(TxPoolPeerBurstFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRatePenaltyFlag.Name) {
+		cfg.RatePenalty = ctx.GlobalDuration(TxPoolRatePenaltyFlag.Name)
+	}
 }
 
 func setEthash(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1484,6 +1719,12 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.GlobalIsSet(MinerRecommitIntervalFlag.Name) {
 		cfg.Recommit = ctx.GlobalDuration(MinerRecommitIntervalFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerTxOrderingFlag.Name) {
+		cfg.TxOrdering = ctx.GlobalString(MinerTxOrderingFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerTxSenderCapFlag.Name) {
+		cfg.TxSenderCap = ctx.GlobalInt(MinerTxSenderCapFlag.Name)
+	}
 	if ctx.GlobalIsSet(MinerNoVerifyFlag.Name) {
 		cfg.Noverify = ctx.GlobalBool(MinerNoVerifyFlag.Name)
 	}
@@ -1633,6 +1874,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.GlobalBool(LogIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(BalanceHistoryFlag.Name) {
+		cfg.BalanceHistory = ctx.GlobalBool(BalanceHistoryFlag.Name)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
@@ -1828,9 +2075,22 @@ func SetWemixConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(UseRocksDb.Name) {
 		params.UseRocksDb = ctx.GlobalInt(UseRocksDb.Name)
 	}
+	if ctx.GlobalIsSet(ParallelExecCount.Name) {
+		params.ParallelExecCount = ctx.GlobalInt(ParallelExecCount.Name)
+	}
 	if ctx.GlobalIsSet(MaxTxsPerBlock.Name) {
 		params.MaxTxsPerBlock = ctx.GlobalInt(MaxTxsPerBlock.Name)
 	}
+	if ctx.GlobalIsSet(PartnerTxExGossip.Name) {
+		params.PartnerTxExGossip = ctx.GlobalBool(PartnerTxExGossip.Name)
+	}
+	if ctx.GlobalIsSet(PartnerTxExSampleRate.Name) {
+		rate := ctx.GlobalFloat64(PartnerTxExSampleRate.Name)
+		if rate < 0 || rate > 1 {
+			Fatalf("Invalid partner batch sample rate: %v", rate)
+		}
+		params.PartnerTxExSampleRate = rate
+	}
 	if ctx.GlobalIsSet(Hub.Name) {
 		params.Hub = ctx.GlobalString(Hub.Name)
 	}
@@ -1904,8 +2164,9 @@ func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url strin
 }
 
 // RegisterGraphQLService is a utility function to construct a new service and register it against a node.
-func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, cfg node.Config) {
-	if err := graphql.New(stack, backend, cfg.GraphQLCors, cfg.GraphQLVirtualHosts); err != nil {
+func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, ethcfg *ethconfig.Config, cfg node.Config) {
+	lightMode := ethcfg.SyncMode == downloader.LightSync
+	if err := graphql.New(stack, backend, lightMode, cfg.GraphQLCors, cfg.GraphQLVirtualHosts); err != nil {
 		Fatalf("Failed to register the GraphQL service: %v", err)
 	}
 }
@@ -2029,12 +2290,20 @@ func MakeGenesis(ctx *cli.Context) *core.Genesis {
 	return genesis
 }
 
-// MakeChain creates a chain manager from set command line flags.
-func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chainDb ethdb.Database) {
-	var err error
-	chainDb = MakeChainDatabase(ctx, stack, false) // TODO(rjl493456442) support read-only database
-	config, _, err := core.SetupGenesisBlock(chainDb, MakeGenesis(ctx))
-	if err != nil {
+// MakeChain creates a chain manager from set command line flags. A chain made
+// over a read-only database can only be read, not imported into.
+func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (chain *core.BlockChain, chainDb ethdb.Database) {
+	var (
+		err    error
+		config *params.ChainConfig
+	)
+	chainDb = MakeChainDatabase(ctx, stack, readonly)
+	if readonly {
+		// The genesis can't be set up, only read
+		if config = rawdb.ReadChainConfig(chainDb, rawdb.ReadCanonicalHash(chainDb, 0)); config == nil {
+			Fatalf("No chain config found in database")
+		}
+	} else if config, _, err = core.SetupGenesisBlock(chainDb, MakeGenesis(ctx)); err != nil {
 		Fatalf("%v", err)
 	}
 	var engine consensus.Engine
@@ -2071,8 +2340,8 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		cache.Preimages = true
 		log.Info("Enabling recording of key preimages since archive mode is used")
 	}
-	if !ctx.GlobalBool(SnapshotFlag.Name) {
-		cache.SnapshotLimit = 0 // Disabled
+	if !ctx.GlobalBool(SnapshotFlag.Name) || readonly {
+		cache.SnapshotLimit = 0 // Disabled, a read-only chain can't generate it
 	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cache.TrieCleanLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
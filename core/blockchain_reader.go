@@ -73,6 +73,13 @@ func (bc *BlockChain) GetHeaderByNumber(number uint64) *types.Header {
 	return bc.hc.GetHeaderByNumber(number)
 }
 
+// GetHeaderByTime retrieves the header of the latest canonical block with a
+// timestamp at or before the given one, up to the current block, nil if the
+// chain starts after it.
+func (bc *BlockChain) GetHeaderByTime(time uint64) *types.Header {
+	return bc.hc.getHeaderByTime(time, bc.CurrentBlock().Header())
+}
+
 // GetHeadersFrom returns a contiguous segment of headers, in rlp-form, going
 // backwards from the given number.
 func (bc *BlockChain) GetHeadersFrom(number, count uint64) []rlp.RawValue {
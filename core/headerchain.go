@@ -41,6 +41,7 @@ const (
 	headerCacheLimit = 512
 	tdCacheLimit     = 1024
 	numberCacheLimit = 2048
+	timeCacheLimit   = 1024
 )
 
 // HeaderChain implements the basic block header chain logic that is shared by
@@ -67,6 +68,7 @@ type HeaderChain struct {
 	headerCache *lru.Cache // Cache for the most recent block headers
 	tdCache     *lru.Cache // Cache for the most recent block total difficulties
 	numberCache *lru.Cache // Cache for the most recent block numbers
+	timeCache   *lru.Cache // Cache for the most recent block numbers by timestamp
 
 	procInterrupt func() bool
 
@@ -80,6 +82,7 @@ func NewHeaderChain(chainDb ethdb.Database, config *params.ChainConfig, engine c
 	headerCache, _ := lru.New(headerCacheLimit)
 	tdCache, _ := lru.New(tdCacheLimit)
 	numberCache, _ := lru.New(numberCacheLimit)
+	timeCache, _ := lru.New(timeCacheLimit)
 
 	// Seed a fast but crypto originating random generator
 	seed, err := crand.Int(crand.Reader, big.NewInt(math.MaxInt64))
@@ -92,6 +95,7 @@ func NewHeaderChain(chainDb ethdb.Database, config *params.ChainConfig, engine c
 		headerCache:   headerCache,
 		tdCache:       tdCache,
 		numberCache:   numberCache,
+		timeCache:     timeCache,
 		procInterrupt: procInterrupt,
 		rand:          mrand.New(mrand.NewSource(seed.Int64())),
 		engine:        engine,
@@ -499,6 +503,52 @@ func (hc *HeaderChain) GetHeaderByNumber(number uint64) *types.Header {
 	return hc.GetHeader(hash, number)
 }
 
+// GetHeaderByTime retrieves the header of the latest canonical block with a
+// timestamp at or before the given one, nil if the chain starts after it.
+func (hc *HeaderChain) GetHeaderByTime(time uint64) *types.Header {
+	return hc.getHeaderByTime(time, hc.CurrentHeader())
+}
+
+// getHeaderByTime retrieves the header of the latest canonical block up to the
+// given head with a timestamp at or before the given one, binary searching the
+// canonical headers, the timestamps of the blocks being strictly increasing.
+func (hc *HeaderChain) getHeaderByTime(time uint64, head *types.Header) *types.Header {
+	if head.Time <= time {
+		return head
+	}
+	// The cached numbers are still valid if the reorgs since kept their block
+	// the latest one at or before the timestamp
+	if cached, ok := hc.timeCache.Get(time); ok {
+		number := cached.(uint64)
+		if number < head.Number.Uint64() {
+			header, next := hc.GetHeaderByNumber(number), hc.GetHeaderByNumber(number+1)
+			if header != nil && next != nil && header.Time <= time && next.Time > time {
+				return header
+			}
+		}
+	}
+	genesis := hc.GetHeaderByNumber(0)
+	if genesis == nil || genesis.Time > time {
+		return nil
+	}
+	// Invariant: the block lo is at or before the timestamp, the block hi after
+	lo, hi := uint64(0), head.Number.Uint64()
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		header := hc.GetHeaderByNumber(mid)
+		if header == nil {
+			return nil
+		}
+		if header.Time <= time {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	hc.timeCache.Add(time, lo)
+	return hc.GetHeaderByNumber(lo)
+}
+
 // GetHeadersFrom returns a contiguous segment of headers, in rlp-form, going
 // backwards from the given number.
 // If the 'number' is higher than the highest local header, this method will
@@ -648,6 +698,7 @@ func (hc *HeaderChain) SetHead(head uint64, updateFn UpdateHeadBlocksCallback, d
 	hc.headerCache.Purge()
 	hc.tdCache.Purge()
 	hc.numberCache.Purge()
+	hc.timeCache.Purge()
 }
 
 // SetGenesis sets a new genesis block header for the chain
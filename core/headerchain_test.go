@@ -115,3 +115,45 @@ func TestHeaderInsertion(t *testing.T) {
 	// And B becomes even longer
 	testInsert(t, hc, chainB[107:128], CanonStatTy, nil, forker)
 }
+
+func TestGetHeaderByTime(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+
+	// A chain of 100 blocks, 10 seconds apart from the time 1000
+	var parent *types.Header
+	for i := int64(0); i < 100; i++ {
+		header := &types.Header{Number: big.NewInt(i), Time: uint64(1000 + 10*i)}
+		if parent != nil {
+			header.ParentHash = parent.Hash()
+		}
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), header.Number.Uint64())
+		rawdb.WriteHeadBlockHash(db, header.Hash())
+		parent = header
+	}
+	hc, err := NewHeaderChain(db, params.AllEthashProtocolChanges, ethash.NewFaker(), func() bool { return false })
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		time   uint64
+		number int64 // -1 for none
+	}{
+		{999, -1}, {1000, 0}, {1009, 0}, {1010, 1}, {1505, 50}, {1980, 98}, {1989, 98}, {1990, 99}, {5000, 99},
+	}
+	// Twice, to check the cached results
+	for round := 0; round < 2; round++ {
+		for _, tt := range tests {
+			header := hc.GetHeaderByTime(tt.time)
+			if tt.number < 0 {
+				if header != nil {
+					t.Errorf("time %d: expected no header, got %d", tt.time, header.Number)
+				}
+				continue
+			}
+			if header == nil || header.Number.Int64() != tt.number {
+				t.Errorf("time %d: expected header %d, got %v", tt.time, tt.number, header)
+			}
+		}
+	}
+}
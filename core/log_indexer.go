@@ -0,0 +1,173 @@
+// log_indexer.go
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/bitutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+const (
+	// LogIndexThrottling is the time to wait between processing two consecutive
+	// log index sections, not to overload the disk while catching up.
+	LogIndexThrottling = 100 * time.Millisecond
+)
+
+// LogIndexer implements a core.ChainIndexer, building an inverted index of the
+// logs of the canonical chain: for each address and topic, the bit vector of
+// the blocks of a section having logs of it. Topics are indexed regardless of
+// their position, like in the header blooms.
+type LogIndexer struct {
+	size    uint64              // section size to generate the index for
+	db      ethdb.Database      // database instance to read receipts from and write index data into
+	section uint64              // Section is the section number being processed currently
+	head    common.Hash         // Head is the hash of the last header processed
+	blocks  map[string][]uint64 // Sorted section indexes of the blocks by address or topic
+}
+
+// NewLogIndexer returns a chain indexer that generates the inverted log index
+// of the canonical chain, waiting throttling between sections.
+func NewLogIndexer(db ethdb.Database, size, confirms uint64, throttling time.Duration) *ChainIndexer {
+	backend := &LogIndexer{
+		db:   db,
+		size: size,
+	}
+	table := rawdb.NewTable(db, string(rawdb.LogIndexPrefix))
+
+	return NewChainIndexer(db, table, backend, size, confirms, throttling, "logindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new log index section.
+func (b *LogIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	b.section, b.head, b.blocks = section, common.Hash{}, make(map[string][]uint64)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the logs of a header's
+// block into the index.
+func (b *LogIndexer) Process(ctx context.Context, header *types.Header) error {
+	b.head = header.Hash()
+	if header.Bloom == (types.Bloom{}) {
+		return nil
+	}
+	number := header.Number.Uint64()
+	receipts := rawdb.ReadRawReceipts(b.db, b.head, number)
+	if receipts == nil {
+		return fmt.Errorf("receipts of block #%d [%x..] not found", number, b.head[:4])
+	}
+	index := number - b.section*b.size
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			b.set(log.Address.Bytes(), index)
+			for _, topic := range log.Topics {
+				b.set(topic.Bytes(), index)
+			}
+		}
+	}
+	return nil
+}
+
+// set adds a block to the blocks of an address or topic. Most of them have logs
+// in few blocks, so they are kept as lists, the bit vectors only being built
+// on commit.
+func (b *LogIndexer) set(key []byte, index uint64) {
+	blocks := b.blocks[string(key)]
+	if n := len(blocks); n > 0 && blocks[n-1] == index {
+		return // blocks are processed in order
+	}
+	b.blocks[string(key)] = append(blocks, index)
+}
+
+// Commit implements core.ChainIndexerBackend, writing the bit vectors of the
+// section into the database.
+func (b *LogIndexer) Commit() error {
+	var (
+		batch = b.db.NewBatch()
+		bits  = make([]byte, b.size/8)
+	)
+	for key, blocks := range b.blocks {
+		for i := range bits {
+			bits[i] = 0
+		}
+		for _, index := range blocks {
+			bits[index/8] |= 1 << (7 - index%8)
+		}
+		rawdb.WriteLogIndex(batch, []byte(key), b.section, b.head, bitutil.CompressBytes(bits))
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	return batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (b *LogIndexer) Prune(threshold uint64) error {
+	return nil
+}
+
+// LogIndexMatches returns the bit vector of the blocks of a section that may
+// have logs matching the given addresses and topics, or nil if the criteria
+// match all blocks. Since topics are indexed regardless of their position, the
+// logs of the blocks still need to be filtered.
+func LogIndexMatches(db ethdb.KeyValueReader, size, section uint64, head common.Hash, addresses []common.Address, topics [][]common.Hash) ([]byte, error) {
+	var matches []byte
+
+	// union returns the blocks having logs of any of the given keys
+	union := func(keys [][]byte) ([]byte, error) {
+		bits := make([]byte, size/8)
+		for _, key := range keys {
+			data := rawdb.ReadLogIndex(db, key, section, head)
+			if data == nil {
+				continue
+			}
+			blob, err := bitutil.DecompressBytes(data, int(size/8))
+			if err != nil {
+				return nil, err
+			}
+			bitutil.ORBytes(bits, bits, blob)
+		}
+		return bits, nil
+	}
+	if len(addresses) > 0 {
+		keys := make([][]byte, len(addresses))
+		for i, address := range addresses {
+			keys[i] = address.Bytes()
+		}
+		bits, err := union(keys)
+		if err != nil {
+			return nil, err
+		}
+		matches = bits
+	}
+	for _, sub := range topics {
+		if len(sub) == 0 {
+			continue // empty rule set == wildcard
+		}
+		keys := make([][]byte, len(sub))
+		for i, topic := range sub {
+			keys[i] = topic.Bytes()
+		}
+		bits, err := union(keys)
+		if err != nil {
+			return nil, err
+		}
+		if matches == nil {
+			matches = bits
+		} else {
+			bitutil.ANDBytes(matches, matches, bits)
+		}
+	}
+	return matches, nil
+}
+
+// EOF
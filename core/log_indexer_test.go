@@ -0,0 +1,92 @@
+// log_indexer_test.go
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestLogIndexer(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		size    = uint64(16)
+		addr1   = common.HexToAddress("0x1")
+		addr2   = common.HexToAddress("0x2")
+		topic1  = common.HexToHash("0xa")
+		topic2  = common.HexToHash("0xb")
+		backend = &LogIndexer{db: db, size: size}
+		logsAt  = map[uint64][]*types.Log{
+			3:  {{Address: addr1, Topics: []common.Hash{topic1}}},
+			7:  {{Address: addr2, Topics: []common.Hash{topic1, topic2}}},
+			12: {{Address: addr1, Topics: []common.Hash{topic2}}},
+		}
+	)
+	if err := backend.Reset(context.Background(), 0, common.Hash{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(0); i < size; i++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(i)}
+		if logs, ok := logsAt[i]; ok {
+			receipt := &types.Receipt{Logs: logs}
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			header.Bloom = receipt.Bloom
+			rawdb.WriteReceipts(db, header.Hash(), i, types.Receipts{receipt})
+		}
+		if err := backend.Process(context.Background(), header); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := backend.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		addresses []common.Address
+		topics    [][]common.Hash
+		blocks    []uint64
+	}{
+		{[]common.Address{addr1}, nil, []uint64{3, 12}},
+		{[]common.Address{addr1, addr2}, nil, []uint64{3, 7, 12}},
+		{nil, [][]common.Hash{{topic1}}, []uint64{3, 7}},
+		{nil, [][]common.Hash{{}, {topic2}}, []uint64{7, 12}},
+		{[]common.Address{addr1}, [][]common.Hash{{topic2}}, []uint64{12}},
+		{[]common.Address{common.HexToAddress("0x3")}, nil, nil},
+	}
+	for i, tt := range tests {
+		matches, err := LogIndexMatches(db, size, 0, backend.head, tt.addresses, tt.topics)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		var blocks []uint64
+		for n := uint64(0); n < size; n++ {
+			if matches[n/8]&(1<<(7-n%8)) != 0 {
+				blocks = append(blocks, n)
+			}
+		}
+		if len(blocks) != len(tt.blocks) {
+			t.Errorf("test %d: blocks mismatch: have %v, want %v", i, blocks, tt.blocks)
+			continue
+		}
+		for j := range blocks {
+			if blocks[j] != tt.blocks[j] {
+				t.Errorf("test %d: blocks mismatch: have %v, want %v", i, blocks, tt.blocks)
+				break
+			}
+		}
+	}
+	// Criteria not restricting addresses or topics match all blocks
+	if matches, err := LogIndexMatches(db, size, 0, backend.head, nil, [][]common.Hash{{}}); err != nil || matches != nil {
+		t.Errorf("expected nil matches for wildcard criteria, got %x, %v", matches, err)
+	}
+	// The index of another section head is empty
+	if matches, _ := LogIndexMatches(db, size, 0, common.Hash{1}, []common.Address{addr1}, nil); matches[0] != 0 || matches[1] != 0 {
+		t.Errorf("expected no matches for unknown section head, got %x", matches)
+	}
+}
+
+// EOF
@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -175,3 +176,97 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// ReadLogIndex retrieves the compressed bit vector of the blocks of a section
+// having logs of an address or topic, nil if none has.
+func ReadLogIndex(db ethdb.KeyValueReader, key []byte, section uint64, head common.Hash) []byte {
+	data, _ := db.Get(logIndexKey(key, section, head))
+	return data
+}
+
+// WriteLogIndex stores the compressed bit vector of the blocks of a section
+// having logs of an address or topic.
+func WriteLogIndex(db ethdb.KeyValueWriter, key []byte, section uint64, head common.Hash, bits []byte) {
+	if err := db.Put(logIndexKey(key, section, head), bits); err != nil {
+		log.Crit("Failed to store log index", "err", err)
+	}
+}
+
+// BalanceChange is a change of the balance or nonce of an account in a block.
+type BalanceChange struct {
+	Number      uint64
+	Hash        common.Hash
+	PrevBalance *big.Int
+	Balance     *big.Int
+	PrevNonce   uint64
+	Nonce       uint64
+}
+
+// storedBalanceChange is the stored form of a balance change, its block being
+// in the key.
+type storedBalanceChange struct {
+	PrevBalance *big.Int
+	Balance     *big.Int
+	PrevNonce   uint64
+	Nonce       uint64
+}
+
+// WriteBalanceChange stores a change of the balance or nonce of an account.
+func WriteBalanceChange(db ethdb.KeyValueWriter, address common.Address, change *BalanceChange) {
+	data, err := rlp.EncodeToBytes(&storedBalanceChange{
+		PrevBalance: change.PrevBalance,
+		Balance:     change.Balance,
+		PrevNonce:   change.PrevNonce,
+		Nonce:       change.Nonce,
+	})
+	if err != nil {
+		log.Crit("Failed to encode balance change", "err", err)
+	}
+	if err := db.Put(balanceHistoryKey(address, change.Number, change.Hash), data); err != nil {
+		log.Crit("Failed to store balance change", "err", err)
+	}
+}
+
+// ReadBalanceHistory retrieves the changes of the balance or nonce of an account
+// in the blocks from..to, in the given chain or any if canonical is nil, up
+// to limit changes if not zero.
+func ReadBalanceHistory(db ethdb.Iteratee, address common.Address, from, to uint64, canonical func(uint64) common.Hash, limit int) ([]*BalanceChange, error) {
+	prefix := append(append([]byte{}, balanceHistoryPrefix...), address.Bytes()...)
+	start := make([]byte, 8)
+	binary.BigEndian.PutUint64(start, from)
+
+	it := db.NewIterator(prefix, start)
+	defer it.Release()
+
+	var changes []*BalanceChange
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+8+common.HashLength {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(prefix):])
+		if number > to {
+			break
+		}
+		hash := common.BytesToHash(key[len(prefix)+8:])
+		if canonical != nil && canonical(number) != hash {
+			continue
+		}
+		var stored storedBalanceChange
+		if err := rlp.DecodeBytes(it.Value(), &stored); err != nil {
+			return nil, err
+		}
+		changes = append(changes, &BalanceChange{
+			Number:      number,
+			Hash:        hash,
+			PrevBalance: stored.PrevBalance,
+			Balance:     stored.Balance,
+			PrevNonce:   stored.PrevNonce,
+			Nonce:       stored.Nonce,
+		})
+		if limit > 0 && len(changes) >= limit {
+			break
+		}
+	}
+	return changes, it.Error()
+}
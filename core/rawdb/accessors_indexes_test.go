@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"fmt"
 	"hash"
 	"math/big"
 	"testing"
@@ -177,3 +178,54 @@ func TestDeleteBloomBits(t *testing.T) {
 	check(1, 1, params.MainnetGenesisHash, true)
 	check(1, 1, params.RinkebyGenesisHash, true)
 }
+
+func TestBalanceHistory(t *testing.T) {
+	var (
+		db      = NewMemoryDatabase()
+		account = common.HexToAddress("0x01")
+		other   = common.HexToAddress("0x02")
+		hashes  = map[uint64]common.Hash{1: {1}, 3: {3}, 5: {5}, 7: {7}}
+	)
+	canonical := func(number uint64) common.Hash {
+		return hashes[number]
+	}
+	for number, hash := range hashes {
+		WriteBalanceChange(db, account, &BalanceChange{Number: number, Hash: hash, PrevBalance: big.NewInt(int64(number)), Balance: big.NewInt(int64(number + 1)), Nonce: number})
+		WriteBalanceChange(db, other, &BalanceChange{Number: number, Hash: hash, PrevBalance: new(big.Int), Balance: new(big.Int)})
+	}
+	// A change of a block reorged out
+	WriteBalanceChange(db, account, &BalanceChange{Number: 5, Hash: common.Hash{0x55}, PrevBalance: new(big.Int), Balance: new(big.Int)})
+
+	numbers := func(changes []*BalanceChange) []uint64 {
+		var numbers []uint64
+		for _, change := range changes {
+			numbers = append(numbers, change.Number)
+		}
+		return numbers
+	}
+	tests := []struct {
+		from, to  uint64
+		canonical func(uint64) common.Hash
+		limit     int
+		want      []uint64
+	}{
+		{0, 10, canonical, 0, []uint64{1, 3, 5, 7}},
+		{3, 5, canonical, 0, []uint64{3, 5}},
+		{2, 2, canonical, 0, nil},
+		{0, 10, canonical, 2, []uint64{1, 3}},
+		{5, 5, nil, 0, []uint64{5, 5}},
+	}
+	for i, tt := range tests {
+		changes, err := ReadBalanceHistory(db, account, tt.from, tt.to, tt.canonical, tt.limit)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if have := numbers(changes); fmt.Sprint(have) != fmt.Sprint(tt.want) {
+			t.Errorf("test %d: blocks mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	changes, _ := ReadBalanceHistory(db, account, 7, 7, canonical, 0)
+	if change := changes[0]; change.Hash != hashes[7] || change.PrevBalance.Int64() != 7 || change.Balance.Int64() != 8 || change.Nonce != 7 {
+		t.Errorf("change mismatch: %+v", change)
+	}
+}
@@ -89,6 +89,8 @@ var (
 
 	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	logIndexPrefix        = []byte("L") // logIndexPrefix + address or topic + section (uint64 big endian) + hash -> block bits
+	balanceHistoryPrefix  = []byte("A") // balanceHistoryPrefix + address + num (uint64 big endian) + hash -> balance change
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	CodePrefix            = []byte("c") // CodePrefix + code hash -> account code
@@ -98,6 +100,7 @@ var (
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	LogIndexPrefix       = []byte("iL") // LogIndexPrefix is the data table of the log indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -210,6 +213,26 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// logIndexKey = logIndexPrefix + address or topic + section (uint64 big endian) + hash
+func logIndexKey(key []byte, section uint64, hash common.Hash) []byte {
+	enc := make([]byte, len(logIndexPrefix)+len(key)+8+common.HashLength)
+	n := copy(enc, logIndexPrefix)
+	n += copy(enc[n:], key)
+	binary.BigEndian.PutUint64(enc[n:], section)
+	copy(enc[n+8:], hash.Bytes())
+	return enc
+}
+
+// balanceHistoryKey = balanceHistoryPrefix + address + num (uint64 big endian) + hash
+func balanceHistoryKey(address common.Address, number uint64, hash common.Hash) []byte {
+	enc := make([]byte, len(balanceHistoryPrefix)+common.AddressLength+8+common.HashLength)
+	n := copy(enc, balanceHistoryPrefix)
+	n += copy(enc[n:], address.Bytes())
+	binary.BigEndian.PutUint64(enc[n:], number)
+	copy(enc[n+8:], hash.Bytes())
+	return enc
+}
+
 // preimageKey = PreimagePrefix + hash
 func preimageKey(hash common.Hash) []byte {
 	return append(PreimagePrefix, hash.Bytes()...)
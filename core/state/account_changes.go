@@ -0,0 +1,84 @@
+// account_changes.go
+
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// AccountChange is a change of the balance or nonce of an account.
+type AccountChange struct {
+	Address     common.Address
+	PrevBalance *big.Int
+	Balance     *big.Int
+	PrevNonce   uint64
+	Nonce       uint64
+}
+
+// AccountChanges returns the changes of the balances and nonces of the accounts
+// since the pre-state, sorted by address. It is to be called once the changes
+// are finalised, before they are committed.
+func (s *StateDB) AccountChanges() ([]*AccountChange, error) {
+	var origin Trie
+	var changes []*AccountChange
+	for addr := range s.stateObjectsDirty {
+		obj := s.stateObjects[addr]
+		if obj == nil {
+			continue
+		}
+		change := &AccountChange{Address: addr, PrevBalance: new(big.Int), Balance: new(big.Int)}
+		if !obj.deleted {
+			change.Balance.Set(obj.Balance())
+			change.Nonce = obj.Nonce()
+		}
+		// Read the account of the pre-state, from the snapshot if any
+		var prev *types.StateAccount
+		if s.snap != nil {
+			if acc, err := s.snap.Account(crypto.HashData(s.hasher, addr.Bytes())); err == nil {
+				if acc != nil {
+					prev = &types.StateAccount{Nonce: acc.Nonce, Balance: acc.Balance}
+				} else {
+					prev = &types.StateAccount{Balance: new(big.Int)}
+				}
+			}
+		}
+		if prev == nil {
+			if origin == nil {
+				tr, err := s.db.OpenTrie(s.originalRoot)
+				if err != nil {
+					return nil, err
+				}
+				origin = tr
+			}
+			enc, err := origin.TryGet(addr.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			prev = &types.StateAccount{Balance: new(big.Int)}
+			if len(enc) > 0 {
+				if err := rlp.DecodeBytes(enc, prev); err != nil {
+					return nil, err
+				}
+			}
+		}
+		change.PrevBalance.Set(prev.Balance)
+		change.PrevNonce = prev.Nonce
+
+		if change.PrevBalance.Cmp(change.Balance) != 0 || change.PrevNonce != change.Nonce {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return bytes.Compare(changes[i].Address[:], changes[j].Address[:]) < 0
+	})
+	return changes, nil
+}
+
+// EOF
@@ -0,0 +1,61 @@
+// account_changes_test.go
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+func TestAccountChanges(t *testing.T) {
+	var (
+		db       = NewDatabase(rawdb.NewMemoryDatabase())
+		sender   = common.HexToAddress("0x01")
+		receiver = common.HexToAddress("0x02")
+		touched  = common.HexToAddress("0x03")
+		created  = common.HexToAddress("0x04")
+	)
+	// The pre-state
+	state, _ := New(common.Hash{}, db, nil)
+	state.SetBalance(sender, big.NewInt(1000))
+	state.SetNonce(sender, 5)
+	state.SetBalance(receiver, big.NewInt(10))
+	state.SetBalance(touched, big.NewInt(7))
+	root, err := state.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A transfer with fee, an account touched without change and a new one
+	state, _ = New(root, db, nil)
+	state.SubBalance(sender, big.NewInt(121))
+	state.SetNonce(sender, 6)
+	state.AddBalance(receiver, big.NewInt(100))
+	state.AddBalance(touched, big.NewInt(0))
+	state.AddBalance(created, big.NewInt(21))
+	state.IntermediateRoot(false)
+
+	changes, err := state.AccountChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*AccountChange{
+		{sender, big.NewInt(1000), big.NewInt(879), 5, 6},
+		{receiver, big.NewInt(10), big.NewInt(110), 0, 0},
+		{created, big.NewInt(0), big.NewInt(21), 0, 0},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("changes mismatch: have %d, want %d", len(changes), len(want))
+	}
+	for i, change := range changes {
+		w := want[i]
+		if change.Address != w.Address || change.PrevBalance.Cmp(w.PrevBalance) != 0 || change.Balance.Cmp(w.Balance) != 0 ||
+			change.PrevNonce != w.PrevNonce || change.Nonce != w.Nonce {
+			t.Errorf("change %d mismatch: have %+v, want %+v", i, change, w)
+		}
+	}
+}
+
+// EOF
@@ -1044,3 +1044,20 @@ func (s *StateDB) AddressInAccessList(addr common.Address) bool {
 func (s *StateDB) SlotInAccessList(addr common.Address, slot common.Hash) (addressPresent bool, slotPresent bool) {
 	return s.accessList.Contains(addr, slot)
 }
+
+// AccessList returns the addresses and storage slots accessed so far by the
+// current transaction.
+func (s *StateDB) AccessList() types.AccessList {
+	list := make(types.AccessList, 0, len(s.accessList.addresses))
+	for addr, idx := range s.accessList.addresses {
+		tuple := types.AccessTuple{Address: addr}
+		if idx >= 0 {
+			tuple.StorageKeys = make([]common.Hash, 0, len(s.accessList.slots[idx]))
+			for slot := range s.accessList.slots[idx] {
+				tuple.StorageKeys = append(tuple.StorageKeys, slot)
+			}
+		}
+		list = append(list, tuple)
+	}
+	return list
+}
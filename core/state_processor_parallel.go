@@ -0,0 +1,174 @@
+// state_processor_parallel.go
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	parallelTxMeter       = metrics.NewRegisteredMeter("chain/parallel/txs", nil)
+	parallelConflictMeter = metrics.NewRegisteredMeter("chain/parallel/conflicts", nil)
+)
+
+// ParallelStateProcessor is a Processor that executes the transactions of a
+// block optimistically in parallel against the parent state, recording what
+// each of them read and changed. The results are then committed in block
+// order: a transaction none of whose reads were changed by the preceding ones
+// has its changes replayed on the block state, the others are re-executed.
+// Receipts and state are identical to the ones of the StateProcessor.
+//
+// ParallelStateProcessor implements Processor.
+type ParallelStateProcessor struct {
+	*StateProcessor
+	workers int // Number of go-routines executing transactions
+}
+
+// NewParallelStateProcessor initialises a new ParallelStateProcessor.
+func NewParallelStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consensus.Engine, workers int) *ParallelStateProcessor {
+	return &ParallelStateProcessor{
+		StateProcessor: NewStateProcessor(config, bc, engine),
+		workers:        workers,
+	}
+}
+
+// speculation is the result of a transaction executed on the parent state.
+type speculation struct {
+	rec    *stateRecorder
+	result *ExecutionResult
+	err    error
+	done   chan struct{}
+}
+
+// Process processes the state changes according to the Ethereum rules, like
+// StateProcessor.Process does, executing the transactions in parallel.
+func (p *ParallelStateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, *big.Int, error) {
+	txs := block.Transactions()
+	parent := p.bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+
+	// Tracers aren't safe for concurrent use, and hard forks mutate the
+	// parent state, run those sequentially.
+	if p.workers <= 1 || len(txs) <= 1 || parent == nil || cfg.Debug ||
+		(p.config.DAOForkSupport && p.config.DAOForkBlock != nil && p.config.DAOForkBlock.Cmp(block.Number()) == 0) {
+		return p.StateProcessor.Process(block, statedb, cfg)
+	}
+
+	var (
+		receipts    types.Receipts
+		usedGas     = new(uint64)
+		fees        = new(big.Int)
+		header      = block.Header()
+		blockHash   = block.Hash()
+		blockNumber = block.Number()
+		allLogs     []*types.Log
+		gp          = new(GasPool).AddGas(block.GasLimit())
+		signer      = types.MakeSigner(p.config, header.Number)
+		specs       = make([]*speculation, len(txs))
+		msgs        = make([]types.Message, len(txs))
+		next        = int32(-1)
+		quit        = make(chan struct{})
+		wg          sync.WaitGroup
+	)
+	for i, tx := range txs {
+		msg, err := tx.AsMessage(signer, header.BaseFee)
+		if err != nil {
+			return nil, nil, 0, big.NewInt(0), fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+		}
+		msgs[i] = msg
+		specs[i] = &speculation{done: make(chan struct{})}
+	}
+	defer func() {
+		close(quit)
+		wg.Wait()
+	}()
+
+	// Execute the transactions on the parent state
+	workers := p.workers
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+	for n := 0; n < workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			blockContext := NewEVMBlockContext(header, p.bc, nil)
+			vmenv := vm.NewEVM(blockContext, vm.TxContext{}, statedb, p.config, cfg)
+			for {
+				i := int(atomic.AddInt32(&next, 1))
+				if i >= len(txs) {
+					return
+				}
+				select {
+				case <-quit:
+					return
+				default:
+				}
+				spec := specs[i]
+				parentState, err := state.New(parent.Root, statedb.Database(), p.bc.snaps)
+				if err != nil {
+					spec.err = err
+					close(spec.done)
+					continue
+				}
+				parentState.Prepare(txs[i].Hash(), i)
+				spec.rec = newStateRecorder(parentState)
+				vmenv.Reset(NewEVMTxContext(msgs[i]), spec.rec)
+				spec.result, spec.err = ApplyMessage(vmenv, msgs[i], new(GasPool).AddGas(block.GasLimit()))
+				close(spec.done)
+			}
+		}()
+	}
+
+	// Commit the results in order, re-executing the ones that read state
+	// changed by the preceding transactions.
+	var (
+		blockContext = NewEVMBlockContext(header, p.bc, nil)
+		vmenv        = vm.NewEVM(blockContext, vm.TxContext{}, statedb, p.config, cfg)
+		writes       = make(map[stateKey]struct{})
+	)
+	for i, tx := range txs {
+		spec, msg := specs[i], msgs[i]
+		<-spec.done
+
+		statedb.Prepare(tx.Hash(), i)
+		rec := newStateRecorder(statedb)
+		result := spec.result
+		if spec.err != nil || gp.Gas() < msg.Gas() || spec.rec.conflicts(writes) {
+			parallelConflictMeter.Mark(1)
+
+			var err error
+			vmenv.Reset(NewEVMTxContext(msg), rec)
+			if result, err = ApplyMessage(vmenv, msg, gp); err != nil {
+				return nil, nil, 0, big.NewInt(0), fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			}
+		} else {
+			spec.rec.replay(rec)
+			if err := gp.SubGas(result.UsedGas); err != nil {
+				return nil, nil, 0, big.NewInt(0), fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			}
+		}
+		for key := range rec.writes {
+			writes[key] = struct{}{}
+		}
+		receipt := makeReceipt(msg, p.config, statedb, blockNumber, blockHash, tx, usedGas, fees, result)
+		receipts = append(receipts, receipt)
+		allLogs = append(allLogs, receipt.Logs...)
+	}
+	parallelTxMeter.Mark(int64(len(txs)))
+
+	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
+	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles())
+
+	return receipts, allLogs, *usedGas, fees, nil
+}
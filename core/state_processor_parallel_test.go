@@ -0,0 +1,116 @@
+// state_processor_parallel_test.go
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that the parallel processor produces the same receipts and state as
+// the sequential one, on blocks with both conflicting and independent
+// transactions.
+func TestParallelStateProcessor(t *testing.T) {
+	defer func(method int) { params.ConsensusMethod = method }(params.ConsensusMethod)
+	params.ConsensusMethod = params.ConsensusPoW
+
+	var (
+		keys    = make([]*ecdsa.PrivateKey, 8)
+		addrs   = make([]common.Address, len(keys))
+		nonces  = make([]uint64, len(keys))
+		funds   = big.NewInt(1000000000000000000)
+		counter = common.HexToAddress("0xc0")
+		perUser = common.HexToAddress("0xc1")
+		suicide = common.HexToAddress("0xc2")
+		sink    = common.HexToAddress("0xc3")
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				// sstore(0, sload(0)+1); log0(0, 0)
+				counter: {Balance: common.Big0, Code: common.FromHex("0x600054600101600055600060" + "00a000")},
+				// sstore(caller, callvalue+1)
+				perUser: {Balance: common.Big0, Code: common.FromHex("0x346001013355" + "00")},
+				// selfdestruct(caller)
+				suicide: {Balance: big.NewInt(1000), Code: common.FromHex("0x33ff")},
+			},
+		}
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		gspec.Alloc[addrs[i]] = GenesisAccount{Balance: funds}
+	}
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(db)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 4, func(n int, b *BlockGen) {
+		send := func(k int, to *common.Address, value int64, data []byte) {
+			var tx *types.Transaction
+			if to == nil {
+				tx = types.NewContractCreation(nonces[k], big.NewInt(value), 100000, b.header.BaseFee, data)
+			} else {
+				tx = types.NewTransaction(nonces[k], *to, big.NewInt(value), 100000, b.header.BaseFee, data)
+			}
+			tx, _ = types.SignTx(tx, signer, keys[k])
+			b.AddTx(tx)
+			nonces[k]++
+		}
+		for k := range keys {
+			send(k, &counter, 0, nil)                // conflicts on the counter
+			send(k, &perUser, int64(n), nil)         // independent slots
+			send(k, &sink, int64(k+1), nil)          // balance changes only
+			send(k, &addrs[(k+1)%len(keys)], 1, nil) // reads balances changed before
+		}
+		send(n, &suicide, 0, nil)
+		send(n, nil, 0, common.FromHex("0x600160005560016000f3"))
+	})
+
+	for _, workers := range []int{0, 1, 2, 4, 16} {
+		db := rawdb.NewMemoryDatabase()
+		gspec.MustCommit(db)
+
+		chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create chain: %v", err)
+		}
+		if workers > 0 {
+			chain.processor = NewParallelStateProcessor(gspec.Config, chain, chain.engine, workers)
+		}
+		for _, block := range blocks {
+			statedb, err := state.New(chain.CurrentBlock().Root(), chain.stateCache, chain.snaps)
+			if err != nil {
+				t.Fatalf("failed to open state: %v", err)
+			}
+			receipts, _, usedGas, _, err := chain.processor.Process(block, statedb, vm.Config{})
+			if err != nil {
+				t.Fatalf("workers %d, block %d: failed to process: %v", workers, block.NumberU64(), err)
+			}
+			if usedGas != block.GasUsed() {
+				t.Errorf("workers %d, block %d: gas used mismatch: have %d, want %d", workers, block.NumberU64(), usedGas, block.GasUsed())
+			}
+			if hash := types.DeriveSha(receipts, trie.NewStackTrie(nil)); hash != block.ReceiptHash() {
+				t.Errorf("workers %d, block %d: receipt hash mismatch: have %x, want %x", workers, block.NumberU64(), hash, block.ReceiptHash())
+			}
+			if root := statedb.IntermediateRoot(true); root != block.Root() {
+				t.Errorf("workers %d, block %d: state root mismatch: have %x, want %x", workers, block.NumberU64(), root, block.Root())
+			}
+			if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+				t.Fatalf("workers %d, block %d: failed to insert: %v", workers, block.NumberU64(), err)
+			}
+		}
+		chain.Stop()
+	}
+}
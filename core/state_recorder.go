@@ -0,0 +1,288 @@
+// state_recorder.go
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// stateKeyKind is the part of an account a stateKey refers to.
+type stateKeyKind byte
+
+const (
+	keyExist      stateKeyKind = iota // existence of the account, incl. storage wipes
+	keyBalance                        // balance
+	keyNonce                          // nonce
+	keyCode                           // code
+	keySlot                           // a single storage slot
+	keyAnyStorage                     // any of the storage slots
+)
+
+// stateKey identifies a piece of state read or written by a transaction.
+type stateKey struct {
+	addr common.Address
+	kind stateKeyKind
+	slot common.Hash
+}
+
+// stateOpKind is the kind of a recorded state mutation.
+type stateOpKind byte
+
+const (
+	opCreateAccount stateOpKind = iota
+	opAddBalance
+	opSubBalance
+	opSetNonce
+	opSetCode
+	opSetState
+	opSuicide
+	opAddLog
+	opAddPreimage
+)
+
+// stateOp is a state mutation made by a transaction.
+type stateOp struct {
+	kind   stateOpKind
+	addr   common.Address
+	key    common.Hash
+	value  common.Hash
+	amount *big.Int
+	nonce  uint64
+	data   []byte
+	log    *types.Log
+}
+
+var ripemd = common.HexToAddress("0000000000000000000000000000000000000003")
+
+// stateRecorder is a vm.StateDB that passes everything through to a StateDB,
+// while recording the state read and the mutations made, so that the latter
+// can be replayed on another StateDB. Mutations in reverted scopes are
+// dropped like the StateDB does.
+type stateRecorder struct {
+	*state.StateDB
+
+	reads  map[stateKey]struct{}
+	writes map[stateKey]struct{}
+	ops    []stateOp
+	marks  map[int]int // snapshot id -> len(ops)
+}
+
+func newStateRecorder(statedb *state.StateDB) *stateRecorder {
+	return &stateRecorder{
+		StateDB: statedb,
+		reads:   make(map[stateKey]struct{}),
+		writes:  make(map[stateKey]struct{}),
+		marks:   make(map[int]int),
+	}
+}
+
+func (r *stateRecorder) read(addr common.Address, kinds ...stateKeyKind) {
+	for _, kind := range kinds {
+		r.reads[stateKey{addr: addr, kind: kind}] = struct{}{}
+	}
+}
+
+func (r *stateRecorder) write(addr common.Address, kinds ...stateKeyKind) {
+	for _, kind := range kinds {
+		r.writes[stateKey{addr: addr, kind: kind}] = struct{}{}
+	}
+}
+
+// mutate records a mutation of an account. An empty account is created or
+// deleted by it, which changes its existence as well.
+func (r *stateRecorder) mutate(op stateOp, apply func(), kinds ...stateKeyKind) {
+	empty := r.StateDB.Empty(op.addr)
+	apply()
+	if empty || r.StateDB.Empty(op.addr) {
+		r.write(op.addr, keyExist)
+	}
+	r.write(op.addr, kinds...)
+	r.ops = append(r.ops, op)
+}
+
+func (r *stateRecorder) CreateAccount(addr common.Address) {
+	r.mutate(stateOp{kind: opCreateAccount, addr: addr}, func() {
+		r.StateDB.CreateAccount(addr)
+	}, keyExist, keyNonce, keyCode, keyAnyStorage)
+}
+
+// AddBalance and SubBalance don't count as balance reads, as balance changes
+// commute with each other.
+func (r *stateRecorder) AddBalance(addr common.Address, amount *big.Int) {
+	r.mutate(stateOp{kind: opAddBalance, addr: addr, amount: new(big.Int).Set(amount)}, func() {
+		r.StateDB.AddBalance(addr, amount)
+	}, keyBalance)
+}
+
+func (r *stateRecorder) SubBalance(addr common.Address, amount *big.Int) {
+	r.mutate(stateOp{kind: opSubBalance, addr: addr, amount: new(big.Int).Set(amount)}, func() {
+		r.StateDB.SubBalance(addr, amount)
+	}, keyBalance)
+}
+
+func (r *stateRecorder) GetBalance(addr common.Address) *big.Int {
+	r.read(addr, keyBalance)
+	return r.StateDB.GetBalance(addr)
+}
+
+func (r *stateRecorder) GetNonce(addr common.Address) uint64 {
+	r.read(addr, keyNonce)
+	return r.StateDB.GetNonce(addr)
+}
+
+func (r *stateRecorder) SetNonce(addr common.Address, nonce uint64) {
+	r.mutate(stateOp{kind: opSetNonce, addr: addr, nonce: nonce}, func() {
+		r.StateDB.SetNonce(addr, nonce)
+	}, keyNonce)
+}
+
+func (r *stateRecorder) GetCodeHash(addr common.Address) common.Hash {
+	r.read(addr, keyExist, keyCode)
+	return r.StateDB.GetCodeHash(addr)
+}
+
+func (r *stateRecorder) GetCode(addr common.Address) []byte {
+	r.read(addr, keyExist, keyCode)
+	return r.StateDB.GetCode(addr)
+}
+
+func (r *stateRecorder) GetCodeSize(addr common.Address) int {
+	r.read(addr, keyExist, keyCode)
+	return r.StateDB.GetCodeSize(addr)
+}
+
+func (r *stateRecorder) SetCode(addr common.Address, code []byte) {
+	r.mutate(stateOp{kind: opSetCode, addr: addr, data: common.CopyBytes(code)}, func() {
+		r.StateDB.SetCode(addr, code)
+	}, keyCode)
+}
+
+func (r *stateRecorder) GetCommittedState(addr common.Address, key common.Hash) common.Hash {
+	r.read(addr, keyExist)
+	r.reads[stateKey{addr: addr, kind: keySlot, slot: key}] = struct{}{}
+	return r.StateDB.GetCommittedState(addr, key)
+}
+
+func (r *stateRecorder) GetState(addr common.Address, key common.Hash) common.Hash {
+	r.read(addr, keyExist)
+	r.reads[stateKey{addr: addr, kind: keySlot, slot: key}] = struct{}{}
+	return r.StateDB.GetState(addr, key)
+}
+
+func (r *stateRecorder) SetState(addr common.Address, key, value common.Hash) {
+	r.mutate(stateOp{kind: opSetState, addr: addr, key: key, value: value}, func() {
+		r.StateDB.SetState(addr, key, value)
+	}, keyAnyStorage)
+	r.writes[stateKey{addr: addr, kind: keySlot, slot: key}] = struct{}{}
+}
+
+func (r *stateRecorder) ForEachStorage(addr common.Address, cb func(common.Hash, common.Hash) bool) error {
+	r.read(addr, keyExist, keyAnyStorage)
+	return r.StateDB.ForEachStorage(addr, cb)
+}
+
+func (r *stateRecorder) Suicide(addr common.Address) bool {
+	var ok bool
+	r.mutate(stateOp{kind: opSuicide, addr: addr}, func() {
+		ok = r.StateDB.Suicide(addr)
+	}, keyExist, keyBalance, keyNonce, keyCode, keyAnyStorage)
+	return ok
+}
+
+func (r *stateRecorder) HasSuicided(addr common.Address) bool {
+	r.read(addr, keyExist)
+	return r.StateDB.HasSuicided(addr)
+}
+
+func (r *stateRecorder) Exist(addr common.Address) bool {
+	r.read(addr, keyExist)
+	return r.StateDB.Exist(addr)
+}
+
+func (r *stateRecorder) Empty(addr common.Address) bool {
+	r.read(addr, keyExist, keyBalance, keyNonce, keyCode)
+	return r.StateDB.Empty(addr)
+}
+
+func (r *stateRecorder) AddLog(log *types.Log) {
+	r.ops = append(r.ops, stateOp{kind: opAddLog, log: &types.Log{
+		Address:     log.Address,
+		Topics:      log.Topics,
+		Data:        log.Data,
+		BlockNumber: log.BlockNumber,
+	}})
+	r.StateDB.AddLog(log)
+}
+
+func (r *stateRecorder) AddPreimage(hash common.Hash, preimage []byte) {
+	r.ops = append(r.ops, stateOp{kind: opAddPreimage, key: hash, data: common.CopyBytes(preimage)})
+	r.StateDB.AddPreimage(hash, preimage)
+}
+
+func (r *stateRecorder) Snapshot() int {
+	id := r.StateDB.Snapshot()
+	r.marks[id] = len(r.ops)
+	return id
+}
+
+func (r *stateRecorder) RevertToSnapshot(id int) {
+	r.StateDB.RevertToSnapshot(id)
+	mark, ok := r.marks[id]
+	if !ok {
+		return
+	}
+	// Keep touches of the ripemd precompile, the StateDB keeps them too
+	// for the sake of consensus, see journal.go.
+	ops := r.ops[:mark]
+	for _, op := range r.ops[mark:] {
+		if op.kind == opAddBalance && op.addr == ripemd && op.amount.Sign() == 0 {
+			ops = append(ops, op)
+		}
+	}
+	r.ops = ops
+}
+
+// conflicts tells if any of the state read by the recorded transaction was
+// changed by the given writes.
+func (r *stateRecorder) conflicts(writes map[stateKey]struct{}) bool {
+	if len(writes) == 0 {
+		return false
+	}
+	for key := range r.reads {
+		if _, ok := writes[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// replay applies the recorded mutations to another recorder.
+func (r *stateRecorder) replay(dst *stateRecorder) {
+	for _, op := range r.ops {
+		switch op.kind {
+		case opCreateAccount:
+			dst.CreateAccount(op.addr)
+		case opAddBalance:
+			dst.AddBalance(op.addr, op.amount)
+		case opSubBalance:
+			dst.SubBalance(op.addr, op.amount)
+		case opSetNonce:
+			dst.SetNonce(op.addr, op.nonce)
+		case opSetCode:
+			dst.SetCode(op.addr, op.data)
+		case opSetState:
+			dst.SetState(op.addr, op.key, op.value)
+		case opSuicide:
+			dst.Suicide(op.addr)
+		case opAddLog:
+			log := *op.log
+			dst.AddLog(&log)
+		case opAddPreimage:
+			dst.AddPreimage(op.key, op.data)
+		}
+	}
+}
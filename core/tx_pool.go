@@ -21,7 +21,6 @@ import (
 	"fmt"
 	"math"
 	"math/big"
-	"runtime"
 	"sort"
 	"sync"
 	"sync/atomic"
@@ -90,6 +89,10 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrTxRateLimited is returned if the sender of a transaction or the peer
+	// it came from exceeds its admission rate.
+	ErrTxRateLimited = errors.New("transaction rate limit exceeded")
 )
 
 var (
@@ -161,6 +164,15 @@ type TxPoolConfig struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	RemoteJournal   string        // Snapshot of remote transactions to survive node restarts, empty to disable
+	RemoteRejournal time.Duration // Time interval to regenerate the remote transaction snapshot
+
+	SenderRate  float64       // Max transactions per second admitted from peers per sender account (0 = unlimited)
+	SenderBurst int           // Max transactions admitted from peers per sender account at once
+	PeerRate    float64       // Max transactions per second admitted per peer (0 = unlimited)
+	PeerBurst   int           // Max transactions admitted per peer at once
+	RatePenalty time.Duration // Time senders and peers exceeding their rate are throttled for, doubled on repeats
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -178,6 +190,12 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	RemoteRejournal: 5 * time.Minute,
+
+	SenderBurst: 1000,
+	PeerBurst:   10000,
+	RatePenalty: time.Minute,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -197,6 +215,18 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.RemoteRejournal < time.Second {
+		log.Warn("Sanitizing invalid txpool remote journal time", "provided", conf.RemoteRejournal, "updated", time.Second)
+		conf.RemoteRejournal = time.Second
+	}
+	if conf.SenderRate < 0 {
+		log.Warn("Sanitizing invalid txpool sender rate", "provided", conf.SenderRate, "updated", 0)
+		conf.SenderRate = 0
+	}
+	if conf.PeerRate < 0 {
+		log.Warn("Sanitizing invalid txpool peer rate", "provided", conf.PeerRate, "updated", 0)
+		conf.PeerRate = 0
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
@@ -256,6 +286,13 @@ type TxPool struct {
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
 
+	remoteJournal *txJournal // Snapshot of remote transactions to back up to disk
+
+	senderLimiter *txRateLimiter // Admission rate limits of senders of transactions from peers
+	peerLimiter   *txRateLimiter // Admission rate limits of peers
+
+	private map[common.Hash]uint64 // Private transactions kept out of public gossip, with their expiry blocks
+
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
 	beats   map[common.Address]time.Time // Last heartbeat from each known account
@@ -296,6 +333,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		pending:         make(map[common.Address]*txList),
 		queue:           make(map[common.Address]*txList),
 		beats:           make(map[common.Address]time.Time),
+		private:         make(map[common.Hash]uint64),
 		all:             newTxLookup(),
 		chainHeadCh:     make(chan ChainHeadEvent, chainHeadChanSize),
 		reqResetCh:      make(chan *txpoolResetRequest),
@@ -305,7 +343,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		reorgShutdownCh: make(chan struct{}),
 		initDoneCh:      make(chan struct{}),
 		gasPrice:        new(big.Int).SetUint64(config.PriceLimit),
-		senderResolver:  NewSenderResolver(runtime.NumCPU()*2, tx2addrCacheSize),
+		senderResolver:  senderCacher,
 	}
 	pool.locals = newAccountSet(pool.signer)
 	for _, addr := range config.Locals {
@@ -313,11 +351,10 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		pool.locals.add(addr)
 	}
 	pool.priced = newTxPricedList(pool.all)
+	pool.senderLimiter = newTxRateLimiter(config.SenderRate, config.SenderBurst, config.RatePenalty, throttledSendersGauge)
+	pool.peerLimiter = newTxRateLimiter(config.PeerRate, config.PeerBurst, config.RatePenalty, throttledPeersGauge)
 	pool.reset(nil, chain.CurrentBlock().Header())
 
-	// Start the ecrecover helper
-	go pool.senderResolver.Run()
-
 	// Start the reorg loop early so it can handle requests generated during journal loading.
 	pool.wg.Add(1)
 	go pool.scheduleReorgLoop()
@@ -333,6 +370,15 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If remote transaction snapshots are enabled, restore the last one. The
+	// transactions are validated against the current head like new ones.
+	if config.RemoteJournal != "" {
+		pool.remoteJournal = newTxJournal(config.RemoteJournal)
+
+		if err := pool.remoteJournal.load(pool.AddRemotesSync); err != nil {
+			log.Warn("Failed to load remote transaction snapshot", "err", err)
+		}
+	}
 
 	// Subscribe events from blockchain and start the main event loop.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
@@ -351,15 +397,17 @@ func (pool *TxPool) loop() {
 	var (
 		prevPending, prevQueued, prevStales int
 		// Start the stats reporting and transaction eviction tickers
-		report  = time.NewTicker(statsReportInterval)
-		evict   = time.NewTicker(evictionInterval)
-		journal = time.NewTicker(pool.config.Rejournal)
+		report   = time.NewTicker(statsReportInterval)
+		evict    = time.NewTicker(evictionInterval)
+		journal  = time.NewTicker(pool.config.Rejournal)
+		snapshot = time.NewTicker(pool.config.RemoteRejournal)
 		// Track the previous head headers for transaction reorgs
 		head = pool.chain.CurrentBlock()
 	)
 	defer report.Stop()
 	defer evict.Stop()
 	defer journal.Stop()
+	defer snapshot.Stop()
 
 	// Notify tests that the init phase is done
 	close(pool.initDoneCh)
@@ -401,6 +449,7 @@ func (pool *TxPool) loop() {
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
 					list := pool.queue[addr].Flatten()
 					for _, tx := range list {
+						traceTx(tx.Hash(), TxEventDropped, "queued too long")
 						pool.removeTx(tx.Hash(), true)
 					}
 					queuedEvictionMeter.Mark(int64(len(list)))
@@ -408,6 +457,10 @@ func (pool *TxPool) loop() {
 			}
 			pool.mu.Unlock()
 
+			now := time.Now()
+			pool.senderLimiter.prune(now)
+			pool.peerLimiter.prune(now)
+
 		// Handle local transaction journal rotation
 		case <-journal.C:
 			if pool.journal != nil {
@@ -417,10 +470,31 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+
+		// Handle remote transaction snapshots
+		case <-snapshot.C:
+			if pool.remoteJournal != nil {
+				pool.snapshotRemotes()
+			}
 		}
 	}
 }
 
+// snapshotRemotes saves the remote transactions to disk, to be restored on
+// the next startup.
+func (pool *TxPool) snapshotRemotes() {
+	pool.mu.RLock()
+	remotes := pool.remote()
+	pool.mu.RUnlock()
+
+	count, err := pool.remoteJournal.write(remotes)
+	if err != nil {
+		log.Warn("Failed to save remote transaction snapshot", "err", err)
+		return
+	}
+	log.Debug("Saved remote transaction snapshot", "transactions", count, "accounts", len(remotes))
+}
+
 // Stop terminates the transaction pool.
 func (pool *TxPool) Stop() {
 	// Unsubscribe all subscriptions registered from txpool
@@ -433,9 +507,9 @@ func (pool *TxPool) Stop() {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
-
-	// Stop ecrecover helper
-	pool.senderResolver.Stop()
+	if pool.remoteJournal != nil {
+		pool.snapshotRemotes()
+	}
 
 	log.Info("Transaction pool stopped")
 }
@@ -467,6 +541,7 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 		// pool.priced is sorted by GasFeeCap, so we have to iterate through pool.all instead
 		drop := pool.all.RemotesBelowTip(price)
 		for _, tx := range drop {
+			traceTx(tx.Hash(), TxEventDropped, "gas tip below minimum")
 			pool.removeTx(tx.Hash(), false)
 		}
 		pool.priced.Removed(len(drop))
@@ -624,6 +699,27 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// remote retrieves all currently known remote transactions, grouped by origin
+// account and sorted by nonce. The returned transaction set is a copy and can be
+// freely modified by calling code.
+func (pool *TxPool) remote() map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+	for _, lists := range []map[common.Address]*txList{pool.pending, pool.queue} {
+		for addr, list := range lists {
+			if pool.locals.contains(addr) {
+				continue
+			}
+			// private transactions are not to be restored as public ones
+			for _, tx := range list.Flatten() {
+				if _, ok := pool.private[tx.Hash()]; !ok {
+					txs[addr] = append(txs[addr], tx)
+				}
+			}
+		}
+	}
+	return txs
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
@@ -755,6 +851,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "gasTipCap", tx.GasTipCap(), "gasFeeCap", tx.GasFeeCap())
 			underpricedTxMeter.Mark(1)
+			traceTx(tx.Hash(), TxEventDropped, "underpriced, pool full")
 			pool.removeTx(tx.Hash(), false)
 		}
 	}
@@ -772,11 +869,13 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			traceTx(old.Hash(), TxEventReplaced, "replaced by "+hash.Hex())
 		}
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
 		pool.journalTx(from, tx)
 		pool.queueTxEvent(tx)
+		traceTx(hash, TxEventPending, "")
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
 
 		// Successful promotion, bump the heartbeat
@@ -798,6 +897,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		localGauge.Inc(1)
 	}
 	pool.journalTx(from, tx)
+	traceTx(hash, TxEventQueued, "")
 
 	log.Trace("Pooled new future transaction", "hash", hash, "from", from, "to", tx.To())
 	return replaced, nil
@@ -823,6 +923,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction, local boo
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		queuedReplaceMeter.Mark(1)
+		traceTx(old.Hash(), TxEventReplaced, "replaced by "+hash.Hex())
 	} else {
 		// Nothing was replaced, bump the queued counter
 		queuedGauge.Inc(1)
@@ -872,6 +973,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.all.Remove(hash)
 		pool.priced.Removed(1)
 		pendingDiscardMeter.Mark(1)
+		traceTx(hash, TxEventDropped, "pending transaction with same nonce priced higher")
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -879,12 +981,14 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		pendingReplaceMeter.Mark(1)
+		traceTx(old.Hash(), TxEventReplaced, "replaced by "+hash.Hex())
 	} else {
 		// Nothing was replaced, bump the pending counter
 		pendingGauge.Inc(1)
 	}
 	// Set the potentially new pending nonce and notify any subsystems of the new tx
 	pool.pendingNonces.set(addr, tx.Nonce()+1)
+	traceTx(hash, TxEventPromoted, "")
 
 	// Successful promotion, bump the heartbeat
 	pool.beats[addr] = time.Now()
@@ -897,8 +1001,9 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 // This method is used to add transactions from the RPC API and performs synchronous pool
 // reorganization and event propagation.
 func (pool *TxPool) AddLocals(txs []*types.Transaction) []error {
+	traceTxs(txs, TxEventSubmitted, "")
 	pool.ResolveSenders(pool.signer, txs)
-	return pool.addTxs(txs, !pool.config.NoLocals, true)
+	return pool.addTxs("", txs, !pool.config.NoLocals, true)
 }
 
 // AddLocal enqueues a single local transaction into the pool if it is valid. This is
@@ -914,14 +1019,21 @@ func (pool *TxPool) AddLocal(tx *types.Transaction) error {
 // This method is used to add transactions from the p2p network and does not wait for pool
 // reorganization and internal event propagation.
 func (pool *TxPool) AddRemotes(txs []*types.Transaction) []error {
-	pool.ResolveSenders(pool.signer, txs)
-	return pool.addTxs(txs, false, false)
+	pool.senderResolver.RecoverSync(SenderLaneGossip, pool.signer, txs)
+	return pool.addTxs("", txs, false, false)
+}
+
+// AddRemotesFrom is like AddRemotes, for transactions received from the given
+// peer. The admission rates of the peer and of the senders are limited.
+func (pool *TxPool) AddRemotesFrom(peer string, txs []*types.Transaction) []error {
+	pool.senderResolver.RecoverSync(SenderLaneGossip, pool.signer, txs)
+	return pool.addTxs(peer, txs, false, false)
 }
 
 // This is like AddRemotes, but waits for pool reorganization. Tests use this method.
 func (pool *TxPool) AddRemotesSync(txs []*types.Transaction) []error {
-	pool.ResolveSenders(pool.signer, txs)
-	return pool.addTxs(txs, false, true)
+	pool.senderResolver.RecoverSync(SenderLaneGossip, pool.signer, txs)
+	return pool.addTxs("", txs, false, true)
 }
 
 // This is like AddRemotes with a single transaction, but waits for pool reorganization. Tests use this method.
@@ -940,11 +1052,12 @@ func (pool *TxPool) AddRemote(tx *types.Transaction) error {
 }
 
 // addTxs attempts to queue a batch of transactions if they are valid.
-func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
+func (pool *TxPool) addTxs(peer string, txs []*types.Transaction, local, sync bool) []error {
 	// Filter out known ones without obtaining the pool lock or recovering signatures
 	var (
 		errs = make([]error, len(txs))
 		news = make([]*types.Transaction, 0, len(txs))
+		now  = time.Now()
 	)
 	for i, tx := range txs {
 		// If the transaction is known, pre-set the error slot
@@ -956,12 +1069,28 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 		// Exclude transactions with invalid signatures as soon as
 		// possible and cache senders in transactions before
 		// obtaining lock
-		_, err := types.Sender(pool.signer, tx)
+		from, err := types.Sender(pool.signer, tx)
 		if err != nil {
 			errs[i] = ErrInvalidSender
 			invalidTxMeter.Mark(1)
+			traceTx(tx.Hash(), TxEventRejected, errs[i].Error())
 			continue
 		}
+		// Enforce the admission rates of transactions from peers
+		if peer != "" {
+			if !pool.peerLimiter.allow(peer, now) {
+				errs[i] = ErrTxRateLimited
+				peerRateLimitMeter.Mark(1)
+				traceTx(tx.Hash(), TxEventRejected, "peer "+errs[i].Error())
+				continue
+			}
+			if !pool.senderLimiter.allow(from.Hex(), now) {
+				errs[i] = ErrTxRateLimited
+				senderRateLimitMeter.Mark(1)
+				traceTx(tx.Hash(), TxEventRejected, "sender "+errs[i].Error())
+				continue
+			}
+		}
 		// Accumulate all unknown transactions for deeper processing
 		news = append(news, tx)
 	}
@@ -998,6 +1127,9 @@ func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local bool) ([]error,
 	for i, tx := range txs {
 		replaced, err := pool.add(tx, local)
 		errs[i] = err
+		if err != nil && err != ErrAlreadyKnown {
+			traceTx(tx.Hash(), TxEventRejected, err.Error())
+		}
 		if err == nil && !replaced {
 			dirty.addTx(tx)
 		}
@@ -1029,6 +1161,13 @@ func (pool *TxPool) Status(hashes []common.Hash) []TxStatus {
 	return status
 }
 
+// Throttled returns the senders and the peers whose transactions are being
+// rejected for exceeding their admission rates.
+func (pool *TxPool) Throttled() (senders []*ThrottledEntity, peers []*ThrottledEntity) {
+	now := time.Now()
+	return pool.senderLimiter.throttled(now), pool.peerLimiter.throttled(now)
+}
+
 // Get returns a transaction if it is contained in the pool and nil otherwise.
 func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
 	return pool.all.Get(hash)
@@ -1273,7 +1412,10 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 // of the transaction pool is valid with regard to the chain state.
 func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// If we're reorging an old state, reinject all dropped transactions
-	var reinject types.Transactions
+	var (
+		reinject types.Transactions
+		added    []*types.Block // new canonical blocks, for tracing
+	)
 
 	if oldHead != nil && oldHead.Hash() != newHead.ParentHash {
 		// If the reorg is too deep, avoid doing it (will happen during fast sync)
@@ -1314,6 +1456,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 				}
 				for add.NumberU64() > rem.NumberU64() {
 					included = append(included, add.Transactions()...)
+					added = append(added, add)
 					if add = pool.chain.GetBlock(add.ParentHash(), add.NumberU64()-1); add == nil {
 						log.Error("Unrooted new chain seen by tx pool", "block", newHead.Number, "hash", newHead.Hash())
 						return
@@ -1326,6 +1469,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 						return
 					}
 					included = append(included, add.Transactions()...)
+					added = append(added, add)
 					if add = pool.chain.GetBlock(add.ParentHash(), add.NumberU64()-1); add == nil {
 						log.Error("Unrooted new chain seen by tx pool", "block", newHead.Number, "hash", newHead.Hash())
 						return
@@ -1338,6 +1482,10 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// Initialize the internal state to the current head
 	if newHead == nil {
 		newHead = pool.chain.CurrentBlock().Header() // Special case during testing
+	} else if oldHead != nil && oldHead.Hash() == newHead.ParentHash {
+		if block := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64()); block != nil {
+			added = append(added, block)
+		}
 	}
 	statedb, err := pool.chain.StateAt(newHead.Root)
 	if err != nil {
@@ -1350,9 +1498,18 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
-	senderCacher.recover(pool.signer, reinject)
+	pool.senderResolver.Recover(SenderLaneTxPool, pool.signer, reinject)
 	pool.addTxsLocked(reinject, false)
 
+	// Drop the private transactions that can no longer be included
+	pool.expirePrivates(newHead.Number.Uint64())
+
+	// Record the inclusion of the known transactions, oldest block first
+	for i, j := 0, len(added)-1; i < j; i, j = i+1, j-1 {
+		added[i], added[j] = added[j], added[i]
+	}
+	traceIncluded(added)
+
 	// Update all fork indicator by next pending block number.
 	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
 	pool.istanbul = pool.chainconfig.IsIstanbul(next)
@@ -1378,6 +1535,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		for _, tx := range forwards {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			traceTx(hash, TxEventDropped, "nonce too low, mined or superseded")
 		}
 		log.Trace("Removed old queued transactions", "count", len(forwards))
 		// Drop all transactions that are too costly (low balance or out of gas)
@@ -1385,6 +1543,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		for _, tx := range drops {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			traceTx(hash, TxEventDropped, "insufficient funds or gas above block limit")
 		}
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
@@ -1407,6 +1566,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			for _, tx := range caps {
 				hash := tx.Hash()
 				pool.all.Remove(hash)
+				traceTx(hash, TxEventDropped, "account queue full")
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 			queuedRateLimitMeter.Mark(int64(len(caps)))
@@ -1469,6 +1629,7 @@ func (pool *TxPool) truncatePending() {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
 						pool.all.Remove(hash)
+						traceTx(hash, TxEventDropped, "pending pool full")
 
 						// Update the account nonce to the dropped transaction
 						pool.pendingNonces.setIfLower(offenders[i], tx.Nonce())
@@ -1496,6 +1657,7 @@ func (pool *TxPool) truncatePending() {
 					// Drop the transaction from the global pools too
 					hash := tx.Hash()
 					pool.all.Remove(hash)
+					traceTx(hash, TxEventDropped, "pending pool full")
 
 					// Update the account nonce to the dropped transaction
 					pool.pendingNonces.setIfLower(addr, tx.Nonce())
@@ -1542,6 +1704,7 @@ func (pool *TxPool) truncateQueue() {
 		// Drop all transactions if they are less than the overflow
 		if size := uint64(list.Len()); size <= drop {
 			for _, tx := range list.Flatten() {
+				traceTx(tx.Hash(), TxEventDropped, "queue full")
 				pool.removeTx(tx.Hash(), true)
 			}
 			drop -= size
@@ -1551,6 +1714,7 @@ func (pool *TxPool) truncateQueue() {
 		// Otherwise drop only last few transactions
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
+			traceTx(txs[i].Hash(), TxEventDropped, "queue full")
 			pool.removeTx(txs[i].Hash(), true)
 			drop--
 			queuedRateLimitMeter.Mark(1)
@@ -1575,6 +1739,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		for _, tx := range olds {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			traceTx(hash, TxEventDropped, "nonce too low, mined or superseded")
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
@@ -1583,6 +1748,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
 			pool.all.Remove(hash)
+			traceTx(hash, TxEventDropped, "insufficient funds or gas above block limit")
 		}
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
@@ -1592,6 +1758,7 @@ func (pool *TxPool) demoteUnexecutables() {
 
 			// Internal shuffle shouldn't touch the lookup set.
 			pool.enqueueTx(hash, tx, false, false)
+			traceTx(hash, TxEventDemoted, "preceding transaction dropped")
 		}
 		pendingGauge.Dec(int64(len(olds) + len(drops) + len(invalids)))
 		if pool.locals.contains(addr) {
@@ -1606,6 +1773,7 @@ func (pool *TxPool) demoteUnexecutables() {
 
 				// Internal shuffle shouldn't touch the lookup set.
 				pool.enqueueTx(hash, tx, false, false)
+				traceTx(hash, TxEventDemoted, "nonce gap")
 			}
 			pendingGauge.Dec(int64(len(gapped)))
 			// This might happen in a reorg, so log it to the metering
@@ -2362,6 +2362,346 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that remote transactions are snapshotted on shutdown if enabled, and
+// restored on startup after being revalidated against the new head.
+func TestTransactionRemoteJournaling(t *testing.T) {
+	t.Parallel()
+
+	// Create a temporary file for the snapshot
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary snapshot: %v", err)
+	}
+	journal := file.Name()
+	defer os.Remove(journal)
+
+	file.Close()
+	os.Remove(journal)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.NoLocals = true
+	config.RemoteJournal = journal
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	// Add pending and queued transactions of two remote accounts
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key1.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(key2.PublicKey), big.NewInt(1000000000))
+
+	txs := []*types.Transaction{
+		pricedTransaction(0, 100000, big.NewInt(1), key1),
+		pricedTransaction(1, 100000, big.NewInt(1), key1),
+		pricedTransaction(0, 100000, big.NewInt(1), key2),
+		pricedTransaction(2, 100000, big.NewInt(1), key2),
+	}
+	for i, err := range pool.AddRemotesSync(txs) {
+		if err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", i, err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 3 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want 3/1", pending, queued)
+	}
+	// Restart on a head including the first transaction of the first account
+	pool.Stop()
+	statedb.SetNonce(crypto.PubkeyToAddress(key1.PublicKey), 1)
+	blockchain = &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("restored pool stats mismatch: have %d/%d, want 2/1", pending, queued)
+	}
+	if pool.Get(txs[0].Hash()) != nil {
+		t.Fatalf("stale transaction restored")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that transactions from peers are admitted at the configured rates of
+// the peers and of the senders, and that the rates are not applied to other
+// submissions.
+func TestTransactionRateLimit(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.SenderRate, config.SenderBurst = 0.001, 2
+	config.PeerRate, config.PeerBurst = 0.001, 5
+	config.RatePenalty = time.Hour
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000000))
+	}
+	// The sender allowance runs out before the peer one
+	errs := pool.AddRemotesFrom("peer1", []*types.Transaction{
+		pricedTransaction(0, 100000, big.NewInt(1), keys[0]),
+		pricedTransaction(1, 100000, big.NewInt(1), keys[0]),
+		pricedTransaction(2, 100000, big.NewInt(1), keys[0]),
+		pricedTransaction(0, 100000, big.NewInt(1), keys[1]),
+		pricedTransaction(1, 100000, big.NewInt(1), keys[1]),
+	})
+	for i, want := range []error{nil, nil, ErrTxRateLimited, nil, nil} {
+		if errs[i] != want {
+			t.Errorf("tx %d: error mismatch: have %v, want %v", i, errs[i], want)
+		}
+	}
+	// Throttled peers are rejected, other peers not
+	if err := pool.AddRemotesFrom("peer1", []*types.Transaction{pricedTransaction(0, 100000, big.NewInt(1), keys[2])})[0]; err != ErrTxRateLimited {
+		t.Errorf("throttled peer admitted: %v", err)
+	}
+	if err := pool.AddRemotesFrom("peer2", []*types.Transaction{pricedTransaction(0, 100000, big.NewInt(1), keys[2])})[0]; err != nil {
+		t.Errorf("transaction of another peer rejected: %v", err)
+	}
+	// Throttled senders are still rejected through other peers, but not when
+	// submitted otherwise
+	if err := pool.AddRemotesFrom("peer2", []*types.Transaction{pricedTransaction(2, 100000, big.NewInt(1), keys[0])})[0]; err != ErrTxRateLimited {
+		t.Errorf("throttled sender admitted: %v", err)
+	}
+	if err := pool.AddRemotesSync([]*types.Transaction{pricedTransaction(2, 100000, big.NewInt(1), keys[0])})[0]; err != nil {
+		t.Errorf("unlimited submission rejected: %v", err)
+	}
+	senders, peers := pool.Throttled()
+	if len(senders) != 1 || len(peers) != 1 || peers[0].ID != "peer1" || peers[0].Rejected != 1 {
+		t.Errorf("throttled entities mismatch: senders %d, peers %d", len(senders), len(peers))
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that private transactions are kept apart from the public ones and
+// dropped once expired.
+func TestTransactionPrivate(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key1.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(key2.PublicKey), big.NewInt(1000000000))
+
+	private := pricedTransaction(0, 100000, big.NewInt(1), key1)
+	public := pricedTransaction(0, 100000, big.NewInt(1), key2)
+
+	if err := pool.AddPrivate(private, 0); err != ErrPrivateTxExpired {
+		t.Fatalf("expired private transaction error mismatch: have %v, want %v", err, ErrPrivateTxExpired)
+	}
+	if err := pool.AddPrivate(private, 2); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	if err := pool.AddPrivate(private, 3); err != ErrAlreadyKnown {
+		t.Fatalf("known private transaction error mismatch: have %v, want %v", err, ErrAlreadyKnown)
+	}
+	if err := pool.AddRemotesSync([]*types.Transaction{public})[0]; err != nil {
+		t.Fatalf("failed to add public transaction: %v", err)
+	}
+	if expiry, ok := pool.PrivateExpiry(private.Hash()); !ok || expiry != 2 {
+		t.Fatalf("private transaction expiry mismatch: have %d/%v, want 2/true", expiry, ok)
+	}
+	if _, ok := pool.PrivateExpiry(public.Hash()); ok {
+		t.Fatalf("public transaction reported private")
+	}
+	// Private transactions are not snapshotted as remote ones
+	pool.mu.RLock()
+	remotes := pool.remote()
+	pool.mu.RUnlock()
+	if len(remotes) != 1 || len(remotes[crypto.PubkeyToAddress(key2.PublicKey)]) != 1 {
+		t.Fatalf("remote transactions mismatch: have %v", remotes)
+	}
+	// Private transactions are dropped when their expiry block is reached
+	for number, alive := range []bool{true, true, false} {
+		<-pool.requestReset(nil, &types.Header{Number: big.NewInt(int64(number)), GasLimit: 1000000, BaseFee: big.NewInt(1)})
+		if (pool.Get(private.Hash()) != nil) != alive {
+			t.Fatalf("block %d: private transaction presence mismatch: want %v", number, alive)
+		}
+		if pool.Get(public.Hash()) == nil {
+			t.Fatalf("block %d: public transaction dropped", number)
+		}
+	}
+	if _, ok := pool.PrivateExpiry(private.Hash()); ok {
+		t.Fatalf("expired private transaction still tracked")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that private transactions which left the pool, e.g. when included in
+// a block, stay private if put back by a reorg before their expiry.
+func TestTransactionPrivateReinject(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	private := pricedTransaction(0, 100000, big.NewInt(1), key)
+	if err := pool.AddPrivates("peer1", []*types.Transaction{private}, []uint64{3})[0]; err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	// Included in block 1
+	pool.mu.Lock()
+	pool.removeTx(private.Hash(), true)
+	pool.mu.Unlock()
+	<-pool.requestReset(nil, &types.Header{Number: big.NewInt(1), GasLimit: 1000000, BaseFee: big.NewInt(1)})
+	if _, ok := pool.PrivateExpiry(private.Hash()); !ok {
+		t.Fatalf("included private transaction forgotten before its expiry")
+	}
+	// Put back by a reorg
+	if err := pool.AddRemotesSync([]*types.Transaction{private})[0]; err != nil {
+		t.Fatalf("failed to reinject private transaction: %v", err)
+	}
+	if expiry, ok := pool.PrivateExpiry(private.Hash()); !ok || expiry != 3 {
+		t.Fatalf("reinjected private transaction expiry mismatch: have %d/%v, want 3/true", expiry, ok)
+	}
+	pool.mu.RLock()
+	remotes := pool.remote()
+	pool.mu.RUnlock()
+	if len(remotes) != 0 {
+		t.Fatalf("reinjected private transaction snapshotted as remote")
+	}
+	<-pool.requestReset(nil, &types.Header{Number: big.NewInt(3), GasLimit: 1000000, BaseFee: big.NewInt(1)})
+	if pool.Get(private.Hash()) != nil {
+		t.Fatalf("expired private transaction still in the pool")
+	}
+	if _, ok := pool.PrivateExpiry(private.Hash()); ok {
+		t.Fatalf("expired private transaction still tracked")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the lifecycle events of transactions are recorded.
+func TestTransactionTrace(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	var (
+		gapped   = pricedTransaction(1, 100000, big.NewInt(1), key)
+		first    = pricedTransaction(0, 100000, big.NewInt(1), key)
+		replaced = pricedTransaction(0, 100000, big.NewInt(2), key)
+		cheap    = pricedTransaction(0, 100000, big.NewInt(1), key)
+	)
+	pool.AddLocal(gapped)
+	pool.AddLocal(first)
+	pool.AddLocal(replaced)
+	pool.AddLocal(cheap) // same as first, now underpriced
+	<-pool.requestPromoteExecutables(nil)
+
+	check := func(tx *types.Transaction, want ...string) {
+		t.Helper()
+		var have []string
+		for _, ev := range TxTrace(tx.Hash()) {
+			have = append(have, ev.Event)
+		}
+		if fmt.Sprint(have) != fmt.Sprint(want) {
+			t.Errorf("events mismatch: have %v, want %v", have, want)
+		}
+	}
+	check(gapped, TxEventSubmitted, TxEventQueued, TxEventPromoted)
+	check(first, TxEventSubmitted, TxEventQueued, TxEventPromoted, TxEventReplaced, TxEventSubmitted, TxEventRejected)
+	check(replaced, TxEventSubmitted, TxEventPending)
+
+	if events := TxTrace(first.Hash()); events[3].Reason != "replaced by "+replaced.Hash().Hex() {
+		t.Errorf("replacement reason mismatch: %q", events[3].Reason)
+	}
+	// Only the latest events are kept
+	hash := common.Hash{0x1}
+	for i := 0; i < 2*txTraceEvents; i++ {
+		TraceTx(hash, &TxTraceEvent{Event: TxEventReceived, Peers: i})
+	}
+	if events := TxTrace(hash); len(events) != txTraceEvents || events[len(events)-1].Peers != 2*txTraceEvents-1 {
+		t.Errorf("bounded events mismatch: %d events", len(events))
+	}
+}
+
+// includingBlockChain is a testBlockChain returning the given block as the
+// new head.
+type includingBlockChain struct {
+	*testBlockChain
+	block *types.Block
+}
+
+func (bc *includingBlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if hash == bc.block.Hash() {
+		return bc.block
+	}
+	return bc.testBlockChain.GetBlock(hash, number)
+}
+
+// Tests that the inclusion of traced transactions is recorded when a new head
+// is imported, with the miner of the block.
+func TestTransactionTraceIncluded(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	key, _ := crypto.GenerateKey()
+	var (
+		tx       = pricedTransaction(0, 100000, big.NewInt(1), key)
+		unseen   = pricedTransaction(1, 100000, big.NewInt(1), key)
+		oldHead  = &types.Header{Number: big.NewInt(0), GasLimit: 1000000, BaseFee: big.NewInt(1)}
+		coinbase = common.Address{0xc0}
+		block    = types.NewBlock(&types.Header{
+			ParentHash: oldHead.Hash(),
+			Number:     big.NewInt(1),
+			Coinbase:   coinbase,
+			GasLimit:   1000000,
+			BaseFee:    big.NewInt(1),
+		}, []*types.Transaction{tx, unseen}, nil, nil, trie.NewStackTrie(nil))
+		blockchain = &includingBlockChain{&testBlockChain{1000000, statedb, new(event.Feed)}, block}
+	)
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	if err := pool.AddRemotesSync([]*types.Transaction{tx})[0]; err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	<-pool.requestReset(oldHead, block.Header())
+
+	events := TxTrace(tx.Hash())
+	if len(events) == 0 {
+		t.Fatalf("no events traced")
+	}
+	ev := events[len(events)-1]
+	if ev.Event != TxEventIncluded || ev.Block == nil || uint64(*ev.Block) != 1 || *ev.BlockHash != block.Hash() || *ev.Miner != coinbase {
+		t.Errorf("inclusion event mismatch: have %+v", ev)
+	}
+	if events := TxTrace(unseen.Hash()); events != nil {
+		t.Errorf("unseen transaction traced: %v", events)
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
@@ -0,0 +1,114 @@
+// tx_private.go
+
+package core
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	// ErrPrivateTxExpired is returned if a private transaction's expiry block
+	// is already in the chain.
+	ErrPrivateTxExpired = errors.New("private transaction expired")
+
+	privateTxGauge       = metrics.NewRegisteredGauge("txpool/private", nil)
+	privateExpiredMeter  = metrics.NewRegisteredMeter("txpool/private/expired", nil)
+	privateAcceptedMeter = metrics.NewRegisteredMeter("txpool/private/accepted", nil)
+)
+
+// AddPrivates adds private transactions from the given peer to the pool.
+// Private transactions are only forwarded to partners, not gossiped, and are
+// dropped once the chain reaches their expiry blocks, the last blocks they can
+// be included in. The admission rates of the peer and of the senders are
+// limited like those of the public ones.
+func (pool *TxPool) AddPrivates(peer string, txs []*types.Transaction, expiries []uint64) []error {
+	pool.ResolveSenders(pool.signer, txs)
+
+	var (
+		errs = make([]error, len(txs))
+		news = make([]*types.Transaction, 0, len(txs))
+		idxs = make([]int, 0, len(txs))
+		prev = make(map[common.Hash]uint64) // expiries of the ones already included
+		head = pool.chain.CurrentBlock().NumberU64()
+	)
+	// Mark the transactions private before they get announced
+	pool.mu.Lock()
+	for i, tx := range txs {
+		if expiries[i] <= head {
+			errs[i] = ErrPrivateTxExpired
+			continue
+		}
+		if pool.all.Get(tx.Hash()) != nil {
+			errs[i] = ErrAlreadyKnown
+			continue
+		}
+		if expiry, ok := pool.private[tx.Hash()]; ok {
+			prev[tx.Hash()] = expiry
+		}
+		pool.private[tx.Hash()] = expiries[i]
+		news = append(news, tx)
+		idxs = append(idxs, i)
+	}
+	pool.mu.Unlock()
+
+	if len(news) == 0 {
+		return errs
+	}
+	added := pool.addTxs(peer, news, false, true)
+
+	pool.mu.Lock()
+	for j, err := range added {
+		if expiry, ok := prev[news[j].Hash()]; err != nil && ok {
+			pool.private[news[j].Hash()] = expiry
+		} else if err != nil {
+			delete(pool.private, news[j].Hash())
+		} else {
+			privateAcceptedMeter.Mark(1)
+		}
+		errs[idxs[j]] = err
+	}
+	privateTxGauge.Update(int64(len(pool.private)))
+	pool.mu.Unlock()
+
+	return errs
+}
+
+// AddPrivate adds a local private transaction to the pool, like AddPrivates.
+func (pool *TxPool) AddPrivate(tx *types.Transaction, expiry uint64) error {
+	return pool.AddPrivates("", []*types.Transaction{tx}, []uint64{expiry})[0]
+}
+
+// PrivateExpiry returns the expiry block of a private transaction, false if
+// the transaction is not a private one in the pool.
+func (pool *TxPool) PrivateExpiry(hash common.Hash) (uint64, bool) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	expiry, ok := pool.private[hash]
+	return expiry, ok
+}
+
+// expirePrivates drops the private transactions that can no longer be
+// included after the given head. The ones no longer in the pool, e.g. those
+// included in blocks, are remembered until then as well, so that they stay
+// private if a reorg puts them back. The caller must hold pool.mu.
+func (pool *TxPool) expirePrivates(head uint64) {
+	for hash, expiry := range pool.private {
+		if expiry > head {
+			continue
+		}
+		if pool.all.Get(hash) != nil {
+			traceTx(hash, TxEventDropped, "private transaction expired")
+			pool.removeTx(hash, true)
+			privateExpiredMeter.Mark(1)
+		}
+		delete(pool.private, hash)
+	}
+	privateTxGauge.Update(int64(len(pool.private)))
+}
+
+// EOF
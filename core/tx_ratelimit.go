@@ -0,0 +1,156 @@
+// tx_ratelimit.go
+
+package core
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	senderRateLimitMeter = metrics.NewRegisteredMeter("txpool/ratelimit/sender", nil) // rejected by sender rate limit
+	peerRateLimitMeter   = metrics.NewRegisteredMeter("txpool/ratelimit/peer", nil)   // rejected by peer rate limit
+
+	throttledSendersGauge = metrics.NewRegisteredGauge("txpool/ratelimit/throttled/senders", nil)
+	throttledPeersGauge   = metrics.NewRegisteredGauge("txpool/ratelimit/throttled/peers", nil)
+)
+
+// ThrottledEntity is a sender account or peer whose transactions are being
+// rejected for exceeding its admission rate.
+type ThrottledEntity struct {
+	ID         string    `json:"id"`
+	Until      time.Time `json:"until"`
+	Violations int       `json:"violations"`
+	Rejected   uint64    `json:"rejected"`
+}
+
+// rateBucket is the token bucket of a rate limited entity.
+type rateBucket struct {
+	tokens     float64
+	last       time.Time
+	until      time.Time // rejects everything until then once over the limit
+	violations int       // times the limit was exceeded
+	rejected   uint64    // transactions rejected
+}
+
+// txRateLimiter limits the rate at which entities, sender accounts or peers,
+// get transactions admitted into the pool, with a token bucket per entity.
+// Entities going over the limit are throttled for a penalty period, doubled
+// on every repeated violation up to 16 times.
+type txRateLimiter struct {
+	rate    float64       // transactions per second, 0 for no limit
+	burst   float64       // bucket size
+	penalty time.Duration // base throttling period on violation
+	gauge   metrics.Gauge
+
+	mu      sync.Mutex
+	buckets map[string]*rateBucket
+}
+
+func newTxRateLimiter(rate float64, burst int, penalty time.Duration, gauge metrics.Gauge) *txRateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &txRateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		penalty: penalty,
+		gauge:   gauge,
+		buckets: make(map[string]*rateBucket),
+	}
+}
+
+// enabled tells if the limiter limits anything.
+func (l *txRateLimiter) enabled() bool {
+	return l != nil && l.rate > 0
+}
+
+// allow consumes a token of the entity if admitted.
+func (l *txRateLimiter) allow(id string, now time.Time) bool {
+	if !l.enabled() {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.buckets[id]
+	if b == nil {
+		b = &rateBucket{tokens: l.burst, last: now}
+		l.buckets[id] = b
+	}
+	if now.Before(b.until) {
+		b.rejected++
+		return false
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+	b.rejected++
+	b.violations++
+	if l.penalty > 0 {
+		shift := b.violations - 1
+		if shift > 4 {
+			shift = 4
+		}
+		b.until = now.Add(l.penalty << shift)
+	}
+	return false
+}
+
+// prune drops the buckets of the entities back to their full allowance and
+// not throttled, which are the same as no bucket. Violations are forgotten
+// once the bucket has been idle for a while.
+func (l *txRateLimiter) prune(now time.Time) {
+	if !l.enabled() {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	throttled := 0
+	for id, b := range l.buckets {
+		if now.Before(b.until) {
+			throttled++
+			continue
+		}
+		refill := b.tokens + now.Sub(b.last).Seconds()*l.rate
+		if refill >= l.burst && now.Sub(b.last) > 16*l.penalty {
+			delete(l.buckets, id)
+		}
+	}
+	l.gauge.Update(int64(throttled))
+}
+
+// throttled returns the entities currently throttled.
+func (l *txRateLimiter) throttled(now time.Time) []*ThrottledEntity {
+	list := []*ThrottledEntity{}
+	if !l.enabled() {
+		return list
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for id, b := range l.buckets {
+		if now.Before(b.until) {
+			list = append(list, &ThrottledEntity{
+				ID:         id,
+				Until:      b.until,
+				Violations: b.violations,
+				Rejected:   b.rejected,
+			})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// EOF
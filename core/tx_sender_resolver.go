@@ -4,100 +4,288 @@ package core
 
 import (
 	"sync"
-	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
 )
 
-// job structure is needed because param is necessary due to evaluation order
-// uncertainty with closure and channel.
-type job struct {
-	f     func(interface{})
-	param interface{}
+// SenderLane is the priority class of a sender recovery request. Workers
+// always take the pending batches of a lower lane first.
+type SenderLane int
+
+const (
+	SenderLaneImport SenderLane = iota // block import and chain sync
+	SenderLaneTxPool                   // local and re-injected transactions
+	SenderLaneGossip                   // transactions from peers
+	numSenderLanes
+)
+
+func (l SenderLane) String() string {
+	switch l {
+	case SenderLaneImport:
+		return "import"
+	case SenderLaneTxPool:
+		return "txpool"
+	case SenderLaneGossip:
+		return "gossip"
+	}
+	return "unknown"
 }
 
-// SenderResolver resolves sender accounts from transactions concurrently
-// with worker threads
+// senderBatchSize is the max number of transactions a worker recovers in
+// one go.
+const senderBatchSize = 32
+
+var (
+	senderHitMeter     = metrics.NewRegisteredMeter("txsender/hit", nil)     // served from the caches
+	senderRecoverMeter = metrics.NewRegisteredMeter("txsender/recover", nil) // ecrecovered
+	senderFailMeter    = metrics.NewRegisteredMeter("txsender/fail", nil)    // invalid signatures
+	senderRecoverTimer = metrics.NewRegisteredTimer("txsender/recover/time", nil)
+
+	senderWaitTimers [numSenderLanes]metrics.Timer // time batches spend queued
+)
+
+func init() {
+	for lane := SenderLane(0); lane < numSenderLanes; lane++ {
+		senderWaitTimers[lane] = metrics.NewRegisteredTimer("txsender/"+lane.String()+"/wait", nil)
+	}
+}
+
+// cachedSender is a sender recovered with a signer.
+type cachedSender struct {
+	signer types.Signer
+	from   common.Address
+}
+
+// senderBatch is a batch of transactions to recover the senders of.
+type senderBatch struct {
+	signer types.Signer
+	txs    []*types.Transaction
+	lane   SenderLane
+	queued time.Time
+	done   *sync.WaitGroup // nil if nobody waits for the batch
+}
+
+// SenderResolver recovers transaction senders concurrently with worker
+// threads for block import, the downloader and the transaction pool. The
+// senders are cached in the transactions themselves and, once ecrecovered,
+// by transaction hash in an LRU cache shared by all, so that a transaction
+// seen through several paths is ecrecovered once.
 type SenderResolver struct {
 	tx2addr *lru.LruCache
-	jobs    chan *job
-	busy    chan interface{}
+	threads int
+	lanes   [numSenderLanes]chan *senderBatch
+
+	lock     sync.RWMutex // held by enqueuers, so that Stop can't race them
+	stopped  bool
+	quit     chan struct{}
+	stopOnce sync.Once
 }
 
-// NewSenderResolver creates a new sender resolver worker pool
-func NewSenderResolver(concurrency, cacheSize int) *SenderResolver {
-	return &SenderResolver{
+// NewSenderResolver creates a new sender resolver and starts its worker
+// threads.
+func NewSenderResolver(threads, cacheSize int) *SenderResolver {
+	s := &SenderResolver{
 		tx2addr: lru.NewLruCache(cacheSize, true),
-		jobs:    make(chan *job, concurrency),
-		busy:    make(chan interface{}, concurrency),
+		threads: threads,
+		quit:    make(chan struct{}),
+	}
+	for lane := range s.lanes {
+		s.lanes[lane] = make(chan *senderBatch, threads*16)
 	}
+	for i := 0; i < threads; i++ {
+		go s.loop()
+	}
+	return s
+}
+
+// Stop stops the worker threads once the queued batches are done. Later
+// requests are served from the caches, recovering the misses of synchronous
+// requests in the caller's thread.
+func (s *SenderResolver) Stop() {
+	s.stopOnce.Do(func() {
+		s.lock.Lock()
+		s.stopped = true
+		close(s.quit)
+		s.lock.Unlock()
+	})
+}
+
+// next returns the next batch to process by priority, nil if stopped.
+func (s *SenderResolver) next() *senderBatch {
+	for _, lane := range s.lanes {
+		select {
+		case b := <-lane:
+			return b
+		default:
+		}
+	}
+	select {
+	case b := <-s.lanes[SenderLaneImport]:
+		return b
+	case b := <-s.lanes[SenderLaneTxPool]:
+		return b
+	case b := <-s.lanes[SenderLaneGossip]:
+		return b
+	case <-s.quit:
+		return nil
+	}
+}
+
+// drain returns a queued batch without waiting, nil if there are none.
+func (s *SenderResolver) drain() *senderBatch {
+	for _, lane := range s.lanes {
+		select {
+		case b := <-lane:
+			return b
+		default:
+		}
+	}
+	return nil
 }
 
 // sender resolver main loop
-func (s *SenderResolver) Run() {
+func (s *SenderResolver) loop() {
 	for {
-		j, ok := <-s.jobs
-		if !ok || j == nil {
-			break
+		b := s.next()
+		if b == nil {
+			// stopped, nothing gets queued anymore, finish what's left
+			for b = s.drain(); b != nil; b = s.drain() {
+				s.process(b)
+			}
+			return
 		}
-		go func() {
-			s.busy <- struct{}{}
-			defer func() {
-				<-s.busy
-			}()
-			j.f(j.param)
-		}()
+		s.process(b)
 	}
 }
 
-// Stop stops sender resolver
-func (s *SenderResolver) Stop() {
-	s.jobs <- nil
-}
+// process recovers and caches the senders of a batch.
+func (s *SenderResolver) process(b *senderBatch) {
+	senderWaitTimers[b.lane].UpdateSince(b.queued)
 
-// Post a new sender resolver task
-func (s *SenderResolver) Post(f func(interface{}), p interface{}) {
-	s.jobs <- &job{f: f, param: p}
+	start := time.Now()
+	var failed int
+	for _, tx := range b.txs {
+		if from, err := types.Sender(b.signer, tx); err == nil {
+			s.tx2addr.Put(tx.Hash(), &cachedSender{signer: b.signer, from: from})
+		} else {
+			failed++
+		}
+	}
+	senderRecoverTimer.UpdateSince(start)
+	senderRecoverMeter.Mark(int64(len(b.txs)))
+	senderFailMeter.Mark(int64(failed))
+
+	if b.done != nil {
+		b.done.Done()
+	}
 }
 
-// ResolveSenders resolves sender accounts from given transactions
-// concurrently using SenderResolver worker pool.
-func (pool *TxPool) ResolveSenders(signer types.Signer, txs []*types.Transaction) {
-	s := pool.senderResolver
-	var by_ecrecover, failed int64
+// lookup sets the sender of the transaction from the caches if known. The
+// senders cached in the transactions are not shared by hash: they may not be
+// recovered, like the senders given by trusted peers.
+func (s *SenderResolver) lookup(signer types.Signer, tx *types.Transaction) bool {
+	if types.GetSender(signer, tx) != nil {
+		return true
+	}
+	if data := s.tx2addr.Get(tx.Hash()); data != nil {
+		// senders recovered with other signers may not be valid with this one
+		if cached := data.(*cachedSender); cached.signer.Equal(signer) {
+			types.SetSender(signer, tx, cached.from)
+			return true
+		}
+	}
+	return false
+}
 
-	var wg sync.WaitGroup
+// schedule queues the transactions whose senders are not cached in batches
+// on the given lane. The batches are added to done if not nil.
+func (s *SenderResolver) schedule(lane SenderLane, signer types.Signer, txs []*types.Transaction, done *sync.WaitGroup) {
+	var misses []*types.Transaction
 	for _, tx := range txs {
-		hash := tx.Hash()
-		if addr := types.GetSender(signer, tx); addr != nil {
-			s.tx2addr.Put(hash, *addr)
-			continue
+		if !s.lookup(signer, tx) {
+			misses = append(misses, tx)
 		}
-
-		data := s.tx2addr.Get(hash)
-		if data != nil {
-			types.SetSender(signer, tx, data.(common.Address))
-			continue
+	}
+	senderHitMeter.Mark(int64(len(txs) - len(misses)))
+	if len(misses) == 0 {
+		return
+	}
+	// Spread small requests over all threads
+	size := (len(misses) + s.threads - 1) / s.threads
+	if size > senderBatchSize {
+		size = senderBatchSize
+	}
+	var batches []*senderBatch
+	for len(misses) > 0 {
+		n := size
+		if n > len(misses) {
+			n = len(misses)
 		}
+		batches = append(batches, &senderBatch{signer: signer, txs: misses[:n], lane: lane, done: done})
+		misses = misses[n:]
+	}
+	if done != nil {
+		done.Add(len(batches))
+	}
+	enqueue := func() {
+		s.lock.RLock()
+		defer s.lock.RUnlock()
 
-		wg.Add(1)
-		atomic.AddInt64(&by_ecrecover, 1)
-		s.Post(func(param interface{}) {
-			t := param.(*types.Transaction)
-			if from, err := types.Sender(signer, t); err == nil {
-				s.tx2addr.Put(t.Hash(), from)
-			} else {
-				atomic.AddInt64(&failed, 1)
+		for _, b := range batches {
+			b.queued = time.Now()
+			switch {
+			case !s.stopped:
+				s.lanes[lane] <- b
+			case done != nil:
+				s.process(b)
 			}
-			wg.Done()
-		}, tx)
+		}
 	}
+	if done != nil {
+		enqueue()
+	} else {
+		go enqueue()
+	}
+}
 
+// Recover recovers the senders of the transactions in the background and
+// caches them back into the transactions. There is no validation being done,
+// nor any reaction to invalid signatures. That is up to calling code later.
+func (s *SenderResolver) Recover(lane SenderLane, signer types.Signer, txs []*types.Transaction) {
+	s.schedule(lane, signer, txs, nil)
+}
+
+// RecoverSync is like Recover, but waits for the senders to be recovered.
+func (s *SenderResolver) RecoverSync(lane SenderLane, signer types.Signer, txs []*types.Transaction) {
+	var wg sync.WaitGroup
+	s.schedule(lane, signer, txs, &wg)
 	wg.Wait()
 }
 
+// RecoverFromBlocks recovers the senders of the transactions in the blocks
+// in the background, like Recover does.
+func (s *SenderResolver) RecoverFromBlocks(lane SenderLane, signer types.Signer, blocks []*types.Block) {
+	count := 0
+	for _, block := range blocks {
+		count += len(block.Transactions())
+	}
+	txs := make([]*types.Transaction, 0, count)
+	for _, block := range blocks {
+		txs = append(txs, block.Transactions()...)
+	}
+	s.Recover(lane, signer, txs)
+}
+
+// ResolveSenders resolves sender accounts from given transactions
+// concurrently using SenderResolver worker pool.
+func (pool *TxPool) ResolveSenders(signer types.Signer, txs []*types.Transaction) {
+	pool.senderResolver.RecoverSync(SenderLaneTxPool, signer, txs)
+}
+
 // ResolveSender resolves sender address from a transaction
 func (pool *TxPool) ResolveSender(signer types.Signer, tx *types.Transaction) {
 	var txs []*types.Transaction
@@ -0,0 +1,132 @@
+// tx_sender_resolver_test.go
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestSenderResolver(t *testing.T) {
+	s := NewSenderResolver(2, 1024)
+	defer s.Stop()
+
+	var (
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		signer = types.NewLondonSigner(common.Big1)
+		txs    []*types.Transaction
+	)
+	for i := 0; i < 100; i++ {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), common.Address{}, common.Big0, 21000, big.NewInt(1), nil), signer, key)
+		txs = append(txs, tx)
+	}
+	s.RecoverSync(SenderLaneGossip, signer, txs)
+	for i, tx := range txs {
+		if from := types.GetSender(signer, tx); from == nil || *from != addr {
+			t.Fatalf("tx %d: sender not recovered: %v", i, from)
+		}
+	}
+
+	// Copies of the transactions are served from the cache
+	copies := make([]*types.Transaction, len(txs))
+	for i, tx := range txs {
+		blob, _ := tx.MarshalBinary()
+		copies[i] = new(types.Transaction)
+		copies[i].UnmarshalBinary(blob)
+		if !s.lookup(signer, copies[i]) {
+			t.Fatalf("tx %d: sender not cached", i)
+		}
+		if from := types.GetSender(signer, copies[i]); from == nil || *from != addr {
+			t.Fatalf("tx %d: wrong cached sender: %v", i, from)
+		}
+	}
+	// but not to other signers
+	blob, _ := txs[0].MarshalBinary()
+	tx := new(types.Transaction)
+	tx.UnmarshalBinary(blob)
+	if s.lookup(types.NewLondonSigner(common.Big2), tx) {
+		t.Fatalf("sender of another signer served from cache")
+	}
+}
+
+func TestSenderResolverStop(t *testing.T) {
+	s := NewSenderResolver(2, 1024)
+	s.Stop()
+	s.Stop()
+
+	var (
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		signer = types.NewLondonSigner(common.Big1)
+		txs    []*types.Transaction
+	)
+	for i := 0; i < 100; i++ {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), common.Address{}, common.Big0, 21000, big.NewInt(1), nil), signer, key)
+		txs = append(txs, tx)
+	}
+	// Asynchronous requests are dropped, synchronous ones served in place
+	s.Recover(SenderLaneGossip, signer, txs[:50])
+	s.RecoverSync(SenderLaneTxPool, signer, txs[50:])
+	for i, tx := range txs[50:] {
+		if from := types.GetSender(signer, tx); from == nil || *from != addr {
+			t.Fatalf("tx %d: sender not recovered: %v", i, from)
+		}
+	}
+}
+
+func TestSenderResolverPriority(t *testing.T) {
+	// no worker threads, batches stay queued
+	s := &SenderResolver{quit: make(chan struct{})}
+	for lane := range s.lanes {
+		s.lanes[lane] = make(chan *senderBatch, 4)
+	}
+	for _, lane := range []SenderLane{SenderLaneGossip, SenderLaneTxPool, SenderLaneImport, SenderLaneGossip} {
+		s.lanes[lane] <- &senderBatch{lane: lane}
+	}
+	for _, want := range []SenderLane{SenderLaneImport, SenderLaneTxPool, SenderLaneGossip, SenderLaneGossip} {
+		if b := s.next(); b == nil || b.lane != want {
+			t.Fatalf("wrong batch order: have %v, want %v", b, want)
+		}
+	}
+}
+
+func TestSenderResolverUntrustedSender(t *testing.T) {
+	s := NewSenderResolver(2, 1024)
+	defer s.Stop()
+
+	var (
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		forged = common.HexToAddress("0xbad")
+		signer = types.NewLondonSigner(common.Big1)
+	)
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{}, common.Big0, 21000, big.NewInt(1), nil), signer, key)
+
+	// A sender given by a peer, not recovered, is served from the transaction
+	types.SetSender(signer, tx, forged)
+	s.RecoverSync(SenderLaneTxPool, signer, []*types.Transaction{tx})
+	if from := types.GetSender(signer, tx); from == nil || *from != forged {
+		t.Fatalf("wrong pool sender: %v", from)
+	}
+	// but not shared with the blocks including the transaction
+	blob, _ := tx.MarshalBinary()
+	imported := new(types.Transaction)
+	imported.UnmarshalBinary(blob)
+	block := types.NewBlockWithHeader(&types.Header{Number: common.Big1}).WithBody([]*types.Transaction{imported}, nil)
+
+	s.RecoverFromBlocks(SenderLaneImport, signer, []*types.Block{block})
+	for start := time.Now(); types.GetSender(signer, imported) == nil; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("block sender not recovered")
+		}
+	}
+	if from := types.GetSender(signer, imported); *from != addr {
+		t.Fatalf("wrong block sender: have %x, want %x", *from, addr)
+	}
+}
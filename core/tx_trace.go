@@ -0,0 +1,117 @@
+// tx_trace.go
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	txTraceLimit  = 16384 // max number of transactions traced
+	txTraceEvents = 64    // max number of events kept per transaction
+)
+
+// Transaction lifecycle events
+const (
+	TxEventSubmitted = "submitted" // submitted locally
+	TxEventReceived  = "received"  // received from a peer
+	TxEventRejected  = "rejected"  // not admitted into the pool
+	TxEventQueued    = "queued"    // added to the non-executable queue
+	TxEventPending   = "pending"   // added to the pending set directly
+	TxEventPromoted  = "promoted"  // moved from the queue to the pending set
+	TxEventDemoted   = "demoted"   // moved from the pending set back to the queue
+	TxEventReplaced  = "replaced"  // replaced by another with the same nonce
+	TxEventDropped   = "dropped"   // removed from the pool
+	TxEventBroadcast = "broadcast" // sent or announced to peers
+	TxEventIncluded  = "included"  // included in a canonical block
+)
+
+// TxTraceEvent is an event in the lifecycle of a transaction.
+type TxTraceEvent struct {
+	Time      time.Time       `json:"time"`
+	Event     string          `json:"event"`
+	Peer      string          `json:"peer,omitempty"`
+	Peers     int             `json:"peers,omitempty"`
+	Reason    string          `json:"reason,omitempty"`
+	Block     *hexutil.Uint64 `json:"block,omitempty"`
+	BlockHash *common.Hash    `json:"blockHash,omitempty"`
+	Miner     *common.Address `json:"miner,omitempty"`
+}
+
+// txTraceLog keeps the latest lifecycle events of the most recently seen
+// transactions in memory.
+type txTraceLog struct {
+	mu     sync.Mutex
+	traces *lru.LruCache // hash -> []*TxTraceEvent
+}
+
+var txTraces = &txTraceLog{traces: lru.NewLruCache(txTraceLimit, false)}
+
+// TraceTx records an event in the lifecycle of a transaction.
+func TraceTx(hash common.Hash, ev *TxTraceEvent) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	txTraces.mu.Lock()
+	defer txTraces.mu.Unlock()
+
+	var events []*TxTraceEvent
+	if data := txTraces.traces.Get(hash); data != nil {
+		events = data.([]*TxTraceEvent)
+	}
+	if len(events) >= txTraceEvents {
+		events = append(events[:0:0], events[len(events)-txTraceEvents+1:]...)
+	}
+	txTraces.traces.Put(hash, append(events, ev))
+}
+
+// TxTrace returns the recorded lifecycle events of a transaction, oldest
+// first, nil if none.
+func TxTrace(hash common.Hash) []*TxTraceEvent {
+	txTraces.mu.Lock()
+	defer txTraces.mu.Unlock()
+
+	if data := txTraces.traces.Get(hash); data != nil {
+		events := data.([]*TxTraceEvent)
+		return append([]*TxTraceEvent{}, events...)
+	}
+	return nil
+}
+
+// traceIncluded records the inclusion of the traced transactions of the
+// blocks, whoever mined them. Transactions never seen before are left out, not
+// to push the traces of the pool's own out of the log.
+func traceIncluded(blocks []*types.Block) {
+	for _, block := range blocks {
+		var (
+			number = hexutil.Uint64(block.NumberU64())
+			hash   = block.Hash()
+			miner  = block.Coinbase()
+		)
+		for _, tx := range block.Transactions() {
+			if txTraces.traces.Exists(tx.Hash()) {
+				TraceTx(tx.Hash(), &TxTraceEvent{Event: TxEventIncluded, Block: &number, BlockHash: &hash, Miner: &miner})
+			}
+		}
+	}
+}
+
+// traceTx records an event of the pool, with the reason if any.
+func traceTx(hash common.Hash, event string, reason string) {
+	TraceTx(hash, &TxTraceEvent{Event: event, Reason: reason})
+}
+
+// traceTxs records the same event of the pool for the transactions.
+func traceTxs(txs types.Transactions, event string, reason string) {
+	for _, tx := range txs {
+		traceTx(tx.Hash(), event, reason)
+	}
+}
+
+// EOF
@@ -363,6 +363,11 @@ func (tx *Transaction) EffectiveGasTipIntCmp(other *big.Int, baseFee *big.Int) i
 	return tx.EffectiveGasTipValue(baseFee).Cmp(other)
 }
 
+// Time returns the time when the transaction was first seen locally.
+func (tx *Transaction) Time() time.Time {
+	return tx.time
+}
+
 // Hash returns the transaction hash.
 func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
@@ -408,11 +413,12 @@ func Txs2TxExs(txs []*Transaction) []*TransactionEx {
 	return out
 }
 
-// Convert []*TransactionEx to []*Transaction
+// Convert []*TransactionEx to []*Transaction, caching the senders given
+// unless missing if trustIt.
 func TxExs2Txs(signer Signer, txs []*TransactionEx, trustIt bool) []*Transaction {
 	var out []*Transaction
 	for _, i := range txs {
-		if trustIt {
+		if trustIt && i.From != (common.Address{}) {
 			i.Tx.from.Store(sigCache{signer: signer, from: i.From})
 		}
 		out = append(out, i.Tx)
@@ -420,6 +426,22 @@ func TxExs2Txs(signer Signer, txs []*TransactionEx, trustIt bool) []*Transaction
 	return out
 }
 
+// TxExsHash returns the hash of the transactions and their senders, which
+// partners sign transaction batches with.
+func TxExsHash(txs []*TransactionEx) common.Hash {
+	sha := hasherPool.Get().(crypto.KeccakState)
+	defer hasherPool.Put(sha)
+	sha.Reset()
+	for _, tx := range txs {
+		hash := tx.Tx.Hash()
+		sha.Write(hash[:])
+		sha.Write(tx.From[:])
+	}
+	var h common.Hash
+	sha.Read(h[:])
+	return h
+}
+
 // EncodeRLP implements rlp.Encoder
 func (tx *TransactionEx) EncodeRLP(w io.Writer) error {
 	if err := tx.Tx.EncodeRLP(w); err != nil {
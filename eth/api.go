@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -86,6 +87,12 @@ func (api *PublicMinerAPI) Mining() bool {
 	return api.e.IsMining()
 }
 
+// SimulatePendingBlock returns the transactions, gas, fees and rewards the
+// next block would have if it were built now from the pending transactions.
+func (api *PublicMinerAPI) SimulatePendingBlock() (*miner.SimulatedBlock, error) {
+	return api.e.Miner().SimulateBlock()
+}
+
 // PrivateMinerAPI provides private RPC methods to control the miner.
 // These methods can be abused by external users and must be considered insecure for use by untrusted users.
 type PrivateMinerAPI struct {
@@ -150,6 +157,36 @@ func (api *PrivateMinerAPI) SetRecommitInterval(interval int) {
 	api.e.Miner().SetRecommitInterval(time.Duration(interval) * time.Millisecond)
 }
 
+// GetTxOrdering returns the transaction ordering policy and the per sender
+// cap of the miner.
+func (api *PrivateMinerAPI) GetTxOrdering() map[string]interface{} {
+	policy, senderCap := api.e.Miner().TxOrdering()
+	return map[string]interface{}{
+		"policy":    policy,
+		"senderCap": senderCap,
+		"available": miner.TxOrderingPolicies(),
+	}
+}
+
+// SetTxOrdering sets the transaction ordering policy of the miner, and the
+// max number of transactions per sender in a block if given.
+func (api *PrivateMinerAPI) SetTxOrdering(policy string, senderCap *int) (bool, error) {
+	_, limit := api.e.Miner().TxOrdering()
+	if senderCap != nil {
+		limit = *senderCap
+	}
+	if err := api.e.Miner().SetTxOrdering(policy, limit); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SimulateBlock builds the next block from the pending transactions without
+// sealing or broadcasting it, and returns what it would contain.
+func (api *PrivateMinerAPI) SimulateBlock() (*miner.SimulatedBlock, error) {
+	return api.e.Miner().SimulateBlock()
+}
+
 // Get and set params.PrefetchCount
 func (api *PrivateMinerAPI) GetPrefetchCount() int {
 	return params.PrefetchCount
@@ -161,6 +198,44 @@ func (api *PrivateMinerAPI) SetPrefetchCount(count int) {
 	}
 }
 
+// PublicTxPoolAPI offers transaction pool RPC methods of the full node.
+type PublicTxPoolAPI struct {
+	e *Ethereum
+}
+
+// NewPublicTxPoolAPI creates a new PublicTxPoolAPI instance.
+func NewPublicTxPoolAPI(e *Ethereum) *PublicTxPoolAPI {
+	return &PublicTxPoolAPI{e}
+}
+
+// TransactionTrace is the lifecycle of a transaction as seen by the node.
+type TransactionTrace struct {
+	Hash   common.Hash          `json:"hash"`
+	Status string               `json:"status"` // pending, queued or unknown to the pool
+	Events []*core.TxTraceEvent `json:"events"`
+}
+
+// GetTransactionTrace returns the recorded lifecycle events of a transaction,
+// from its reception to its inclusion or removal from the pool, nil if the
+// transaction is not known.
+func (api *PublicTxPoolAPI) GetTransactionTrace(hash common.Hash) *TransactionTrace {
+	status := "unknown"
+	switch api.e.TxPool().Status([]common.Hash{hash})[0] {
+	case core.TxStatusPending:
+		status = "pending"
+	case core.TxStatusQueued:
+		status = "queued"
+	}
+	events := core.TxTrace(hash)
+	if events == nil && status == "unknown" {
+		return nil
+	}
+	if events == nil {
+		events = []*core.TxTraceEvent{}
+	}
+	return &TransactionTrace{Hash: hash, Status: status, Events: events}
+}
+
 // PrivateAdminAPI is the collection of Ethereum full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -324,6 +399,16 @@ func (api *PrivateAdminAPI) SynchroniseWith(id enode.ID) error {
 	return api.eth.handler.SynchroniseWith(id)
 }
 
+// TxPoolThrottled returns the sender accounts and peers whose transactions
+// are being rejected for exceeding their admission rates.
+func (api *PrivateAdminAPI) TxPoolThrottled() map[string][]*core.ThrottledEntity {
+	senders, peers := api.eth.TxPool().Throttled()
+	return map[string][]*core.ThrottledEntity{
+		"senders": senders,
+		"peers":   peers,
+	}
+}
+
 // PublicDebugAPI is the collection of Ethereum full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -0,0 +1,72 @@
+// balance_history.go
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxBalanceHistory is the max number of balance changes returned at once.
+const maxBalanceHistory = 10000
+
+// BalanceChange is a change of the balance or nonce of an account in a block,
+// be it by transactions, fee payments or block rewards.
+type BalanceChange struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	PrevBalance *hexutil.Big   `json:"prevBalance"`
+	Balance     *hexutil.Big   `json:"balance"`
+	PrevNonce   hexutil.Uint64 `json:"prevNonce"`
+	Nonce       hexutil.Uint64 `json:"nonce"`
+}
+
+// GetBalanceHistory returns the changes of the balance or nonce of an account
+// in the canonical blocks from..to, as indexed since the balance history was
+// enabled.
+func (api *PublicEthereumAPI) GetBalanceHistory(address common.Address, from, to rpc.BlockNumber) ([]*BalanceChange, error) {
+	if !api.e.config.BalanceHistory {
+		return nil, errors.New("balance history not enabled")
+	}
+	head := api.e.blockchain.CurrentBlock().NumberU64()
+	resolve := func(number rpc.BlockNumber) uint64 {
+		switch {
+		case number == rpc.EarliestBlockNumber:
+			return 0
+		case number < 0 || uint64(number) > head:
+			return head
+		default:
+			return uint64(number)
+		}
+	}
+	begin, end := resolve(from), resolve(to)
+	if begin > end {
+		return nil, fmt.Errorf("invalid block range %d..%d", begin, end)
+	}
+	changes, err := rawdb.ReadBalanceHistory(api.e.chainDb, address, begin, end, api.e.blockchain.GetCanonicalHash, maxBalanceHistory+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) > maxBalanceHistory {
+		return nil, fmt.Errorf("more than %d balance changes in block range %d..%d", maxBalanceHistory, begin, end)
+	}
+	result := make([]*BalanceChange, len(changes))
+	for i, change := range changes {
+		result[i] = &BalanceChange{
+			BlockNumber: hexutil.Uint64(change.Number),
+			BlockHash:   change.Hash,
+			PrevBalance: (*hexutil.Big)(change.PrevBalance),
+			Balance:     (*hexutil.Big)(change.Balance),
+			PrevNonce:   hexutil.Uint64(change.PrevNonce),
+			Nonce:       hexutil.Uint64(change.Nonce),
+		}
+	}
+	return result, nil
+}
+
+// EOF
@@ -138,6 +138,10 @@ type Config struct {
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 
+	LogIndex bool `toml:",omitempty"` // Whether to maintain the inverted address and topic index of the logs
+
+	BalanceHistory bool `toml:",omitempty"` // Whether to index the balance and nonce changes of the accounts
+
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
@@ -24,14 +24,17 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
 type Backend interface {
 	ChainDb() ethdb.Database
+	ChainConfig() *params.ChainConfig
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
 	HeaderByHash(ctx context.Context, blockHash common.Hash) (*types.Header, error)
 	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
@@ -47,6 +50,14 @@ type Backend interface {
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// LogIndexBackend is implemented by the backends maintaining the inverted log
+// index, which is searched before the bloom bits where available.
+type LogIndexBackend interface {
+	// LogIndexStatus returns the section size and number of indexed sections,
+	// zero if the index is disabled.
+	LogIndexStatus() (uint64, uint64)
+}
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	backend Backend
@@ -147,13 +158,28 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		logs []*types.Log
 		err  error
 	)
+	if backend, ok := f.backend.(LogIndexBackend); ok && f.selective() {
+		size, sections := backend.LogIndexStatus()
+		if indexed := sections * size; indexed > uint64(f.begin) {
+			if indexed > end {
+				logs, err = f.invertedLogs(ctx, size, end)
+			} else {
+				logs, err = f.invertedLogs(ctx, size, indexed-1)
+			}
+			if err != nil {
+				return logs, err
+			}
+		}
+	}
 	size, sections := f.backend.BloomStatus()
-	if indexed := sections * size; indexed > uint64(f.begin) {
+	if indexed := sections * size; indexed > uint64(f.begin) && uint64(f.begin) <= end {
+		var found []*types.Log
 		if indexed > end {
-			logs, err = f.indexedLogs(ctx, end)
+			found, err = f.indexedLogs(ctx, end)
 		} else {
-			logs, err = f.indexedLogs(ctx, indexed-1)
+			found, err = f.indexedLogs(ctx, indexed-1)
 		}
+		logs = append(logs, found...)
 		if err != nil {
 			return logs, err
 		}
@@ -210,6 +236,55 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 	}
 }
 
+// selective returns whether the filter criteria restrict the addresses or the
+// topics, the inverted log index being of no use otherwise.
+func (f *Filter) selective() bool {
+	if len(f.addresses) > 0 {
+		return true
+	}
+	for _, sub := range f.topics {
+		if len(sub) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// invertedLogs returns the logs matching the filter criteria based on the
+// inverted log index of the given section size.
+func (f *Filter) invertedLogs(ctx context.Context, size, end uint64) ([]*types.Log, error) {
+	var logs []*types.Log
+
+	for section := uint64(f.begin) / size; section <= end/size; section++ {
+		if err := ctx.Err(); err != nil {
+			return logs, err
+		}
+		head := rawdb.ReadCanonicalHash(f.db, (section+1)*size-1)
+		matches, err := core.LogIndexMatches(f.db, size, section, head, f.addresses, f.topics)
+		if err != nil {
+			return logs, err
+		}
+		for number := uint64(f.begin); number <= end && number < (section+1)*size; number++ {
+			index := number - section*size
+			if matches[index/8]&(1<<(7-index%8)) == 0 {
+				continue
+			}
+			header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+			if header == nil || err != nil {
+				return logs, err
+			}
+			found, err := f.checkMatches(ctx, header)
+			if err != nil {
+				return logs, err
+			}
+			logs = append(logs, found...)
+		}
+		f.begin = int64((section + 1) * size)
+	}
+	f.begin = int64(end) + 1
+	return logs, nil
+}
+
 // unindexedLogs returns the logs matching the filter criteria based on raw block
 // iteration and bloom matching.
 func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
@@ -267,11 +342,25 @@ func (f *Filter) checkMatches(ctx context.Context, header *types.Header) (logs [
 			}
 			logs = filterLogs(unfiltered, nil, nil, f.addresses, f.topics)
 		}
+		// Stop as soon as the logs exceed the response size limit of the call
+		if err := rpc.ResponseBudgetFromContext(ctx).Consume(logsSize(logs)); err != nil {
+			return nil, err
+		}
 		return logs, nil
 	}
 	return nil, nil
 }
 
+// logsSize estimates the size of the JSON encoding of logs, each log taking
+// about 330 bytes of fields besides its topics and data.
+func logsSize(logs []*types.Log) int {
+	size := 0
+	for _, log := range logs {
+		size += 330 + 69*len(log.Topics) + 2*len(log.Data)
+	}
+	return size
+}
+
 func includes(addresses []common.Address, a common.Address) bool {
 	for _, addr := range addresses {
 		if addr == a {
@@ -102,6 +102,8 @@ type EventSystem struct {
 	pendingLogsCh chan []*types.Log          // Channel to receive new log event
 	rmLogsCh      chan core.RemovedLogsEvent // Channel to receive removed log event
 	chainCh       chan core.ChainEvent       // Channel to receive new chain event
+	quit          chan struct{}              // Channel closed to stop the event loop
+	done          chan struct{}              // Channel closed when the event loop has stopped
 }
 
 // NewEventSystem creates a new manager that listens for event on the given mux,
@@ -121,6 +123,8 @@ func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 		rmLogsCh:      make(chan core.RemovedLogsEvent, rmLogsChanSize),
 		pendingLogsCh: make(chan []*types.Log, logsChanSize),
 		chainCh:       make(chan core.ChainEvent, chainEvChanSize),
+		quit:          make(chan struct{}),
+		done:          make(chan struct{}),
 	}
 
 	// Subscribe events
@@ -164,6 +168,8 @@ func (sub *Subscription) Unsubscribe() {
 			select {
 			case sub.es.uninstall <- sub.f:
 				break uninstallLoop
+			case <-sub.es.done:
+				break uninstallLoop
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
 			case <-sub.f.headers:
@@ -179,8 +185,12 @@ func (sub *Subscription) Unsubscribe() {
 
 // subscribe installs the subscription in the event broadcast loop.
 func (es *EventSystem) subscribe(sub *subscription) *Subscription {
-	es.install <- sub
-	<-sub.installed
+	select {
+	case es.install <- sub:
+		<-sub.installed
+	case <-es.done:
+		close(sub.err) // the event system is stopped, end the subscription
+	}
 	return &Subscription{ID: sub.id, f: sub, es: es}
 }
 
@@ -439,8 +449,19 @@ func (es *EventSystem) lightFilterLogs(header *types.Header, addresses []common.
 	return nil
 }
 
+// Stop stops the event system, ending its subscriptions. Subscriptions created
+// once stopped are ended right away.
+func (es *EventSystem) Stop() {
+	close(es.quit)
+	<-es.done
+}
+
 // eventLoop (un)installs filters and processes mux events.
 func (es *EventSystem) eventLoop() {
+	index := make(filterIndex)
+	for i := UnknownSubscription; i < LastIndexSubscription; i++ {
+		index[i] = make(map[rpc.ID]*subscription)
+	}
 	// Ensure all subscriptions get cleaned up
 	defer func() {
 		es.txsSub.Unsubscribe()
@@ -448,12 +469,19 @@ func (es *EventSystem) eventLoop() {
 		es.rmLogsSub.Unsubscribe()
 		es.pendingLogsSub.Unsubscribe()
 		es.chainSub.Unsubscribe()
-	}()
 
-	index := make(filterIndex)
-	for i := UnknownSubscription; i < LastIndexSubscription; i++ {
-		index[i] = make(map[rpc.ID]*subscription)
-	}
+		// End the subscriptions still installed
+		ended := make(map[rpc.ID]struct{})
+		for _, subs := range index {
+			for id, f := range subs {
+				if _, ok := ended[id]; !ok {
+					ended[id] = struct{}{}
+					close(f.err)
+				}
+			}
+		}
+		close(es.done)
+	}()
 
 	for {
 		select {
@@ -489,6 +517,8 @@ func (es *EventSystem) eventLoop() {
 			close(f.err)
 
 		// System stopped
+		case <-es.quit:
+			return
 		case <-es.txsSub.Err():
 			return
 		case <-es.logsSub.Err():
@@ -58,6 +58,10 @@ func (b *testBackend) ChainDb() ethdb.Database {
 	return b.db
 }
 
+func (b *testBackend) ChainConfig() *params.ChainConfig {
+	return params.TestChainConfig
+}
+
 func (b *testBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
 	var (
 		hash common.Hash
@@ -737,6 +741,35 @@ func TestPendingTxFilterDeadlock(t *testing.T) {
 	}
 }
 
+// TestEventSystemStop tests that stopping the event system ends its
+// subscriptions, those created after being ended right away.
+func TestEventSystemStop(t *testing.T) {
+	t.Parallel()
+
+	var (
+		backend = &testBackend{db: rawdb.NewMemoryDatabase()}
+		es      = NewEventSystem(backend, false)
+		headers = make(chan *types.Header)
+		logs    = make(chan []*types.Log)
+	)
+	heads := es.SubscribeNewHeads(headers)
+	mined, err := es.SubscribeLogs(ethereum.FilterQuery{FromBlock: big.NewInt(rpc.LatestBlockNumber.Int64()), ToBlock: big.NewInt(rpc.PendingBlockNumber.Int64())}, logs)
+	if err != nil {
+		t.Fatalf("failed to subscribe to logs: %v", err)
+	}
+	es.Stop()
+
+	late := es.SubscribeNewHeads(headers)
+	for i, sub := range []*Subscription{heads, mined, late} {
+		select {
+		case <-sub.Err():
+		case <-time.After(time.Second):
+			t.Fatalf("subscription %d not ended", i)
+		}
+		sub.Unsubscribe()
+	}
+}
+
 func flattenLogs(pl [][]*types.Log) []*types.Log {
 	var logs []*types.Log
 	for _, l := range pl {
@@ -17,18 +17,23 @@
 package filters
 
 import (
+	"bytes"
 	"context"
 	"io/ioutil"
 	"math/big"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/bitutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -227,3 +232,146 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+// invertedBackend is a test backend maintaining the inverted log index and the
+// bloom bits in small sections.
+type invertedBackend struct {
+	*testBackend
+	logIndexSize, logIndexSections uint64
+	bloomSize, bloomSections       uint64
+}
+
+func (b *invertedBackend) LogIndexStatus() (uint64, uint64) {
+	return b.logIndexSize, b.logIndexSections
+}
+
+func (b *invertedBackend) BloomStatus() (uint64, uint64) {
+	return b.bloomSize, b.bloomSections
+}
+
+func (b *invertedBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
+	requests := make(chan chan *bloombits.Retrieval)
+
+	go session.Multiplex(16, 0, requests)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case request := <-requests:
+				task := <-request
+
+				task.Bitsets = make([][]byte, len(task.Sections))
+				for i, section := range task.Sections {
+					head := rawdb.ReadCanonicalHash(b.db, (section+1)*b.bloomSize-1)
+					task.Bitsets[i], _ = rawdb.ReadBloomBits(b.db, task.Bit, section, head)
+				}
+				request <- task
+			}
+		}
+	}()
+}
+
+// logIndexReads counts the reads of the log index entries of a key.
+type logIndexReads struct {
+	ethdb.Database
+	key   []byte
+	reads int
+}
+
+func (db *logIndexReads) Get(key []byte) ([]byte, error) {
+	if bytes.Contains(key, db.key) {
+		db.reads++
+	}
+	return db.Database.Get(key)
+}
+
+// TestFiltersInvertedIndex tests the logs searched in the inverted log index,
+// then in the bloom bits and finally in the remaining blocks.
+func TestFiltersInvertedIndex(t *testing.T) {
+	var (
+		addr    = common.HexToAddress("0x1234")
+		other   = common.HexToAddress("0x5678")
+		db      = &logIndexReads{Database: rawdb.NewMemoryDatabase(), key: addr.Bytes()}
+		backend = &invertedBackend{
+			testBackend:  &testBackend{db: db},
+			logIndexSize: 8, logIndexSections: 1, // blocks 0-7
+			bloomSize: 16, bloomSections: 1, // blocks 0-15
+		}
+		logsAt = map[uint64][]common.Address{
+			2: {addr}, 3: {other}, 5: {addr, addr}, // inverted log index
+			9: {other, addr}, 14: {addr}, // bloom bits
+			18: {addr}, 20: {other}, 21: {addr}, // unindexed
+		}
+	)
+	// Blocks 0-23, and the sections of the indexes
+	genesis := &types.Header{Number: common.Big0}
+	rawdb.WriteHeader(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+	headers := []*types.Header{genesis}
+	for i := uint64(1); i < 24; i++ {
+		headers = append(headers, writeStreamTestBlock(db, headers[i-1], 0, logsAt[i]...).Header())
+	}
+	index := make(map[common.Address][]byte)
+	for n, addresses := range logsAt {
+		if n < backend.logIndexSize {
+			for _, address := range addresses {
+				if index[address] == nil {
+					index[address] = make([]byte, backend.logIndexSize/8)
+				}
+				index[address][n/8] |= 1 << (7 - n%8)
+			}
+		}
+	}
+	for address, bits := range index {
+		rawdb.WriteLogIndex(db, address.Bytes(), 0, headers[backend.logIndexSize-1].Hash(), bitutil.CompressBytes(bits))
+	}
+	gen, err := bloombits.NewGenerator(uint(backend.bloomSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := uint64(0); n < backend.bloomSize; n++ {
+		if err := gen.AddBloom(uint(n), headers[n].Bloom); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < types.BloomBitLength; i++ {
+		bits, err := gen.Bitset(uint(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rawdb.WriteBloomBits(db, uint(i), 0, headers[backend.bloomSize-1].Hash(), bitutil.CompressBytes(bits))
+	}
+
+	tests := []struct {
+		begin, end int64
+		addresses  []common.Address
+		blocks     []uint64 // blocks of the logs found
+		inverted   bool     // whether the inverted log index is searched
+	}{
+		{0, -1, []common.Address{addr}, []uint64{2, 5, 5, 9, 14, 18, 21}, true},
+		{4, 19, []common.Address{addr}, []uint64{5, 5, 9, 14, 18}, true},
+		{0, 6, []common.Address{addr}, []uint64{2, 5, 5}, true},
+		{0, -1, []common.Address{addr, other}, []uint64{2, 3, 5, 5, 9, 9, 14, 18, 20, 21}, true},
+		{8, -1, []common.Address{addr}, []uint64{9, 14, 18, 21}, false},
+		{0, -1, nil, []uint64{2, 3, 5, 5, 9, 9, 14, 18, 20, 21}, false},
+	}
+	for i, tt := range tests {
+		db.reads = 0
+		logs, err := NewRangeFilter(backend, tt.begin, tt.end, tt.addresses, nil).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		blocks := make([]uint64, len(logs))
+		for j, log := range logs {
+			blocks[j] = log.BlockNumber
+		}
+		if !reflect.DeepEqual(blocks, tt.blocks) {
+			t.Errorf("test %d: blocks mismatch: have %v, want %v", i, blocks, tt.blocks)
+		}
+		if inverted := db.reads > 0; inverted != tt.inverted {
+			t.Errorf("test %d: inverted log index searched: have %v, want %v", i, inverted, tt.inverted)
+		}
+	}
+}
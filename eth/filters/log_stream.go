@@ -0,0 +1,293 @@
+// log_stream.go
+
+package filters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// logStreamBatch is the max number of blocks whose logs are searched at
+	// once while catching up with the chain.
+	logStreamBatch = 2048
+
+	// Types of the events of a resumable log subscription.
+	LogEventLog   = "log"
+	LogEventReorg = "reorg"
+	LogEventError = "error"
+)
+
+// LogCursor is a position in the chain for resumable log subscriptions.
+//
+// Without block hash, it is the block from which logs are streamed. With block
+// hash, it is the last block received by the client, or its last log if the
+// log index is set, and logs are streamed from right after it. If the block was
+// reorged out meanwhile, a reorg event is sent first.
+type LogCursor struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   *common.Hash   `json:"blockHash,omitempty"`
+	LogIndex    *hexutil.Uint  `json:"logIndex,omitempty"`
+}
+
+// LogEvent is an event of a resumable log subscription: a log matching the
+// criteria, a reorg invalidating the logs sent after a common ancestor, or the
+// error ending the subscription, with the cursor to resume it from.
+type LogEvent struct {
+	Type     string     `json:"type"`
+	Log      *types.Log `json:"log,omitempty"`
+	Ancestor *LogCursor `json:"ancestor,omitempty"` // Last block still canonical, for reorg events
+	Error    string     `json:"error,omitempty"`
+	Cursor   *LogCursor `json:"cursor,omitempty"` // Last log or block sent, for error events
+}
+
+// logStream streams the logs of the canonical chain matching a criteria, from
+// the last block sent, detecting the reorgs of the blocks sent.
+type logStream struct {
+	backend   Backend
+	addresses []common.Address
+	topics    [][]common.Hash
+
+	number  uint64      // Number of the last block sent
+	hash    common.Hash // Hash of the last block sent, zero before the genesis
+	partial *uint       // Index of the last log sent, if the last block is partially sent
+}
+
+// newLogStream creates a log stream starting at a cursor, or at the first block
+// of the criteria, or after the current head.
+func newLogStream(ctx context.Context, backend Backend, crit FilterCriteria, cursor *LogCursor) (*logStream, error) {
+	if crit.BlockHash != nil {
+		return nil, errors.New("blockHash criteria not supported")
+	}
+	if crit.ToBlock != nil && crit.ToBlock.Sign() >= 0 {
+		return nil, errors.New("toBlock criteria not supported")
+	}
+	s := &logStream{
+		backend:   backend,
+		addresses: crit.Addresses,
+		topics:    crit.Topics,
+	}
+	head, err := backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	} else if head == nil {
+		return nil, errors.New("unknown head block")
+	}
+	switch {
+	case cursor != nil && cursor.BlockHash != nil:
+		header, err := backend.HeaderByHash(ctx, *cursor.BlockHash)
+		if err != nil {
+			return nil, err
+		}
+		if header == nil || header.Number.Uint64() != uint64(cursor.BlockNumber) {
+			return nil, fmt.Errorf("unknown cursor block %d [%x]", cursor.BlockNumber, *cursor.BlockHash)
+		}
+		s.number, s.hash = header.Number.Uint64(), header.Hash()
+		if cursor.LogIndex != nil {
+			index := uint(*cursor.LogIndex)
+			s.partial = &index
+		}
+		return s, nil
+
+	case cursor != nil:
+		return s, s.startAt(uint64(cursor.BlockNumber), head)
+
+	case crit.FromBlock != nil && crit.FromBlock.Sign() >= 0:
+		return s, s.startAt(crit.FromBlock.Uint64(), head)
+
+	default:
+		return s, s.startAt(head.Number.Uint64()+1, head)
+	}
+}
+
+// startAt sets the stream to start at the given block.
+func (s *logStream) startAt(number uint64, head *types.Header) error {
+	if number > head.Number.Uint64()+1 {
+		return fmt.Errorf("start block %d beyond head %d", number, head.Number)
+	}
+	if number > 0 {
+		s.number = number - 1
+		s.hash = rawdb.ReadCanonicalHash(s.backend.ChainDb(), s.number)
+	}
+	return nil
+}
+
+// cursor returns the cursor of the last log or block sent.
+func (s *logStream) cursor() *LogCursor {
+	cursor := &LogCursor{BlockNumber: hexutil.Uint64(s.number)}
+	if s.hash == (common.Hash{}) {
+		return cursor // nothing sent yet, resume from the genesis
+	}
+	hash := s.hash
+	cursor.BlockHash = &hash
+	if s.partial != nil {
+		index := hexutil.Uint(*s.partial)
+		cursor.LogIndex = &index
+	}
+	return cursor
+}
+
+// sent records a log as the last one sent.
+func (s *logStream) sent(log *types.Log) {
+	index := log.Index
+	s.number, s.hash, s.partial = log.BlockNumber, log.BlockHash, &index
+}
+
+// next sends the events since the last block sent up to the current head,
+// returning the first error of the search or of the send.
+func (s *logStream) next(ctx context.Context, send func(*LogEvent) error) error {
+	db := s.backend.ChainDb()
+
+	// Roll back to the common ancestor if the last block sent was reorged out
+	if s.hash != (common.Hash{}) && rawdb.ReadCanonicalHash(db, s.number) != s.hash {
+		header, err := canonicalAncestor(db, s.hash, s.number)
+		if err != nil {
+			return err
+		}
+		s.number, s.hash, s.partial = header.Number.Uint64(), header.Hash(), nil
+
+		hash := s.hash
+		if err := send(&LogEvent{Type: LogEventReorg, Ancestor: &LogCursor{BlockNumber: hexutil.Uint64(s.number), BlockHash: &hash}}); err != nil {
+			return err
+		}
+	}
+	// Send the rest of a partially sent block
+	if s.partial != nil {
+		logs, err := NewBlockFilter(s.backend, s.hash, s.addresses, s.topics).Logs(ctx)
+		if err != nil {
+			return err
+		}
+		for _, log := range logs {
+			if log.Index > *s.partial {
+				if err := send(&LogEvent{Type: LogEventLog, Log: log}); err != nil {
+					return err
+				}
+				s.sent(log)
+			}
+		}
+		s.partial = nil
+	}
+	// Send the logs of the new blocks, in batches to bound the memory used
+	head, err := s.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil || head == nil {
+		return err
+	}
+	begin := s.number + 1
+	if s.hash == (common.Hash{}) {
+		begin = 0
+	}
+	for begin <= head.Number.Uint64() {
+		end := begin + logStreamBatch - 1
+		if end > head.Number.Uint64() {
+			end = head.Number.Uint64()
+		}
+		// The logs are attributed to the blocks of the hash read beforehand,
+		// a reorg during the search being detected by the next round
+		hash := rawdb.ReadCanonicalHash(db, end)
+		if hash == (common.Hash{}) {
+			return nil
+		}
+		logs, err := NewRangeFilter(s.backend, int64(begin), int64(end), s.addresses, s.topics).Logs(ctx)
+		if err != nil {
+			return err
+		}
+		for _, log := range logs {
+			if err := send(&LogEvent{Type: LogEventLog, Log: log}); err != nil {
+				return err
+			}
+			s.sent(log)
+		}
+		s.number, s.hash, s.partial = end, hash, nil
+		begin = end + 1
+	}
+	return nil
+}
+
+// canonicalAncestor returns the header of the first ancestor of a block in the
+// canonical chain.
+func canonicalAncestor(db ethdb.Reader, hash common.Hash, number uint64) (*types.Header, error) {
+	header := rawdb.ReadHeader(db, hash, number)
+	for header != nil && header.Number.Sign() > 0 && rawdb.ReadCanonicalHash(db, header.Number.Uint64()) != header.Hash() {
+		header = rawdb.ReadHeader(db, header.ParentHash, header.Number.Uint64()-1)
+	}
+	if header == nil {
+		return nil, fmt.Errorf("ancestor of block %d [%x] not found", number, hash)
+	}
+	return header, nil
+}
+
+// ResumableLogs creates a subscription streaming the logs matching the given
+// criteria from a cursor, the historical logs first, then the new ones. Reorgs
+// of the blocks sent are notified with their common ancestor, the logs sent
+// after it being invalid, and the logs of the new chain follow.
+func (api *PublicFilterAPI) ResumableLogs(ctx context.Context, crit FilterCriteria, cursor *LogCursor) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	stream, err := newLogStream(ctx, api.backend, crit, cursor)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		rpcSub  = notifier.CreateSubscription()
+		headers = make(chan *types.Header)
+		headSub = api.events.SubscribeNewHeads(headers)
+		wake    = make(chan struct{}, 1)
+	)
+	streamCtx, cancel := context.WithCancel(context.Background())
+	wake <- struct{}{}
+
+	// Drain the head events, the event system not waiting for the stream
+	go func() {
+		defer headSub.Unsubscribe()
+		defer cancel()
+		for {
+			select {
+			case <-headers:
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			case <-rpcSub.Err(): // client send an unsubscribe request
+				return
+			case <-notifier.Closed(): // connection dropped
+				return
+			}
+		}
+	}()
+	go func() {
+		send := func(ev *LogEvent) error {
+			return notifier.Notify(rpcSub.ID, ev)
+		}
+		for {
+			select {
+			case <-wake:
+				if err := stream.next(streamCtx, send); err != nil {
+					if streamCtx.Err() == nil {
+						log.Debug("Resumable log subscription failed", "id", rpcSub.ID, "err", err)
+
+						// Tell the client where to resume from before stopping
+						send(&LogEvent{Type: LogEventError, Error: err.Error(), Cursor: stream.cursor()})
+					}
+					cancel()
+					return
+				}
+			case <-streamCtx.Done():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// EOF
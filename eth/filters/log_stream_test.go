@@ -0,0 +1,207 @@
+// log_stream_test.go
+
+package filters
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// writeStreamTestBlock writes a canonical block on top of a parent, with one
+// transaction emitting logs of the given addresses.
+func writeStreamTestBlock(db ethdb.Database, parent *types.Header, fork byte, addresses ...common.Address) *types.Block {
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		Extra:      []byte{fork},
+	}
+	tx := types.NewTransaction(header.Number.Uint64(), common.Address{0xff}, common.Big0, 21000, common.Big1, nil)
+	receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, TxHash: tx.Hash(), GasUsed: 21000}
+	for _, address := range addresses {
+		receipt.Logs = append(receipt.Logs, &types.Log{Address: address})
+	}
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+	block := types.NewBlock(header, []*types.Transaction{tx}, nil, []*types.Receipt{receipt}, trie.NewStackTrie(nil))
+	rawdb.WriteBlock(db, block)
+	rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), types.Receipts{receipt})
+	rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+	rawdb.WriteHeadBlockHash(db, block.Hash())
+	return block
+}
+
+func subscribeStreamTest(t *testing.T, client *rpc.Client, address common.Address, cursor *LogCursor) (chan *LogEvent, *rpc.ClientSubscription) {
+	events := make(chan *LogEvent, 16)
+	crit := map[string]interface{}{"address": address}
+	sub, err := client.EthSubscribe(context.Background(), events, "resumableLogs", crit, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events, sub
+}
+
+func expectStreamEvents(t *testing.T, events chan *LogEvent, want ...*LogEvent) {
+	t.Helper()
+	for i, w := range want {
+		select {
+		case ev := <-events:
+			if ev.Type != w.Type {
+				t.Fatalf("event %d: type mismatch: have %s, want %s", i, ev.Type, w.Type)
+			}
+			if w.Log != nil && (ev.Log.BlockHash != w.Log.BlockHash || ev.Log.Index != w.Log.Index) {
+				t.Fatalf("event %d: log mismatch: have %d/%x/%d, want %d/%x/%d", i,
+					ev.Log.BlockNumber, ev.Log.BlockHash, ev.Log.Index, w.Log.BlockNumber, w.Log.BlockHash, w.Log.Index)
+			}
+			if w.Ancestor != nil && *ev.Ancestor.BlockHash != *w.Ancestor.BlockHash {
+				t.Fatalf("event %d: ancestor mismatch: have %x, want %x", i, *ev.Ancestor.BlockHash, *w.Ancestor.BlockHash)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("event %d: timeout", i)
+		}
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event %+v", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestResumableLogs(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false, deadline)
+		server  = rpc.NewServer()
+		addr    = common.HexToAddress("0x1234")
+		other   = common.HexToAddress("0x5678")
+	)
+	server.RegisterName("eth", api)
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	// Blocks 1-4, with logs of addr in blocks 2 and 4
+	genesis := &types.Header{Number: common.Big0}
+	rawdb.WriteHeader(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+	b1 := writeStreamTestBlock(db, genesis, 0)
+	b2 := writeStreamTestBlock(db, b1.Header(), 0, addr, other)
+	b3 := writeStreamTestBlock(db, b2.Header(), 0)
+	b4 := writeStreamTestBlock(db, b3.Header(), 0, addr, addr)
+
+	logAt := func(block *types.Block, index uint) *LogEvent {
+		return &LogEvent{Type: LogEventLog, Log: &types.Log{BlockNumber: block.NumberU64(), BlockHash: block.Hash(), Index: index}}
+	}
+	// Historical logs are replayed from a block number
+	events, sub := subscribeStreamTest(t, client, addr, &LogCursor{BlockNumber: 1})
+	defer sub.Unsubscribe()
+	expectStreamEvents(t, events, logAt(b2, 0), logAt(b4, 0), logAt(b4, 1))
+
+	// Subscriptions resume after the last log received
+	hash4 := b4.Hash()
+	index := hexutil.Uint(0)
+	resumed, resumedSub := subscribeStreamTest(t, client, addr, &LogCursor{BlockNumber: 4, BlockHash: &hash4, LogIndex: &index})
+	defer resumedSub.Unsubscribe()
+	expectStreamEvents(t, resumed, logAt(b4, 1))
+
+	// Reorg blocks 4 and 5 out, the subscriptions rolling back to block 3
+	b4r := writeStreamTestBlock(db, b3.Header(), 1)
+	b5r := writeStreamTestBlock(db, b4r.Header(), 1, other, addr)
+	backend.chainFeed.Send(core.ChainEvent{Block: b5r, Hash: b5r.Hash()})
+
+	hash3 := b3.Hash()
+	reorg := &LogEvent{Type: LogEventReorg, Ancestor: &LogCursor{BlockNumber: 3, BlockHash: &hash3}}
+	expectStreamEvents(t, events, reorg, logAt(b5r, 1))
+	expectStreamEvents(t, resumed, reorg, logAt(b5r, 1))
+
+	// Resuming from a reorged block starts with the reorg
+	stale, staleSub := subscribeStreamTest(t, client, addr, &LogCursor{BlockNumber: 4, BlockHash: &hash4})
+	defer staleSub.Unsubscribe()
+	expectStreamEvents(t, stale, reorg, logAt(b5r, 1))
+
+	// Live logs are streamed without cursor
+	live, liveSub := subscribeStreamTest(t, client, addr, nil)
+	defer liveSub.Unsubscribe()
+	b6 := writeStreamTestBlock(db, b5r.Header(), 1, addr)
+	backend.chainFeed.Send(core.ChainEvent{Block: b6, Hash: b6.Hash()})
+	expectStreamEvents(t, live, logAt(b6, 0))
+	expectStreamEvents(t, events, logAt(b6, 0))
+}
+
+// failingBackend is a test backend failing to retrieve logs and receipts once
+// broken.
+type failingBackend struct {
+	*testBackend
+	broken bool
+}
+
+func (b *failingBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
+	if b.broken {
+		return nil, errors.New("logs unavailable")
+	}
+	return b.testBackend.GetLogs(ctx, hash)
+}
+
+func (b *failingBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	if b.broken {
+		return nil, errors.New("receipts unavailable")
+	}
+	return b.testBackend.GetReceipts(ctx, hash)
+}
+
+func TestResumableLogsError(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &failingBackend{testBackend: &testBackend{db: db}}
+		api     = NewPublicFilterAPI(backend, false, deadline)
+		server  = rpc.NewServer()
+		addr    = common.HexToAddress("0x1234")
+	)
+	server.RegisterName("eth", api)
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	genesis := &types.Header{Number: common.Big0}
+	rawdb.WriteHeader(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+	b1 := writeStreamTestBlock(db, genesis, 0, addr)
+	b2 := writeStreamTestBlock(db, b1.Header(), 0, addr)
+
+	events, sub := subscribeStreamTest(t, client, addr, &LogCursor{BlockNumber: 1})
+	defer sub.Unsubscribe()
+	expectStreamEvents(t, events,
+		&LogEvent{Type: LogEventLog, Log: &types.Log{BlockNumber: 1, BlockHash: b1.Hash()}},
+		&LogEvent{Type: LogEventLog, Log: &types.Log{BlockNumber: 2, BlockHash: b2.Hash()}},
+	)
+
+	// The failure to search a new block ends the stream with the last block sent
+	backend.broken = true
+	b3 := writeStreamTestBlock(db, b2.Header(), 0, addr)
+	backend.chainFeed.Send(core.ChainEvent{Block: b3, Hash: b3.Hash()})
+
+	select {
+	case ev := <-events:
+		if ev.Type != LogEventError || ev.Error == "" {
+			t.Fatalf("expected error event, got %+v", ev)
+		}
+		if ev.Cursor == nil || ev.Cursor.BlockNumber != 2 || ev.Cursor.BlockHash == nil || *ev.Cursor.BlockHash != b2.Hash() || ev.Cursor.LogIndex != nil {
+			t.Fatalf("cursor mismatch: have %+v, want block 2 [%x]", ev.Cursor, b2.Hash())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("error event timeout")
+	}
+	expectStreamEvents(t, events)
+}
+
+// EOF
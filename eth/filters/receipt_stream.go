@@ -0,0 +1,253 @@
+// receipt_stream.go
+
+package filters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ReceiptCriteria selects the receipts of a receipts subscription. Each non
+// empty list restricts the transactions to those matching any of its items,
+// the receipts of all transactions being sent without criteria.
+type ReceiptCriteria struct {
+	TxHashes []common.Hash    `json:"txHashes"`
+	From     []common.Address `json:"from"`
+	To       []common.Address `json:"to"`
+}
+
+// receiptStream streams the receipts of the transactions of the new canonical
+// blocks matching a criteria.
+type receiptStream struct {
+	backend Backend
+
+	txHashes map[common.Hash]struct{}
+	from     map[common.Address]struct{}
+	to       map[common.Address]struct{}
+
+	number uint64      // Number of the last block sent
+	hash   common.Hash // Hash of the last block sent
+}
+
+// newReceiptStream creates a receipt stream starting after the current head.
+func newReceiptStream(ctx context.Context, backend Backend, crit ReceiptCriteria) (*receiptStream, error) {
+	head, err := backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	} else if head == nil {
+		return nil, errors.New("unknown head block")
+	}
+	s := &receiptStream{
+		backend: backend,
+		number:  head.Number.Uint64(),
+		hash:    head.Hash(),
+	}
+	if len(crit.TxHashes) > 0 {
+		s.txHashes = make(map[common.Hash]struct{}, len(crit.TxHashes))
+		for _, hash := range crit.TxHashes {
+			s.txHashes[hash] = struct{}{}
+		}
+	}
+	if len(crit.From) > 0 {
+		s.from = make(map[common.Address]struct{}, len(crit.From))
+		for _, addr := range crit.From {
+			s.from[addr] = struct{}{}
+		}
+	}
+	if len(crit.To) > 0 {
+		s.to = make(map[common.Address]struct{}, len(crit.To))
+		for _, addr := range crit.To {
+			s.to[addr] = struct{}{}
+		}
+	}
+	return s, nil
+}
+
+// match returns whether a transaction matches the criteria.
+func (s *receiptStream) match(tx *types.Transaction, signer types.Signer) bool {
+	if s.txHashes != nil {
+		if _, ok := s.txHashes[tx.Hash()]; !ok {
+			return false
+		}
+	}
+	if s.to != nil {
+		if tx.To() == nil {
+			return false
+		}
+		if _, ok := s.to[*tx.To()]; !ok {
+			return false
+		}
+	}
+	if s.from != nil {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return false
+		}
+		if _, ok := s.from[from]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// next sends the matching receipts of the canonical blocks since the last block
+// sent up to the current head. After a reorg, the receipts sent since the
+// common ancestor are sent again marked as removed, from the newest block, then
+// the receipts of the new chain are sent from the common ancestor.
+func (s *receiptStream) next(ctx context.Context, send func(map[string]interface{}) error) error {
+	db := s.backend.ChainDb()
+	if rawdb.ReadCanonicalHash(db, s.number) != s.hash {
+		ancestor, err := canonicalAncestor(db, s.hash, s.number)
+		if err != nil {
+			return err
+		}
+		for number, hash := s.number, s.hash; number > ancestor.Number.Uint64(); number-- {
+			header, err := s.sendBlock(ctx, hash, number, true, send)
+			if err != nil {
+				return err
+			}
+			hash = header.ParentHash
+		}
+		s.number, s.hash = ancestor.Number.Uint64(), ancestor.Hash()
+	}
+	head, err := s.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil || head == nil {
+		return err
+	}
+	for number := s.number + 1; number <= head.Number.Uint64(); number++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hash := rawdb.ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return nil
+		}
+		if _, err := s.sendBlock(ctx, hash, number, false, send); err != nil {
+			return err
+		}
+		s.number, s.hash = number, hash
+	}
+	return nil
+}
+
+// sendBlock sends the matching receipts of a block, marked as removed if the
+// block was reorged out, and returns its header.
+func (s *receiptStream) sendBlock(ctx context.Context, hash common.Hash, number uint64, removed bool, send func(map[string]interface{}) error) (*types.Header, error) {
+	db := s.backend.ChainDb()
+	header, body := rawdb.ReadHeader(db, hash, number), rawdb.ReadBody(db, hash, number)
+	if header == nil || body == nil {
+		return nil, fmt.Errorf("block %d [%x] not found", number, hash)
+	}
+	var (
+		config   = s.backend.ChainConfig()
+		signer   = types.MakeSigner(config, header.Number)
+		receipts types.Receipts
+		err      error
+	)
+	for i, tx := range body.Transactions {
+		if !s.match(tx, signer) {
+			continue
+		}
+		if receipts == nil {
+			if receipts, err = s.backend.GetReceipts(ctx, hash); err != nil {
+				return nil, err
+			}
+			if len(receipts) != len(body.Transactions) {
+				return nil, fmt.Errorf("receipts of block %d [%x] not found", number, hash)
+			}
+		}
+		fields := ethapi.RPCMarshalReceipt(receipts[i], tx, header, uint64(i), config)
+		if removed {
+			fields["removed"] = true
+		}
+		if err := send(fields); err != nil {
+			return nil, err
+		}
+	}
+	return header, nil
+}
+
+// Receipts creates a subscription sending the receipts of the transactions
+// matching the given criteria when they are included in the canonical chain,
+// in the format of eth_getTransactionReceipt. After a reorg, the receipts of
+// the blocks reorged out are sent again with a "removed" field set to true,
+// then the receipts of the new chain are sent. A failure ends the subscription
+// with a last notification having an "error" field, and the "blockNumber" and
+// "blockHash" of the last block whose receipts were all sent.
+func (api *PublicFilterAPI) Receipts(ctx context.Context, crit ReceiptCriteria) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if api.events.lightMode {
+		return nil, errors.New("receipts subscription not supported in light mode")
+	}
+	stream, err := newReceiptStream(ctx, api.backend, crit)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		rpcSub  = notifier.CreateSubscription()
+		headers = make(chan *types.Header)
+		headSub = api.events.SubscribeNewHeads(headers)
+		wake    = make(chan struct{}, 1)
+	)
+	streamCtx, cancel := context.WithCancel(context.Background())
+
+	// Drain the head events, the event system not waiting for the stream
+	go func() {
+		defer headSub.Unsubscribe()
+		defer cancel()
+		for {
+			select {
+			case <-headers:
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			case <-rpcSub.Err(): // client send an unsubscribe request
+				return
+			case <-notifier.Closed(): // connection dropped
+				return
+			}
+		}
+	}()
+	go func() {
+		send := func(receipt map[string]interface{}) error {
+			return notifier.Notify(rpcSub.ID, receipt)
+		}
+		for {
+			select {
+			case <-wake:
+				if err := stream.next(streamCtx, send); err != nil {
+					if streamCtx.Err() == nil {
+						log.Debug("Receipts subscription failed", "id", rpcSub.ID, "err", err)
+
+						// Tell the client the last block sent before stopping
+						send(map[string]interface{}{
+							"error":       err.Error(),
+							"blockNumber": hexutil.Uint64(stream.number),
+							"blockHash":   stream.hash,
+						})
+					}
+					cancel()
+					return
+				}
+			case <-streamCtx.Done():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// EOF
@@ -0,0 +1,171 @@
+// receipt_stream_test.go
+
+package filters
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// writeReceiptTestBlock writes a canonical London block on top of a parent,
+// with one transaction to 0xff having the block number as nonce.
+func writeReceiptTestBlock(db ethdb.Database, parent *types.Header, fork byte) *types.Block {
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		Extra:      []byte{fork},
+		BaseFee:    common.Big1,
+	}
+	tx := types.NewTransaction(header.Number.Uint64(), common.Address{0xff}, common.Big0, 21000, common.Big1, nil)
+	receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, TxHash: tx.Hash(), GasUsed: 21000}
+
+	block := types.NewBlock(header, []*types.Transaction{tx}, nil, []*types.Receipt{receipt}, trie.NewStackTrie(nil))
+	rawdb.WriteBlock(db, block)
+	rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), types.Receipts{receipt})
+	rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+	rawdb.WriteHeadBlockHash(db, block.Hash())
+	return block
+}
+
+func TestReceiptsSubscription(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false, deadline)
+		server  = rpc.NewServer()
+	)
+	server.RegisterName("eth", api)
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	genesis := &types.Header{Number: common.Big0}
+	rawdb.WriteHeader(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+	rawdb.WriteHeadBlockHash(db, genesis.Hash())
+
+	txHash := types.NewTransaction(2, common.Address{0xff}, common.Big0, 21000, common.Big1, nil).Hash()
+
+	var (
+		byHash    = make(chan map[string]interface{}, 4)
+		byTo      = make(chan map[string]interface{}, 4)
+		byOtherTo = make(chan map[string]interface{}, 4)
+	)
+	for ch, crit := range map[chan map[string]interface{}]ReceiptCriteria{
+		byHash:    {TxHashes: []common.Hash{txHash}},
+		byTo:      {To: []common.Address{{0xff}}},
+		byOtherTo: {To: []common.Address{{0xee}}},
+	} {
+		sub, err := client.EthSubscribe(context.Background(), ch, "receipts", crit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sub.Unsubscribe()
+	}
+	b1 := writeReceiptTestBlock(db, genesis, 0)
+	b2 := writeReceiptTestBlock(db, b1.Header(), 0)
+	backend.chainFeed.Send(core.ChainEvent{Block: b2, Hash: b2.Hash()})
+
+	// expect checks the receipts of blocks, the first ones being removed
+	expect := func(ch chan map[string]interface{}, removed int, blocks ...*types.Block) {
+		t.Helper()
+		for i, block := range blocks {
+			select {
+			case receipt := <-ch:
+				if receipt["blockHash"] != block.Hash().Hex() || receipt["transactionHash"] != block.Transactions()[0].Hash().Hex() {
+					t.Fatalf("receipt mismatch: have %v, want block %x", receipt, block.Hash())
+				}
+				if _, ok := receipt["removed"]; ok != (i < removed) {
+					t.Fatalf("receipt of block %d: removed mismatch: have %v, want %v", block.NumberU64(), ok, i < removed)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timeout waiting for the receipt of block %d", block.NumberU64())
+			}
+		}
+		select {
+		case receipt := <-ch:
+			t.Fatalf("unexpected receipt %v", receipt)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	expect(byHash, 0, b2)
+	expect(byTo, 0, b1, b2)
+	expect(byOtherTo, 0)
+
+	// After a reorg, the receipts of the blocks reorged out are removed and
+	// the receipts of the new chain are sent
+	b2r := writeReceiptTestBlock(db, b1.Header(), 1)
+	b3r := writeReceiptTestBlock(db, b2r.Header(), 1)
+	backend.chainFeed.Send(core.ChainEvent{Block: b3r, Hash: b3r.Hash()})
+	expect(byHash, 1, b2, b2r)
+	expect(byTo, 1, b2, b2r, b3r)
+	expect(byOtherTo, 0)
+}
+
+func TestReceiptsSubscriptionError(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &failingBackend{testBackend: &testBackend{db: db}}
+		api     = NewPublicFilterAPI(backend, false, deadline)
+		server  = rpc.NewServer()
+	)
+	server.RegisterName("eth", api)
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	genesis := &types.Header{Number: common.Big0}
+	rawdb.WriteHeader(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+	rawdb.WriteHeadBlockHash(db, genesis.Hash())
+
+	receipts := make(chan map[string]interface{}, 4)
+	sub, err := client.EthSubscribe(context.Background(), receipts, "receipts", ReceiptCriteria{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	b1 := writeReceiptTestBlock(db, genesis, 0)
+	backend.chainFeed.Send(core.ChainEvent{Block: b1, Hash: b1.Hash()})
+	select {
+	case receipt := <-receipts:
+		if receipt["blockHash"] != b1.Hash().Hex() {
+			t.Fatalf("receipt mismatch: have %v, want block %x", receipt, b1.Hash())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the receipt of block 1")
+	}
+
+	// The failure to read the receipts of a new block ends the subscription
+	// with the last block sent
+	backend.broken = true
+	b2 := writeReceiptTestBlock(db, b1.Header(), 0)
+	backend.chainFeed.Send(core.ChainEvent{Block: b2, Hash: b2.Hash()})
+	select {
+	case notification := <-receipts:
+		if notification["error"] == nil || notification["error"] == "" {
+			t.Fatalf("expected error notification, got %v", notification)
+		}
+		if notification["blockNumber"] != "0x1" || notification["blockHash"] != b1.Hash().Hex() {
+			t.Fatalf("last block mismatch: have %v, want block 1 [%x]", notification, b1.Hash())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the error notification")
+	}
+	select {
+	case receipt := <-receipts:
+		t.Fatalf("unexpected notification %v", receipt)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// EOF
@@ -67,6 +67,18 @@ type txPool interface {
 	// AddRemotes should add the given transactions to the pool.
 	AddRemotes([]*types.Transaction) []error
 
+	// AddRemotesFrom should add the given transactions received from a peer
+	// to the pool.
+	AddRemotesFrom(string, []*types.Transaction) []error
+
+	// AddPrivates should add the given private transactions, expiring at the
+	// given blocks, to the pool.
+	AddPrivates(string, []*types.Transaction, []uint64) []error
+
+	// PrivateExpiry should return the expiry block of a private transaction,
+	// false if the transaction is not a private one.
+	PrivateExpiry(hash common.Hash) (uint64, bool)
+
 	// Pending should return pending transactions.
 	// The slice should be modifiable by the caller.
 	Pending(enforceTips bool) map[common.Address]types.Transactions
@@ -274,7 +286,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		}
 		return p.RequestTxs(hashes)
 	}
-	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, h.txpool.AddRemotes, fetchTx)
+	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, h.txpool.AddRemotesFrom, fetchTx)
 	h.chainSync = newChainSyncer(h)
 	return h, nil
 }
@@ -599,9 +611,16 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		txset = make(map[*ethPeer][]common.Hash) // Set peer->hash to transfer directly
 		annos = make(map[*ethPeer][]common.Hash) // Set peer->hash to announce
 
+		private  types.Transactions // Private transactions, only forwarded to partners
+		expiries []uint64
 	)
 	// Broadcast transactions to a batch of peers not knowing about it
 	for _, tx := range txs {
+		if expiry, ok := h.txpool.PrivateExpiry(tx.Hash()); ok {
+			private = append(private, tx)
+			expiries = append(expiries, expiry)
+			continue
+		}
 		peers := h.peers.peersWithoutTransaction2(tx.Hash())
 		// Send the tx unconditionally to a subset of our peers
 		// numDirect := int(math.Sqrt(float64(len(peers))))
@@ -614,6 +633,9 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		for _, peer := range peers[numDirect:] {
 			annos[peer] = append(annos[peer], tx.Hash())
 		}
+		if len(peers) > 0 {
+			core.TraceTx(tx.Hash(), &core.TxTraceEvent{Event: core.TxEventBroadcast, Peers: len(peers)})
+		}
 	}
 	for peer, hashes := range txset {
 		directPeers++
@@ -625,9 +647,41 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		annoCount += len(hashes)
 		peer.AsyncSendPooledTransactionHashes(hashes)
 	}
+	if len(private) > 0 {
+		h.forwardPrivateTransactions(private, expiries)
+	}
 	log.Debug("Transaction broadcast", "txs", len(txs),
 		"announce packs", annoPeers, "announced hashes", annoCount,
-		"tx packs", directPeers, "broadcast txs", directCount)
+		"tx packs", directPeers, "broadcast txs", directCount, "private txs", len(private))
+}
+
+// forwardPrivateTransactions sends private transactions to the partners not
+// known to have them, and to nobody else.
+func (h *handler) forwardPrivateTransactions(txs types.Transactions, expiries []uint64) {
+	counts := make([]int, len(txs))
+	for _, peer := range h.peers.partnersWithoutTransactions(txs) {
+		var (
+			send   types.Transactions
+			blocks []uint64
+		)
+		for i, tx := range txs {
+			if !peer.KnownTransaction(tx.Hash()) {
+				send = append(send, tx)
+				blocks = append(blocks, expiries[i])
+				counts[i]++
+			}
+		}
+		go func(peer *ethPeer) {
+			if err := peer.SendPrivateTransactions(send, blocks); err != nil {
+				peer.Log().Debug("Failed to forward private transactions", "count", len(send), "err", err)
+			}
+		}(peer)
+	}
+	for i, tx := range txs {
+		if counts[i] > 0 {
+			core.TraceTx(tx.Hash(), &core.TxTraceEvent{Event: core.TxEventBroadcast, Peers: counts[i], Reason: "private"})
+		}
+	}
 }
 
 // minedBroadcastLoop sends mined blocks to connected peers.
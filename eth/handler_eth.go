@@ -71,11 +71,16 @@ func (h *ethHandler) Handle(peer *eth.Peer, packet eth.Packet) error {
 		return h.txFetcher.Notify(peer.ID(), *packet)
 
 	case *eth.TransactionsPacket:
+		h.traceReceived(peer, *packet, "")
 		return h.txFetcher.Enqueue(peer.ID(), *packet, false)
 
 	case *eth.PooledTransactionsPacket:
+		h.traceReceived(peer, *packet, "")
 		return h.txFetcher.Enqueue(peer.ID(), *packet, true)
 
+	case *eth.PrivateTransactionsPacket:
+		return h.handlePrivateTransactions(peer, packet)
+
 	default:
 		return fmt.Errorf("unexpected eth packet type: %T", packet)
 	}
@@ -136,3 +141,29 @@ func (h *ethHandler) handleBlockBroadcast(peer *eth.Peer, block *types.Block, td
 	}
 	return nil
 }
+
+// handlePrivateTransactions is invoked from a peer's message handler when it
+// forwards private transactions for the local node to mine.
+func (h *ethHandler) handlePrivateTransactions(peer *eth.Peer, packet *eth.PrivateTransactionsPacket) error {
+	txs := make([]*types.Transaction, len(packet.Txs))
+	for i, txex := range packet.Txs {
+		txs[i] = txex.Tx
+	}
+	h.traceReceived(peer, txs, "private")
+	for i, err := range h.txpool.AddPrivates(peer.ID(), txs, packet.Expiries) {
+		if err != nil && err != core.ErrAlreadyKnown {
+			peer.Log().Trace("Rejected private transaction", "hash", txs[i].Hash(), "err", err)
+		}
+	}
+	return nil
+}
+
+// traceReceived records the reception of the transactions not known to the
+// pool yet in their lifecycle logs.
+func (h *ethHandler) traceReceived(peer *eth.Peer, txs []*types.Transaction, reason string) {
+	for _, tx := range txs {
+		if !h.txpool.Has(tx.Hash()) {
+			core.TraceTx(tx.Hash(), &core.TxTraceEvent{Event: core.TxEventReceived, Peer: peer.ID(), Reason: reason})
+		}
+	}
+}
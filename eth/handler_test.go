@@ -91,6 +91,21 @@ func (p *testTxPool) AddRemotes(txs []*types.Transaction) []error {
 	return make([]error, len(txs))
 }
 
+// AddRemotesFrom appends a batch of transactions from a peer to the pool.
+func (p *testTxPool) AddRemotesFrom(peer string, txs []*types.Transaction) []error {
+	return p.AddRemotes(txs)
+}
+
+// AddPrivates appends a batch of private transactions to the pool.
+func (p *testTxPool) AddPrivates(peer string, txs []*types.Transaction, expiries []uint64) []error {
+	return p.AddRemotes(txs)
+}
+
+// PrivateExpiry returns false, the test pool has no private transactions.
+func (p *testTxPool) PrivateExpiry(hash common.Hash) (uint64, bool) {
+	return 0, false
+}
+
 // Pending returns all the transactions known to the pool
 func (p *testTxPool) Pending(enforceTips bool) map[common.Address]types.Transactions {
 	p.lock.RLock()
@@ -22,6 +22,7 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -276,6 +277,27 @@ var (
 	isHub int = -1 //  -1: unset, 1: hub, 0: not hub
 )
 
+// partnersWithoutTransactions retrieves a list of partner peers that do not
+// have some of the given transactions in their set of known hashes.
+func (ps *peerSet) partnersWithoutTransactions(txs types.Transactions) []*ethPeer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*ethPeer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		if !wemixminer.IsPartner(p.ID()) {
+			continue
+		}
+		for _, tx := range txs {
+			if !p.KnownTransaction(tx.Hash()) {
+				list = append(list, p)
+				break
+			}
+		}
+	}
+	return list
+}
+
 // peersWithoutTransaction2 retrieves a list of peers that do not have a given
 // transaction in their set of known hashes.
 func (ps *peerSet) peersWithoutTransaction2(hash common.Hash) []*ethPeer {
@@ -92,7 +92,11 @@ func (p *Peer) broadcastTransactions() {
 			if len(txs) > 0 {
 				done = make(chan struct{})
 				go func() {
-					if err := p.SendTransactions(txs); err != nil {
+					send := p.SendTransactions
+					if p.sendsTransactionsEx() {
+						send = p.SendTransactionsExSigned
+					}
+					if err := send(txs); err != nil {
 						fail <- err
 						return
 					}
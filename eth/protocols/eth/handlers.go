@@ -893,13 +893,38 @@ func handleTransactionsEx(backend Backend, msg Decoder, peer *Peer) error {
 		return nil
 	}
 	// Transactions can be processed, parse all of them and deliver to the pool
-	var txexs TransactionsExPacket
-	if err := msg.Decode(&txexs); err != nil {
+	var raw rlp.RawValue
+	if err := msg.Decode(&raw); err != nil {
 		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 	}
+	txexs, sig, expiries, err := decodeTransactionsEx(raw)
+	if err != nil {
+		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+	}
+	// Private transactions are only of use to partners, which mine them, and
+	// only partners may forward them, in signed batches
+	if expiries != nil {
+		if !wemixminer.AmPartner() {
+			return nil
+		}
+		if sig == nil || !wemixminer.IsPartner(peer.ID()) {
+			peer.Log().Debug("Dropping private transactions from non-partner", "signed", sig != nil)
+			return nil
+		}
+	}
+	// Only the senders in batches signed by partners are trusted, after
+	// re-verifying a sample of them. Partners lying get disconnected.
+	signer := types.MakeSigner(backend.Chain().Config(), backend.Chain().CurrentBlock().Number())
+	trustIt := false
+	if sig != nil && wemixminer.IsPartner(peer.ID()) {
+		if err := verifyTransactionsEx(peer.Node().Pubkey(), signer, txexs, sig, params.PartnerTxExSampleRate); err != nil {
+			peer.Log().Warn("Dropping partner sending bad transaction batch", "err", err)
+			return err
+		}
+		trustIt = true
+	}
 	f := func() error {
-		signer := types.MakeSigner(backend.Chain().Config(), backend.Chain().CurrentBlock().Number())
-		txs := types.TxExs2Txs(signer, txexs, wemixminer.IsPartner(peer.ID()))
+		txs := types.TxExs2Txs(signer, txexs, trustIt)
 		for i, tx := range txs {
 			// Validate and mark the remote transaction
 			if tx == nil {
@@ -907,6 +932,9 @@ func handleTransactionsEx(backend Backend, msg Decoder, peer *Peer) error {
 			}
 			peer.markTransaction(tx.Hash())
 		}
+		if expiries != nil {
+			return backend.Handle(peer, &PrivateTransactionsPacket{Txs: txexs, Sig: sig, Expiries: expiries})
+		}
 		txsp := TransactionsPacket(txs)
 		return backend.Handle(peer, &txsp)
 	}
@@ -132,6 +132,21 @@ type TransactionsPacket []*types.Transaction
 // TransactionsExPacket is the network packet for broadcasting new extended transactions.
 type TransactionsExPacket []*types.TransactionEx
 
+// TransactionsExSignedPacket is the form of TransactionsExPacket partners send
+// each other, signed by the node key of the sender.
+type TransactionsExSignedPacket struct {
+	Txs TransactionsExPacket
+	Sig []byte // signature of types.TxExsHash(Txs)
+}
+
+// PrivateTransactionsPacket is the form of TransactionsExPacket carrying
+// private transactions to partners, which are not to be gossiped.
+type PrivateTransactionsPacket struct {
+	Txs      TransactionsExPacket
+	Sig      []byte   // signature of types.TxExsHash(Txs)
+	Expiries []uint64 // last blocks the transactions can be included in
+}
+
 // GetBlockHeadersPacket represents a block header query.
 type GetBlockHeadersPacket struct {
 	Origin  HashOrNumber // Block from which to retrieve headers
@@ -391,6 +406,9 @@ func (*TransactionsPacket) Kind() byte   { return TransactionsMsg }
 func (*TransactionsExPacket) Name() string { return "TransactionsEx" }
 func (*TransactionsExPacket) Kind() byte   { return TransactionsExMsg }
 
+func (*PrivateTransactionsPacket) Name() string { return "PrivateTransactions" }
+func (*PrivateTransactionsPacket) Kind() byte   { return TransactionsExMsg }
+
 func (*GetBlockHeadersPacket) Name() string { return "GetBlockHeaders" }
 func (*GetBlockHeadersPacket) Kind() byte   { return GetBlockHeadersMsg }
 
@@ -0,0 +1,168 @@
+// wemix_txex.go
+
+package eth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	wemixminer "github.com/ethereum/go-ethereum/wemix/miner"
+)
+
+var (
+	// nodeKey holds the *ecdsa.PrivateKey signing the transaction batches
+	// sent to partners
+	nodeKey atomic.Value
+
+	txExTrustedMeter  = metrics.NewRegisteredMeter("eth/txex/trusted", nil)  // senders taken from signed batches
+	txExVerifiedMeter = metrics.NewRegisteredMeter("eth/txex/verified", nil) // senders re-verified by sampling
+	txExForgedMeter   = metrics.NewRegisteredMeter("eth/txex/forged", nil)   // batches with forged senders
+
+	errBatchSignature = errors.New("invalid transaction batch signature")
+	errForgedSender   = errors.New("forged transaction sender")
+)
+
+// SetNodeKey sets the node key transaction batches sent to partners are
+// signed with.
+func SetNodeKey(key *ecdsa.PrivateKey) {
+	nodeKey.Store(key)
+}
+
+func getNodeKey() *ecdsa.PrivateKey {
+	key, _ := nodeKey.Load().(*ecdsa.PrivateKey)
+	return key
+}
+
+// sendsTransactionsEx tells if transactions are to be sent to the peer in
+// signed batches carrying the senders.
+func (p *Peer) sendsTransactionsEx() bool {
+	return params.PartnerTxExGossip && getNodeKey() != nil && wemixminer.AmPartner() && wemixminer.IsPartner(p.ID())
+}
+
+// SendTransactionsExSigned sends transactions with their senders to a
+// partner in a batch signed with the node key, and includes the hashes in
+// its transaction hash set for future reference.
+func (p *Peer) SendTransactionsExSigned(txs types.Transactions) error {
+	packet, err := signTransactionsEx(getNodeKey(), types.Txs2TxExs(txs))
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		p.knownTxs.Add(tx.Hash())
+	}
+	return p2p.Send(p.rw, TransactionsExMsg, packet)
+}
+
+// SendPrivateTransactions sends private transactions with their senders and
+// expiry blocks to a partner, and includes the hashes in its transaction hash
+// set for future reference.
+func (p *Peer) SendPrivateTransactions(txs types.Transactions, expiries []uint64) error {
+	key := getNodeKey()
+	if key == nil {
+		return errBatchSignature
+	}
+	packet, err := signTransactionsEx(key, types.Txs2TxExs(txs))
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		p.knownTxs.Add(tx.Hash())
+	}
+	return p2p.Send(p.rw, TransactionsExMsg, &PrivateTransactionsPacket{
+		Txs:      packet.Txs,
+		Sig:      packet.Sig,
+		Expiries: expiries,
+	})
+}
+
+// signTransactionsEx signs a batch of transactions and senders.
+func signTransactionsEx(key *ecdsa.PrivateKey, txexs TransactionsExPacket) (*TransactionsExSignedPacket, error) {
+	sig, err := crypto.Sign(types.TxExsHash(txexs).Bytes(), key)
+	if err != nil {
+		return nil, err
+	}
+	return &TransactionsExSignedPacket{Txs: txexs, Sig: sig}, nil
+}
+
+// decodeTransactionsEx decodes a TransactionsExMsg payload, a signed batch, a
+// private one or a plain one. The signature is nil for plain batches, and the
+// expiry blocks nil for all but private ones.
+func decodeTransactionsEx(raw rlp.RawValue) (TransactionsExPacket, []byte, []uint64, error) {
+	// A signed batch is a list of the transactions and a signature, a private
+	// one the same followed by a list of expiry blocks, and a plain one a flat
+	// list of transactions each followed by a 20 byte sender.
+	if content, _, err := rlp.SplitList(raw); err == nil {
+		if kind, _, rest, err := rlp.Split(content); err == nil && kind == rlp.List {
+			if kind, sig, rest, err := rlp.Split(rest); err == nil && kind == rlp.String && len(sig) == crypto.SignatureLength {
+				if len(rest) == 0 {
+					var packet TransactionsExSignedPacket
+					if err := rlp.DecodeBytes(raw, &packet); err != nil {
+						return nil, nil, nil, err
+					}
+					return packet.Txs, packet.Sig, nil, nil
+				}
+				if kind, _, rest, err := rlp.Split(rest); err == nil && kind == rlp.List && len(rest) == 0 {
+					var packet PrivateTransactionsPacket
+					if err := rlp.DecodeBytes(raw, &packet); err != nil {
+						return nil, nil, nil, err
+					}
+					if len(packet.Expiries) != len(packet.Txs) {
+						return nil, nil, nil, fmt.Errorf("%d expiry blocks for %d private transactions", len(packet.Expiries), len(packet.Txs))
+					}
+					if packet.Expiries == nil {
+						packet.Expiries = []uint64{}
+					}
+					return packet.Txs, packet.Sig, packet.Expiries, nil
+				}
+			}
+		}
+	}
+	var packet TransactionsExPacket
+	if err := rlp.DecodeBytes(raw, &packet); err != nil {
+		return nil, nil, nil, err
+	}
+	return packet, nil, nil, nil
+}
+
+// verifyTransactionsEx checks that a batch was signed with the given node key,
+// and ecrecovers the given fraction of the senders in it to catch lies.
+func verifyTransactionsEx(pub *ecdsa.PublicKey, signer types.Signer, txexs TransactionsExPacket, sig []byte, rate float64) error {
+	if pub == nil {
+		return errBatchSignature
+	}
+	signed, err := crypto.SigToPub(types.TxExsHash(txexs).Bytes(), sig)
+	if err != nil || !bytes.Equal(crypto.FromECDSAPub(signed), crypto.FromECDSAPub(pub)) {
+		return errBatchSignature
+	}
+	var verified, trusted int64
+	for _, txex := range txexs {
+		if txex.Tx == nil || txex.From == (common.Address{}) {
+			continue
+		}
+		if rate < 1 && rand.Float64() >= rate {
+			trusted++
+			continue
+		}
+		if from, err := types.Sender(signer, txex.Tx); err != nil || from != txex.From {
+			txExForgedMeter.Mark(1)
+			return fmt.Errorf("%w: transaction %v, claimed %v", errForgedSender, txex.Tx.Hash(), txex.From)
+		}
+		verified++
+	}
+	txExVerifiedMeter.Mark(verified)
+	txExTrustedMeter.Mark(trusted)
+	return nil
+}
+
+// EOF
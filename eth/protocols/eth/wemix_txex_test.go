@@ -0,0 +1,96 @@
+// wemix_txex_test.go
+
+package eth
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestTransactionsExSigned(t *testing.T) {
+	var (
+		partnerKey, _ = crypto.GenerateKey()
+		otherKey, _   = crypto.GenerateKey()
+		key, _        = crypto.GenerateKey()
+		signer        = types.LatestSignerForChainID(common.Big1)
+		txs           types.Transactions
+	)
+	for i := 0; i < 8; i++ {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), common.Address{}, common.Big0, 21000, big.NewInt(1), nil), signer, key)
+		types.Sender(signer, tx)
+		txs = append(txs, tx)
+	}
+
+	// Signed batches and plain ones are told apart
+	packet, err := signTransactionsEx(partnerKey, types.Txs2TxExs(txs))
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	for _, signed := range []bool{true, false} {
+		var v interface{} = packet
+		if !signed {
+			v = packet.Txs
+		}
+		raw, _ := rlp.EncodeToBytes(v)
+		txexs, sig, expiries, err := decodeTransactionsEx(raw)
+		if err != nil {
+			t.Fatalf("signed %v: failed to decode: %v", signed, err)
+		}
+		if (sig != nil) != signed || expiries != nil || len(txexs) != len(txs) {
+			t.Fatalf("signed %v: decoded %d txs, signature %x", signed, len(txexs), sig)
+		}
+		for i, txex := range txexs {
+			if txex.Tx.Hash() != txs[i].Hash() || txex.From != crypto.PubkeyToAddress(key.PublicKey) {
+				t.Fatalf("signed %v: tx %d mismatch", signed, i)
+			}
+		}
+		if signed {
+			if err := verifyTransactionsEx(&partnerKey.PublicKey, signer, txexs, sig, 1); err != nil {
+				t.Fatalf("failed to verify: %v", err)
+			}
+			if err := verifyTransactionsEx(&otherKey.PublicKey, signer, txexs, sig, 1); !errors.Is(err, errBatchSignature) {
+				t.Fatalf("batch of another node accepted: %v", err)
+			}
+		}
+	}
+
+	// Private batches carry the expiry blocks
+	expiries := make([]uint64, len(txs))
+	for i := range expiries {
+		expiries[i] = uint64(100 + i)
+	}
+	raw, _ := rlp.EncodeToBytes(&PrivateTransactionsPacket{Txs: packet.Txs, Sig: packet.Sig, Expiries: expiries})
+	txexs, sig, decoded, err := decodeTransactionsEx(raw)
+	if err != nil {
+		t.Fatalf("failed to decode private batch: %v", err)
+	}
+	if sig == nil || len(txexs) != len(txs) || len(decoded) != len(expiries) || decoded[3] != expiries[3] {
+		t.Fatalf("private batch mismatch: %d txs, expiries %v, signature %x", len(txexs), decoded, sig)
+	}
+	raw, _ = rlp.EncodeToBytes(&PrivateTransactionsPacket{Txs: packet.Txs, Sig: packet.Sig, Expiries: expiries[1:]})
+	if _, _, _, err := decodeTransactionsEx(raw); err == nil {
+		t.Fatalf("private batch with missing expiry blocks decoded")
+	}
+
+	// Forged senders are caught when sampled
+	forged := types.Txs2TxExs(txs)
+	forged[3].From = common.Address{0x1}
+	packet, _ = signTransactionsEx(partnerKey, forged)
+	if err := verifyTransactionsEx(&partnerKey.PublicKey, signer, packet.Txs, packet.Sig, 1); !errors.Is(err, errForgedSender) {
+		t.Fatalf("forged sender not caught: %v", err)
+	}
+	if err := verifyTransactionsEx(&partnerKey.PublicKey, signer, packet.Txs, packet.Sig, 0); err != nil {
+		t.Fatalf("unsampled batch rejected: %v", err)
+	}
+	// and batches altered after signing are rejected
+	forged[3].From = crypto.PubkeyToAddress(key.PublicKey)
+	if err := verifyTransactionsEx(&partnerKey.PublicKey, signer, packet.Txs, packet.Sig, 0); !errors.Is(err, errBatchSignature) {
+		t.Fatalf("altered batch accepted: %v", err)
+	}
+}
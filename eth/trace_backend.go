@@ -0,0 +1,83 @@
+// trace_backend.go
+
+package eth
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// TraceBackend is a tracers.Backend over a local chain, for tracing blocks
+// offline without running the node, e.g. from a read-only database.
+type TraceBackend struct {
+	states *chainStates
+}
+
+// NewTraceBackend creates a tracing backend over the chain and its database.
+func NewTraceBackend(chain *core.BlockChain, chainDb ethdb.Database) *TraceBackend {
+	return &TraceBackend{states: &chainStates{blockchain: chain, chainDb: chainDb}}
+}
+
+func (b *TraceBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.states.blockchain.GetHeaderByHash(hash), nil
+}
+
+func (b *TraceBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return b.states.blockchain.CurrentBlock().Header(), nil
+	}
+	return b.states.blockchain.GetHeaderByNumber(uint64(number)), nil
+}
+
+func (b *TraceBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return b.states.blockchain.GetBlockByHash(hash), nil
+}
+
+func (b *TraceBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return b.states.blockchain.CurrentBlock(), nil
+	}
+	return b.states.blockchain.GetBlockByNumber(uint64(number)), nil
+}
+
+func (b *TraceBackend) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error) {
+	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(b.states.chainDb, txHash)
+	return tx, blockHash, blockNumber, index, nil
+}
+
+func (b *TraceBackend) RPCGasCap() uint64 {
+	return ethconfig.Defaults.RPCGasCap
+}
+
+func (b *TraceBackend) ChainConfig() *params.ChainConfig {
+	return b.states.blockchain.Config()
+}
+
+func (b *TraceBackend) Engine() consensus.Engine {
+	return b.states.blockchain.Engine()
+}
+
+func (b *TraceBackend) ChainDb() ethdb.Database {
+	return b.states.chainDb
+}
+
+func (b *TraceBackend) StateAtBlock(ctx context.Context, block *types.Block, reexec uint64, base *state.StateDB, checkLive, preferDisk bool) (*state.StateDB, error) {
+	return b.states.stateAtBlock(block, reexec, base, checkLive, preferDisk)
+}
+
+func (b *TraceBackend) StateAtTransaction(ctx context.Context, block *types.Block, txIndex int, reexec uint64) (core.Message, vm.BlockContext, *state.StateDB, error) {
+	return b.states.stateAtTransaction(block, txIndex, reexec)
+}
+
+// EOF
@@ -564,12 +564,19 @@ func (api *API) StandardTraceBadBlockToFile(ctx context.Context, hash common.Has
 // executes all the transactions contained within. The return value will be one item
 // per transaction, dependent on the requestd tracer.
 func (api *API) traceBlock(ctx context.Context, block *types.Block, config *TraceConfig) ([]*txTraceResult, error) {
+	results, _, err := api.traceBlockState(ctx, block, config)
+	return results, err
+}
+
+// traceBlockState is like traceBlock, and also returns the state after the
+// transactions of the block, before the block rewards are credited.
+func (api *API) traceBlockState(ctx context.Context, block *types.Block, config *TraceConfig) ([]*txTraceResult, *state.StateDB, error) {
 	if block.NumberU64() == 0 {
-		return nil, errors.New("genesis is not traceable")
+		return nil, nil, errors.New("genesis is not traceable")
 	}
 	parent, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	reexec := defaultTraceReexec
 	if config != nil && config.Reexec != nil {
@@ -577,7 +584,7 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	}
 	statedb, err := api.backend.StateAtBlock(ctx, parent, reexec, nil, true, false)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	// Execute all the transaction contained within the block concurrently
 	var (
@@ -639,9 +646,9 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 
 	// If execution failed in between, abort
 	if failed != nil {
-		return nil, failed
+		return nil, nil, failed
 	}
-	return results, nil
+	return results, statedb, nil
 }
 
 // standardTraceBlockToFile configures a new tracer which uses standard JSON output,
@@ -879,6 +886,7 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 				return nil, err
 			}
 		}
+		txctx.AccessList = message.AccessList()
 		if t, err := New(*config.Tracer, txctx); err != nil {
 			return nil, err
 		} else {
@@ -939,5 +947,11 @@ func APIs(backend Backend) []rpc.API {
 			Service:   NewAPI(backend),
 			Public:    false,
 		},
+		{
+			Namespace: "trace",
+			Version:   "1.0",
+			Service:   NewTraceAPI(backend),
+			Public:    false,
+		},
 	}
 }
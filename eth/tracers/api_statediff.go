@@ -0,0 +1,105 @@
+// api_statediff.go
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var stateDiffTracerName = "stateDiffTracer"
+
+// RewardAccount is the balance of an account credited with block rewards.
+type RewardAccount struct {
+	Balance *hexutil.Big `json:"balance"`
+}
+
+// RewardStateDiff is the balances of the accounts credited with block
+// rewards, before and after the credits.
+type RewardStateDiff struct {
+	Pre  map[common.Address]*RewardAccount `json:"pre"`
+	Post map[common.Address]*RewardAccount `json:"post"`
+}
+
+// BlockStateDiff is the state diff of every transaction in a block, and
+// that of the block rewards credited after them in Finalize.
+type BlockStateDiff struct {
+	Transactions []*txTraceResult `json:"transactions"`
+	Rewards      *RewardStateDiff `json:"rewards"`
+}
+
+// TraceBlockStateDiff returns the pre and post states of the accounts and
+// storage slots modified by each transaction of a block, and the balances
+// of the accounts credited with the block rewards, the Wemix reward shares
+// or the coinbase and uncle rewards, before and after the credits.
+func (api *API) TraceBlockStateDiff(ctx context.Context, number rpc.BlockNumber, config *TraceConfig) (*BlockStateDiff, error) {
+	block, err := api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	diffConfig := &TraceConfig{Tracer: &stateDiffTracerName}
+	if config != nil {
+		diffConfig.Timeout, diffConfig.Reexec = config.Timeout, config.Reexec
+	}
+	results, statedb, err := api.traceBlockState(ctx, block, diffConfig)
+	if err != nil {
+		return nil, err
+	}
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	final, err := api.backend.StateAtBlock(ctx, block, reexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	return &BlockStateDiff{
+		Transactions: results,
+		Rewards:      rewardStateDiff(block, statedb, final),
+	}, nil
+}
+
+// rewardStateDiff compares the balances of the accounts the block rewards
+// may be credited to, after the transactions and after the block.
+func rewardStateDiff(block *types.Block, statedb, final *state.StateDB) *RewardStateDiff {
+	diff := &RewardStateDiff{
+		Pre:  make(map[common.Address]*RewardAccount),
+		Post: make(map[common.Address]*RewardAccount),
+	}
+	for _, addr := range rewardAccounts(block) {
+		pre, post := statedb.GetBalance(addr), final.GetBalance(addr)
+		if pre.Cmp(post) == 0 {
+			continue
+		}
+		diff.Pre[addr] = &RewardAccount{Balance: (*hexutil.Big)(pre)}
+		diff.Post[addr] = &RewardAccount{Balance: (*hexutil.Big)(post)}
+	}
+	return diff
+}
+
+// rewardAccounts returns the accounts the rewards of a block may be credited
+// to: the coinbase, the uncle coinbases and the recipients of the Wemix
+// reward shares recorded in the header.
+func rewardAccounts(block *types.Block) []common.Address {
+	addrs := []common.Address{block.Coinbase()}
+	for _, uncle := range block.Uncles() {
+		addrs = append(addrs, uncle.Coinbase)
+	}
+	var rewards []struct {
+		Addr common.Address `json:"addr"`
+	}
+	if len(block.Header().Rewards) > 0 && json.Unmarshal(block.Header().Rewards, &rewards) == nil {
+		for _, reward := range rewards {
+			addrs = append(addrs, reward.Addr)
+		}
+	}
+	return addrs
+}
+
+// EOF
@@ -0,0 +1,120 @@
+// export.go
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// RangeTracer traces consecutive blocks for bulk export. Unlike traceBlock it
+// regenerates the state once per range, and carries it from block to block.
+type RangeTracer struct {
+	api    *API
+	config *TraceConfig
+}
+
+// NewRangeTracer creates a tracer of block ranges running the given tracer
+// configuration.
+func NewRangeTracer(backend Backend, config *TraceConfig) *RangeTracer {
+	return &RangeTracer{api: NewAPI(backend), config: config}
+}
+
+// TraceRange traces the blocks from start to end inclusive, and calls fn with
+// the json encoded traces of each block in order, those of debug_traceChain.
+// The genesis block, having no transactions, is skipped.
+func (t *RangeTracer) TraceRange(ctx context.Context, start, end uint64, fn func(number uint64, traces []byte) error) error {
+	if start == 0 {
+		start = 1
+	}
+	if start > end {
+		return nil
+	}
+	reexec := defaultTraceReexec
+	if t.config != nil && t.config.Reexec != nil {
+		reexec = *t.config.Reexec
+	}
+	parent, err := t.api.blockByNumber(ctx, rpc.BlockNumber(start-1))
+	if err != nil {
+		return err
+	}
+	// Don't use the live database for tracing to avoid persisting state junks
+	statedb, err := t.api.backend.StateAtBlock(ctx, parent, reexec, nil, false, false)
+	if err != nil {
+		return err
+	}
+	root := parent.Root()
+	for number := start; number <= end; number++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		block, err := t.api.blockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return err
+		}
+		results := t.traceBlockOn(ctx, block, statedb.Copy())
+
+		// Move the state to the block, including the rewards credited in Finalize
+		if statedb, err = t.api.backend.StateAtBlock(ctx, block, reexec, statedb, false, false); err != nil {
+			return err
+		}
+		if have := statedb.IntermediateRoot(t.api.backend.ChainConfig().IsEIP158(block.Number())); have != block.Root() {
+			return fmt.Errorf("regenerated state of block %d mismatches: have %x, want %x", number, have, block.Root())
+		}
+		// The state is referenced by StateAtBlock, release the parent one
+		if trieDb := statedb.Database().TrieDB(); trieDb != nil && root != (common.Hash{}) {
+			trieDb.Dereference(root)
+		}
+		root = block.Root()
+
+		traces, err := json.Marshal(&blockTraceResult{
+			Block:  hexutil.Uint64(number),
+			Hash:   block.Hash(),
+			Traces: results,
+		})
+		if err != nil {
+			return err
+		}
+		if err := fn(number, traces); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// traceBlockOn traces the transactions of a block one after the other on top
+// of the given parent state.
+func (t *RangeTracer) traceBlockOn(ctx context.Context, block *types.Block, statedb *state.StateDB) []*txTraceResult {
+	var (
+		signer   = types.MakeSigner(t.api.backend.ChainConfig(), block.Number())
+		blockCtx = core.NewEVMBlockContext(block.Header(), t.api.chainContext(ctx), nil)
+		eip158   = t.api.backend.ChainConfig().IsEIP158(block.Number())
+		results  = make([]*txTraceResult, len(block.Transactions()))
+	)
+	for i, tx := range block.Transactions() {
+		msg, _ := tx.AsMessage(signer, block.BaseFee())
+		txctx := &Context{
+			BlockHash: block.Hash(),
+			TxIndex:   i,
+			TxHash:    tx.Hash(),
+		}
+		res, err := t.api.traceTx(ctx, msg, txctx, blockCtx, statedb, t.config)
+		if err != nil {
+			results[i] = &txTraceResult{Error: err.Error()}
+		} else {
+			results[i] = &txTraceResult{Result: res}
+		}
+		statedb.Finalise(eip158)
+	}
+	return results
+}
+
+// EOF
@@ -0,0 +1,153 @@
+// gas_profile.go
+
+package tracers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	gasProfilerName       = "gasProfiler"
+	gasProfilerFoldedName = "gasProfilerFolded"
+)
+
+// GasProfileEntry is the gas spent on an opcode by the code of a contract,
+// when called with a function selector.
+type GasProfileEntry struct {
+	Address  common.Address `json:"address"`
+	Selector string         `json:"selector,omitempty"`
+	Op       string         `json:"op"`
+	Gas      uint64         `json:"gas"`
+	Count    uint64         `json:"count"`
+}
+
+// GasProfile is the gas spent by transactions, aggregated by contract,
+// function selector and opcode, and optionally as folded stacks, one
+// "frame;...;frame;OPCODE gas" line per stack, for flamegraph tools.
+//
+// The gas of the entries is spent before refunds: it adds up to the gas used
+// plus the gas refunded.
+type GasProfile struct {
+	GasUsed uint64             `json:"gasUsed"`
+	Refund  uint64             `json:"refund"`
+	Entries []*GasProfileEntry `json:"entries"`
+	Folded  []string           `json:"folded,omitempty"`
+}
+
+// Merge adds the gas of another profile to the profile.
+func (p *GasProfile) Merge(other *GasProfile) {
+	type key struct {
+		addr     common.Address
+		selector string
+		op       string
+	}
+	entries := make(map[key]*GasProfileEntry, len(p.Entries))
+	for _, entry := range p.Entries {
+		entries[key{entry.Address, entry.Selector, entry.Op}] = entry
+	}
+	for _, entry := range other.Entries {
+		if cur, ok := entries[key{entry.Address, entry.Selector, entry.Op}]; ok {
+			cur.Gas += entry.Gas
+			cur.Count += entry.Count
+			continue
+		}
+		cpy := *entry
+		entries[key{entry.Address, entry.Selector, entry.Op}] = &cpy
+		p.Entries = append(p.Entries, &cpy)
+	}
+	if len(other.Folded) > 0 {
+		folded := make(map[string]uint64, len(p.Folded)+len(other.Folded))
+		for _, lines := range [][]string{p.Folded, other.Folded} {
+			for _, line := range lines {
+				if i := strings.LastIndexByte(line, ' '); i > 0 {
+					gas, _ := strconv.ParseUint(line[i+1:], 10, 64)
+					folded[line[:i]] += gas
+				}
+			}
+		}
+		p.Folded = FoldedStacks(folded)
+	}
+	p.GasUsed += other.GasUsed
+	p.Refund += other.Refund
+	p.Sort()
+}
+
+// Sort orders the entries of the profile by decreasing gas.
+func (p *GasProfile) Sort() {
+	sort.Slice(p.Entries, func(i, j int) bool {
+		a, b := p.Entries[i], p.Entries[j]
+		if a.Gas != b.Gas {
+			return a.Gas > b.Gas
+		}
+		if c := bytes.Compare(a.Address[:], b.Address[:]); c != 0 {
+			return c < 0
+		}
+		if a.Selector != b.Selector {
+			return a.Selector < b.Selector
+		}
+		return a.Op < b.Op
+	})
+}
+
+// FoldedStacks formats the gas spent per stack as sorted folded stack lines.
+func FoldedStacks(stacks map[string]uint64) []string {
+	lines := make([]string, 0, len(stacks))
+	for stack, gas := range stacks {
+		if gas > 0 {
+			lines = append(lines, stack+" "+strconv.FormatUint(gas, 10))
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// GasProfileConfig holds the parameters of debug_traceBlockGasProfile.
+type GasProfileConfig struct {
+	Folded  bool
+	Timeout *string
+	Reexec  *uint64
+}
+
+// TraceBlockGasProfile returns the gas spent by the transactions of a block,
+// aggregated by contract, function selector and opcode, and as folded stacks
+// if requested.
+func (api *API) TraceBlockGasProfile(ctx context.Context, number rpc.BlockNumber, config *GasProfileConfig) (*GasProfile, error) {
+	block, err := api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	traceConfig := &TraceConfig{Tracer: &gasProfilerName}
+	if config != nil {
+		if config.Folded {
+			traceConfig.Tracer = &gasProfilerFoldedName
+		}
+		traceConfig.Timeout, traceConfig.Reexec = config.Timeout, config.Reexec
+	}
+	results, err := api.traceBlock(ctx, block, traceConfig)
+	if err != nil {
+		return nil, err
+	}
+	profile := &GasProfile{Entries: []*GasProfileEntry{}}
+	for i, result := range results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("tracing transaction %d failed: %s", i, result.Error)
+		}
+		var txProfile GasProfile
+		if err := json.Unmarshal(result.Result.(json.RawMessage), &txProfile); err != nil {
+			return nil, err
+		}
+		profile.Merge(&txProfile)
+	}
+	return profile, nil
+}
+
+// EOF
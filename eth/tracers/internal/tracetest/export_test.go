@@ -0,0 +1,112 @@
+// export_test.go
+
+package tracetest
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a range tracer traces the blocks of a range in order on the state
+// it carries from block to block, and checks the state root of each block.
+func TestTraceRange(t *testing.T) {
+	defer func(method int) { params.ConsensusMethod = method }(params.ConsensusMethod)
+	params.ConsensusMethod = params.ConsensusPoW
+
+	// Three blocks, block n having n transfers
+	var (
+		key, _  = crypto.GenerateKey()
+		from    = crypto.PubkeyToAddress(key.PublicKey)
+		to      = common.HexToAddress("0x1001")
+		db      = rawdb.NewMemoryDatabase()
+		genesis = (&core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}}}).MustCommit(db)
+		signer  = types.LatestSigner(params.TestChainConfig)
+		nonce   uint64
+	)
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 3, func(i int, b *core.BlockGen) {
+		for j := 0; j <= i; j++ {
+			b.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: nonce, To: &to, Value: big.NewInt(int64(j + 1)), Gas: params.TxGas, GasPrice: b.BaseFee()}))
+			nonce++
+		}
+	})
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	tracer := "callTracer"
+	exporter := tracers.NewRangeTracer(eth.NewTraceBackend(chain, db), &tracers.TraceConfig{Tracer: &tracer})
+
+	// The traces of each block are those of debug_traceChain
+	var numbers []uint64
+	err = exporter.TraceRange(context.Background(), 0, 3, func(number uint64, traces []byte) error {
+		numbers = append(numbers, number)
+
+		var result struct {
+			Block  hexutil.Uint64
+			Hash   common.Hash
+			Traces []struct {
+				Result struct {
+					From  common.Address
+					To    common.Address
+					Value *hexutil.Big
+				}
+				Error string
+			}
+		}
+		if err := json.Unmarshal(traces, &result); err != nil {
+			t.Fatalf("block %d: invalid traces %s: %v", number, traces, err)
+		}
+		block := blocks[number-1]
+		if uint64(result.Block) != number || result.Hash != block.Hash() {
+			t.Errorf("block %d: block mismatch: have %d [%x], want [%x]", number, result.Block, result.Hash, block.Hash())
+		}
+		if len(result.Traces) != len(block.Transactions()) {
+			t.Fatalf("block %d: trace count mismatch: have %d, want %d", number, len(result.Traces), len(block.Transactions()))
+		}
+		for i, trace := range result.Traces {
+			if trace.Error != "" || trace.Result.From != from || trace.Result.To != to || trace.Result.Value.ToInt().Int64() != int64(i+1) {
+				t.Errorf("block %d: trace %d mismatch: have %+v", number, i, trace)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to trace range: %v", err)
+	}
+	if len(numbers) != 3 || numbers[0] != 1 || numbers[2] != 3 {
+		t.Errorf("traced blocks mismatch: have %v, want [1 2 3]", numbers)
+	}
+
+	// A block whose state root doesn't match the regenerated state fails
+	header := blocks[1].Header()
+	header.Root = common.Hash{0x01}
+	forged := types.NewBlockWithHeader(header).WithBody(blocks[1].Transactions(), nil)
+	rawdb.WriteBlock(db, forged)
+	rawdb.WriteCanonicalHash(db, forged.Hash(), forged.NumberU64())
+
+	err = exporter.TraceRange(context.Background(), 2, 2, func(uint64, []byte) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "regenerated state of block 2 mismatches") {
+		t.Errorf("forged state root: have error %v", err)
+	}
+}
+
+// EOF
@@ -0,0 +1,168 @@
+// flat_calltrace_test.go
+
+package tracetest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// flatCallTrace is a result of a flatCallTracer run.
+type flatCallTrace struct {
+	Action struct {
+		CallType      string          `json:"callType"`
+		From          *common.Address `json:"from"`
+		To            *common.Address `json:"to"`
+		Address       *common.Address `json:"address"`
+		RefundAddress *common.Address `json:"refundAddress"`
+		Value         *hexutil.Big    `json:"value"`
+		Balance       *hexutil.Big    `json:"balance"`
+	} `json:"action"`
+	BlockHash   common.Hash `json:"blockHash"`
+	BlockNumber uint64      `json:"blockNumber"`
+	Error       string      `json:"error"`
+	Result      *struct {
+		Address *common.Address `json:"address"`
+		GasUsed hexutil.Uint64  `json:"gasUsed"`
+	} `json:"result"`
+	Subtraces           int         `json:"subtraces"`
+	TraceAddress        []int       `json:"traceAddress"`
+	TransactionHash     common.Hash `json:"transactionHash"`
+	TransactionPosition int         `json:"transactionPosition"`
+	Type                string      `json:"type"`
+}
+
+// flattenCallTrace lists the frames of a call tree depth first, with the
+// paths to them.
+func flattenCallTrace(call *callTrace, address []int, calls []*callTrace, addresses [][]int) ([]*callTrace, [][]int) {
+	calls, addresses = append(calls, call), append(addresses, address)
+	for i := range call.Calls {
+		sub := append(append([]int{}, address...), i)
+		calls, addresses = flattenCallTrace(&call.Calls[i], sub, calls, addresses)
+	}
+	return calls, addresses
+}
+
+// Tests that the flat call tracer lists the same call frames the call tracer
+// nests, in the order and with the paths Parity does.
+func TestFlatCallTracerNative(t *testing.T) {
+	files, err := ioutil.ReadDir(filepath.Join("testdata", "call_tracer"))
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(file.Name(), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				test = new(callTracerTest)
+				tx   = new(types.Transaction)
+			)
+			if blob, err := ioutil.ReadFile(filepath.Join("testdata", "call_tracer", file.Name())); err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			} else if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+				t.Fatalf("failed to parse testcase input: %v", err)
+			}
+			var (
+				signer    = types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+				origin, _ = signer.Sender(tx)
+				txContext = vm.TxContext{
+					Origin:   origin,
+					GasPrice: tx.GasPrice(),
+				}
+				context = vm.BlockContext{
+					CanTransfer: core.CanTransfer,
+					Transfer:    core.Transfer,
+					Coinbase:    test.Context.Miner,
+					BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+					Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+					Difficulty:  (*big.Int)(test.Context.Difficulty),
+					GasLimit:    uint64(test.Context.GasLimit),
+				}
+				_, statedb = tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc, false)
+				txctx      = &tracers.Context{BlockHash: common.Hash{0x1}, TxIndex: 3, TxHash: tx.Hash()}
+			)
+			tracer, err := tracers.New("flatCallTracer", txctx)
+			if err != nil {
+				t.Fatalf("failed to create flat call tracer: %v", err)
+			}
+			evm := vm.NewEVM(context, txContext, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+			msg, err := tx.AsMessage(signer, nil)
+			if err != nil {
+				t.Fatalf("failed to prepare transaction for tracing: %v", err)
+			}
+			st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+			if _, err = st.TransitionDb(); err != nil {
+				t.Fatalf("failed to execute transaction: %v", err)
+			}
+			res, err := tracer.GetResult()
+			if err != nil {
+				t.Fatalf("failed to retrieve trace result: %v", err)
+			}
+			var traces []*flatCallTrace
+			if err := json.Unmarshal(res, &traces); err != nil {
+				t.Fatalf("failed to unmarshal trace result: %v", err)
+			}
+			calls, addresses := flattenCallTrace(test.Result, []int{}, nil, nil)
+			if len(traces) != len(calls) {
+				t.Fatalf("trace count mismatch: have %d, want %d", len(traces), len(calls))
+			}
+			for i, trace := range traces {
+				call := calls[i]
+				if !reflect.DeepEqual(trace.TraceAddress, addresses[i]) || trace.Subtraces != len(call.Calls) {
+					t.Errorf("trace %d: position mismatch: have %v/%d, want %v/%d", i, trace.TraceAddress, trace.Subtraces, addresses[i], len(call.Calls))
+				}
+				if trace.BlockHash != txctx.BlockHash || trace.TransactionHash != txctx.TxHash || trace.TransactionPosition != txctx.TxIndex || trace.BlockNumber != uint64(test.Context.Number) {
+					t.Errorf("trace %d: context mismatch", i)
+				}
+				if (trace.Error != "") != (call.Error != "") {
+					t.Errorf("trace %d: error mismatch: have %q, want %q", i, trace.Error, call.Error)
+				}
+				switch call.Type {
+				case "CREATE", "CREATE2":
+					if trace.Type != "create" || *trace.Action.From != call.From {
+						t.Errorf("trace %d: create mismatch", i)
+					}
+					if call.Error == "" && (trace.Result == nil || *trace.Result.Address != call.To) {
+						t.Errorf("trace %d: created address mismatch", i)
+					}
+				case "SELFDESTRUCT":
+					if trace.Type != "suicide" || *trace.Action.Address != call.From || *trace.Action.RefundAddress != call.To {
+						t.Errorf("trace %d: suicide mismatch", i)
+					}
+				default:
+					if trace.Type != "call" || trace.Action.CallType != strings.ToLower(call.Type) || *trace.Action.From != call.From || *trace.Action.To != call.To {
+						t.Errorf("trace %d: call mismatch: have %s %s", i, trace.Type, trace.Action.CallType)
+					}
+					if call.Error == "" && (trace.Result == nil || call.GasUsed != nil && trace.Result.GasUsed != *call.GasUsed) {
+						t.Errorf("trace %d: result mismatch", i)
+					}
+				}
+			}
+		})
+	}
+}
+
+// EOF
@@ -0,0 +1,266 @@
+// gasprofile_test.go
+
+package tracetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that the gas profiler accounts for all the gas spent by a tx once,
+// the gas forwarded to sub-calls being charged to the callees, and in total
+// for the gas the call tracer reports.
+func TestGasProfilerNative(t *testing.T) {
+	files, err := ioutil.ReadDir(filepath.Join("testdata", "call_tracer"))
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(file.Name(), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			test, res, _ := runCallTracerTest(t, "gasProfilerFolded", file.Name(), new(tracers.Context))
+			var profile tracers.GasProfile
+			if err := json.Unmarshal(res, &profile); err != nil {
+				t.Fatalf("failed to unmarshal trace result: %v", err)
+			}
+			var total, folded uint64
+			for _, entry := range profile.Entries {
+				total += entry.Gas
+				switch entry.Op {
+				case "CALL", "CALLCODE", "DELEGATECALL", "STATICCALL":
+					if entry.Gas > entry.Count*50000 {
+						t.Errorf("%x %s: forwarded gas charged to the caller: %d gas for %d calls", entry.Address, entry.Op, entry.Gas, entry.Count)
+					}
+				case "INTRINSIC":
+					if entry.Gas < 21000 {
+						t.Errorf("%x: intrinsic gas too low: %d", entry.Address, entry.Gas)
+					}
+				}
+			}
+			// The call tracer fixture has the gas used by the tx, bar the
+			// intrinsic gas
+			tx := new(types.Transaction)
+			if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+				t.Fatalf("failed to parse testcase input: %v", err)
+			}
+			var (
+				number       = new(big.Int).SetUint64(uint64(test.Context.Number))
+				intrinsic, _ = core.IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, test.Genesis.Config.IsHomestead(number), test.Genesis.Config.IsIstanbul(number))
+				want         = uint64(*test.Result.GasUsed) + intrinsic
+			)
+			// The fixture gas is spent before refunds
+			if profile.GasUsed+profile.Refund != want {
+				t.Errorf("gas used mismatch: have %d+%d refunded, want %d", profile.GasUsed, profile.Refund, want)
+			}
+			if total != want {
+				t.Errorf("profiled gas mismatch: have %d, want %d", total, want)
+			}
+			for _, line := range profile.Folded {
+				gas, err := strconv.ParseUint(line[strings.LastIndexByte(line, ' ')+1:], 10, 64)
+				if err != nil {
+					t.Fatalf("invalid folded stack line %q: %v", line, err)
+				}
+				folded += gas
+			}
+			if folded != want {
+				t.Errorf("folded gas mismatch: have %d, want %d", folded, want)
+			}
+		})
+	}
+}
+
+// gasProfileBackend is a tracers.Backend serving the blocks and states of a
+// chain from its database.
+type gasProfileBackend struct {
+	chain *core.BlockChain
+	db    ethdb.Database
+}
+
+func (b *gasProfileBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.chain.GetHeaderByHash(hash), nil
+}
+
+func (b *gasProfileBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	return b.chain.GetHeaderByNumber(uint64(number)), nil
+}
+
+func (b *gasProfileBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return b.chain.GetBlockByHash(hash), nil
+}
+
+func (b *gasProfileBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	return b.chain.GetBlockByNumber(uint64(number)), nil
+}
+
+func (b *gasProfileBackend) GetTransaction(ctx context.Context, hash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error) {
+	return nil, common.Hash{}, 0, 0, errors.New("not supported")
+}
+
+func (b *gasProfileBackend) RPCGasCap() uint64                { return 25000000 }
+func (b *gasProfileBackend) ChainConfig() *params.ChainConfig { return b.chain.Config() }
+func (b *gasProfileBackend) Engine() consensus.Engine         { return b.chain.Engine() }
+func (b *gasProfileBackend) ChainDb() ethdb.Database          { return b.db }
+
+func (b *gasProfileBackend) StateAtBlock(ctx context.Context, block *types.Block, reexec uint64, base *state.StateDB, checkLive bool, preferDisk bool) (*state.StateDB, error) {
+	return b.chain.StateAt(block.Root())
+}
+
+func (b *gasProfileBackend) StateAtTransaction(ctx context.Context, block *types.Block, txIndex int, reexec uint64) (core.Message, vm.BlockContext, *state.StateDB, error) {
+	return nil, vm.BlockContext{}, nil, errors.New("not supported")
+}
+
+// Tests that debug_traceBlockGasProfile accounts for the gas used by the
+// block, charging the storage writes of a contract to its function, the
+// access lists to the intrinsic gas, and the refunds apart.
+func TestTraceBlockGasProfile(t *testing.T) {
+	t.Parallel()
+
+	// A contract storing 1 at slot 0, called by one tx next to a transfer,
+	// and a contract clearing slot 0, called by a tx with an access list
+	var (
+		key, _   = crypto.GenerateKey()
+		from     = crypto.PubkeyToAddress(key.PublicKey)
+		miner    = common.HexToAddress("0xc0ffee")
+		contract = common.HexToAddress("0xc0de")
+		clearer  = common.HexToAddress("0xc1ea")
+		slot     = common.Hash{}
+		genesis  = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{
+			from:     {Balance: big.NewInt(params.Ether)},
+			contract: {Code: common.FromHex("0x600160005500"), Balance: new(big.Int)},
+			clearer:  {Code: common.FromHex("0x600060005500"), Balance: new(big.Int), Storage: map[common.Hash]common.Hash{slot: common.BigToHash(common.Big1)}},
+		}}
+		db     = rawdb.NewMemoryDatabase()
+		parent = genesis.MustCommit(db)
+		signer = types.LatestSigner(params.TestChainConfig)
+		header = &types.Header{
+			ParentHash: parent.Hash(),
+			Coinbase:   miner,
+			Number:     big.NewInt(1),
+			GasLimit:   parent.GasLimit(),
+			Time:       parent.Time() + 1,
+			Difficulty: big.NewInt(1),
+			BaseFee:    misc.CalcBaseFee(params.TestChainConfig, parent.Header()),
+		}
+		txs = []*types.Transaction{
+			types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 0, To: &contract, Gas: 100000, GasPrice: header.BaseFee, Data: common.FromHex("0xa9059cbb")}),
+			types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 1, To: &miner, Value: big.NewInt(1000), Gas: params.TxGas, GasPrice: header.BaseFee}),
+			types.MustSignNewTx(key, signer, &types.DynamicFeeTx{ChainID: params.TestChainConfig.ChainID, Nonce: 2, To: &clearer, Gas: 100000, GasFeeCap: header.BaseFee, GasTipCap: common.Big0,
+				AccessList: types.AccessList{{Address: clearer, StorageKeys: []common.Hash{slot}}}}),
+		}
+	)
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// Work out the gas used by the block, and write it without importing
+	statedb, _ := chain.StateAt(parent.Root())
+	gp := new(core.GasPool).AddGas(header.GasLimit)
+	for i, tx := range txs {
+		statedb.Prepare(tx.Hash(), i)
+		if _, err := core.ApplyTransaction(params.TestChainConfig, chain, nil, gp, statedb, header, tx, &header.GasUsed, new(big.Int), vm.Config{}); err != nil {
+			t.Fatalf("tx %d: failed to apply: %v", i, err)
+		}
+	}
+	block := types.NewBlockWithHeader(header).WithBody(txs, nil)
+	rawdb.WriteBlock(db, block)
+	rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+
+	api := tracers.NewAPI(&gasProfileBackend{chain: chain, db: db})
+	profile, err := api.TraceBlockGasProfile(context.Background(), rpc.BlockNumber(1), &tracers.GasProfileConfig{Folded: true})
+	if err != nil {
+		t.Fatalf("failed to profile block: %v", err)
+	}
+	if profile.GasUsed != header.GasUsed {
+		t.Errorf("gas used mismatch: have %d, want %d", profile.GasUsed, header.GasUsed)
+	}
+	if profile.Refund == 0 {
+		t.Errorf("storage clearing refund missing")
+	}
+	var total, intrinsic uint64
+	for _, entry := range profile.Entries {
+		total += entry.Gas
+		if entry.Op == "INTRINSIC" {
+			intrinsic += entry.Gas
+		}
+	}
+	if total != header.GasUsed+profile.Refund {
+		t.Errorf("profiled gas mismatch: have %d, want %d+%d refunded", total, header.GasUsed, profile.Refund)
+	}
+	if want := 3*params.TxGas + 4*params.TxDataNonZeroGasEIP2028 + params.TxAccessListAddressGas + params.TxAccessListStorageKeyGas; intrinsic != want {
+		t.Errorf("intrinsic gas mismatch: have %d, want %d", intrinsic, want)
+	}
+	var sstore *tracers.GasProfileEntry
+	for _, entry := range profile.Entries {
+		if entry.Address == contract && entry.Op == "SSTORE" {
+			sstore = entry
+		}
+	}
+	if sstore == nil || sstore.Selector != "0xa9059cbb" || sstore.Count != 1 || sstore.Gas != params.SstoreSetGasEIP2200+params.ColdSloadCostEIP2929 {
+		t.Errorf("contract SSTORE entry mismatch: have %+v", sstore)
+	}
+	if len(profile.Folded) == 0 {
+		t.Errorf("folded stacks missing")
+	}
+
+	// Unknown blocks can't be profiled
+	if _, err := api.TraceBlockGasProfile(context.Background(), rpc.BlockNumber(2), nil); err == nil {
+		t.Errorf("profiled a missing block")
+	}
+}
+
+// Tests that merging gas profiles sums the gas of the same entries and
+// stacks.
+func TestGasProfileMerge(t *testing.T) {
+	profile := &tracers.GasProfile{
+		GasUsed: 30,
+		Refund:  5,
+		Entries: []*tracers.GasProfileEntry{{Op: "SSTORE", Gas: 20, Count: 1}, {Op: "ADD", Gas: 10, Count: 2}},
+		Folded:  []string{"0x01;SSTORE 20", "0x01;ADD 10"},
+	}
+	profile.Merge(&tracers.GasProfile{
+		GasUsed: 15,
+		Entries: []*tracers.GasProfileEntry{{Op: "ADD", Gas: 15, Count: 3}},
+		Folded:  []string{"0x01;ADD 15"},
+	})
+	if profile.GasUsed != 45 || profile.Refund != 5 {
+		t.Errorf("gas used mismatch: have %d+%d refunded, want %d+%d", profile.GasUsed, profile.Refund, 45, 5)
+	}
+	if len(profile.Entries) != 2 || profile.Entries[0].Op != "ADD" || profile.Entries[0].Gas != 25 || profile.Entries[0].Count != 5 {
+		t.Errorf("merged entries mismatch: have %+v", profile.Entries[0])
+	}
+	if want := []string{"0x01;ADD 25", "0x01;SSTORE 20"}; strings.Join(profile.Folded, ",") != strings.Join(want, ",") {
+		t.Errorf("merged folded stacks mismatch: have %v, want %v", profile.Folded, want)
+	}
+}
+
+// EOF
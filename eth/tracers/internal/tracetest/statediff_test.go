@@ -0,0 +1,162 @@
+// statediff_test.go
+
+package tracetest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// stateDiffAccount is an account in a stateDiffTracer result.
+type stateDiffAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// runCallTracerTest executes the tx of a call tracer test with the given
+// tracer, and returns the test, the trace result and the state after the tx.
+// The tx hash is filled in the tracer context.
+func runCallTracerTest(t *testing.T, tracerName string, filename string, txctx *tracers.Context) (*callTracerTest, json.RawMessage, *state.StateDB) {
+	var (
+		test = new(callTracerTest)
+		tx   = new(types.Transaction)
+	)
+	if blob, err := ioutil.ReadFile(filepath.Join("testdata", "call_tracer", filename)); err != nil {
+		t.Fatalf("failed to read testcase: %v", err)
+	} else if err := json.Unmarshal(blob, test); err != nil {
+		t.Fatalf("failed to parse testcase: %v", err)
+	}
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	var (
+		signer    = types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+		origin, _ = signer.Sender(tx)
+		txContext = vm.TxContext{
+			Origin:   origin,
+			GasPrice: tx.GasPrice(),
+		}
+		context = vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			Coinbase:    test.Context.Miner,
+			BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+			Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+			Difficulty:  (*big.Int)(test.Context.Difficulty),
+			GasLimit:    uint64(test.Context.GasLimit),
+		}
+		_, statedb = tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc, false)
+	)
+	txctx.TxHash = tx.Hash()
+	txctx.AccessList = tx.AccessList()
+	tracer, err := tracers.New(tracerName, txctx)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", tracerName, err)
+	}
+	evm := vm.NewEVM(context, txContext, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+	msg, err := tx.AsMessage(signer, nil)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return test, res, statedb
+}
+
+// Tests that the state diff tracer reports the pre-tx state of the accounts
+// a tx modified, and their post-tx state including the gas refund.
+func TestStateDiffTracerNative(t *testing.T) {
+	files, err := ioutil.ReadDir(filepath.Join("testdata", "call_tracer"))
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(file.Name(), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			test, res, statedb := runCallTracerTest(t, "stateDiffTracer", file.Name(), new(tracers.Context))
+
+			var diff struct {
+				Pre  map[common.Address]*stateDiffAccount `json:"pre"`
+				Post map[common.Address]*stateDiffAccount `json:"post"`
+			}
+			if err := json.Unmarshal(res, &diff); err != nil {
+				t.Fatalf("failed to unmarshal trace result: %v", err)
+			}
+			// The sender always pays for gas and bumps its nonce
+			sender := test.Result.From
+			if diff.Pre[sender] == nil || diff.Post[sender] == nil {
+				t.Fatalf("sender %x missing from diff", sender)
+			}
+			if have, want := diff.Post[sender].Nonce, diff.Pre[sender].Nonce+1; have != want {
+				t.Errorf("sender nonce mismatch: have %d, want %d", have, want)
+			}
+			for addr, pre := range diff.Pre {
+				// Accounts not in genesis are empty before the tx
+				alloc, ok := test.Genesis.Alloc[addr]
+				if !ok {
+					if pre.Balance.ToInt().Sign() != 0 || pre.Nonce != 0 || len(pre.Code) != 0 {
+						t.Errorf("account %x: not in genesis, but not empty in pre state", addr)
+					}
+					continue
+				}
+				if pre.Balance.ToInt().Cmp(alloc.Balance) != 0 {
+					t.Errorf("account %x: pre balance mismatch: have %v, want %v", addr, pre.Balance, alloc.Balance)
+				}
+				for key, val := range pre.Storage {
+					if alloc.Storage[key] != val {
+						t.Errorf("account %x: pre storage %x mismatch: have %x, want %x", addr, key, val, alloc.Storage[key])
+					}
+				}
+			}
+			for addr, post := range diff.Post {
+				if post.Balance != nil && post.Balance.ToInt().Cmp(statedb.GetBalance(addr)) != 0 {
+					t.Errorf("account %x: post balance mismatch: have %v, want %v", addr, post.Balance, statedb.GetBalance(addr))
+				}
+				for key, val := range post.Storage {
+					if have := statedb.GetState(addr, key); have != val {
+						t.Errorf("account %x: post storage %x mismatch: have %x, want %x", addr, key, val, have)
+					}
+				}
+			}
+			// A created contract is in the post state only
+			if test.Result.Type == "CREATE" && test.Result.Error == "" {
+				if _, ok := diff.Pre[test.Result.To]; ok {
+					t.Errorf("created contract %x in pre state", test.Result.To)
+				}
+				if post := diff.Post[test.Result.To]; post == nil || len(post.Code) == 0 {
+					t.Errorf("created contract %x missing code in post state", test.Result.To)
+				}
+			}
+		})
+	}
+}
+
+// EOF
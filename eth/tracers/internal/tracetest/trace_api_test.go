@@ -0,0 +1,105 @@
+// trace_api_test.go
+
+package tracetest
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that trace_filter returns the traces of the calls between the given
+// addresses, paginated with after and count.
+func TestTraceFilter(t *testing.T) {
+	t.Parallel()
+
+	// One block with transfers to three recipients
+	var (
+		key, _     = crypto.GenerateKey()
+		from       = crypto.PubkeyToAddress(key.PublicKey)
+		recipients = []common.Address{common.HexToAddress("0x1001"), common.HexToAddress("0x1002"), common.HexToAddress("0x1003")}
+		genesis    = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{
+			from: {Balance: big.NewInt(params.Ether)},
+		}}
+		db     = rawdb.NewMemoryDatabase()
+		parent = genesis.MustCommit(db)
+		signer = types.LatestSigner(params.TestChainConfig)
+		header = &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(1),
+			GasLimit:   parent.GasLimit(),
+			Time:       parent.Time() + 1,
+			Difficulty: big.NewInt(1),
+			BaseFee:    misc.CalcBaseFee(params.TestChainConfig, parent.Header()),
+		}
+		txs []*types.Transaction
+	)
+	for i, to := range recipients {
+		to := to
+		txs = append(txs, types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: uint64(i), To: &to, Value: big.NewInt(1), Gas: params.TxGas, GasPrice: header.BaseFee}))
+	}
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	block := types.NewBlockWithHeader(header).WithBody(txs, nil)
+	rawdb.WriteBlock(db, block)
+	rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+
+	count := func(n uint64) *uint64 { return &n }
+	tests := []struct {
+		args tracers.TraceFilterArgs
+		want []common.Address // recipients of the traces
+	}{
+		{tracers.TraceFilterArgs{}, recipients},
+		{tracers.TraceFilterArgs{ToAddress: []common.Address{recipients[1]}}, recipients[1:2]},
+		{tracers.TraceFilterArgs{FromAddress: []common.Address{recipients[0]}}, nil},
+		{tracers.TraceFilterArgs{Count: count(2)}, recipients[:2]},
+		{tracers.TraceFilterArgs{After: count(1), Count: count(1)}, recipients[1:2]},
+		{tracers.TraceFilterArgs{After: count(3)}, nil},
+		{tracers.TraceFilterArgs{Count: count(0)}, nil},
+	}
+	api := tracers.NewTraceAPI(&gasProfileBackend{chain: chain, db: db})
+	for i, tt := range tests {
+		first, last := rpc.BlockNumber(0), rpc.BlockNumber(1)
+		tt.args.FromBlock, tt.args.ToBlock = &first, &last
+
+		traces, err := api.Filter(context.Background(), tt.args)
+		if err != nil {
+			t.Fatalf("test %d: failed to filter traces: %v", i, err)
+		}
+		if len(traces) != len(tt.want) {
+			t.Fatalf("test %d: trace count mismatch: have %d, want %d", i, len(traces), len(tt.want))
+		}
+		for j, trace := range traces {
+			var frame struct {
+				Action struct {
+					To common.Address `json:"to"`
+				} `json:"action"`
+			}
+			if err := json.Unmarshal(trace, &frame); err != nil {
+				t.Fatalf("test %d: invalid trace %d: %v", i, j, err)
+			}
+			if frame.Action.To != tt.want[j] {
+				t.Errorf("test %d: trace %d recipient mismatch: have %x, want %x", i, j, frame.Action.To, tt.want[j])
+			}
+		}
+	}
+}
+
+// EOF
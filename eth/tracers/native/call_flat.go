@@ -0,0 +1,187 @@
+// call_flat.go
+
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func init() {
+	register("flatCallTracer", newFlatCallTracer)
+}
+
+// flatCallAction is the action of a Parity style flat call trace.
+type flatCallAction struct {
+	CallType       string `json:"callType,omitempty"`
+	CreationMethod string `json:"creationMethod,omitempty"`
+	From           string `json:"from,omitempty"`
+	To             string `json:"to,omitempty"`
+	Address        string `json:"address,omitempty"`
+	RefundAddress  string `json:"refundAddress,omitempty"`
+	Balance        string `json:"balance,omitempty"`
+	Gas            string `json:"gas,omitempty"`
+	Input          string `json:"input,omitempty"`
+	Init           string `json:"init,omitempty"`
+	Value          string `json:"value,omitempty"`
+}
+
+// flatCallResult is the result of a successful Parity style flat call trace.
+type flatCallResult struct {
+	Address string `json:"address,omitempty"`
+	Code    string `json:"code,omitempty"`
+	GasUsed string `json:"gasUsed"`
+	Output  string `json:"output,omitempty"`
+}
+
+// flatCallFrame is a Parity style flat call trace, one per call frame.
+type flatCallFrame struct {
+	Action              flatCallAction  `json:"action"`
+	BlockHash           *common.Hash    `json:"blockHash"`
+	BlockNumber         uint64          `json:"blockNumber"`
+	Error               string          `json:"error,omitempty"`
+	Result              *flatCallResult `json:"result"`
+	Subtraces           int             `json:"subtraces"`
+	TraceAddress        []int           `json:"traceAddress"`
+	TransactionHash     *common.Hash    `json:"transactionHash"`
+	TransactionPosition *int            `json:"transactionPosition"`
+	Type                string          `json:"type"`
+}
+
+// flatCallTracer is a native go tracer producing the call frames of a tx as
+// a flat list of Parity/OpenEthereum style traces, each with the path to it
+// in the call tree.
+type flatCallTracer struct {
+	*callTracer
+	ctx         *tracers.Context
+	blockNumber uint64
+}
+
+// newFlatCallTracer returns a native go tracer which lists the call frames of
+// a tx the way Parity does, and implements vm.EVMLogger.
+func newFlatCallTracer(ctx *tracers.Context) tracers.Tracer {
+	return &flatCallTracer{
+		callTracer: newCallTracer(ctx).(*callTracer),
+		ctx:        ctx,
+	}
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *flatCallTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.callTracer.CaptureStart(env, from, to, create, input, gas, value)
+	t.blockNumber = env.Context.BlockNumber.Uint64()
+}
+
+// GetResult returns the json-encoded flat list of call traces, and any error
+// arising from the encoding or forceful termination (via `Stop`).
+func (t *flatCallTracer) GetResult() (json.RawMessage, error) {
+	if len(t.callstack) != 1 {
+		return nil, errors.New("incorrect number of top-level calls")
+	}
+	frames := t.flatten(t.callstack[0], []int{}, nil)
+	res, err := json.Marshal(frames)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// flatten appends the traces of a call frame and of its sub-calls, depth
+// first, to the list.
+func (t *flatCallTracer) flatten(call callFrame, address []int, frames []*flatCallFrame) []*flatCallFrame {
+	frame := &flatCallFrame{
+		BlockNumber:  t.blockNumber,
+		Subtraces:    len(call.Calls),
+		TraceAddress: address,
+	}
+	if t.ctx != nil && t.ctx.TxHash != (common.Hash{}) {
+		var (
+			blockHash = t.ctx.BlockHash
+			txHash    = t.ctx.TxHash
+			txIndex   = t.ctx.TxIndex
+		)
+		frame.BlockHash, frame.TransactionHash, frame.TransactionPosition = &blockHash, &txHash, &txIndex
+	}
+	value := call.Value
+	if value == "" {
+		value = "0x0"
+	}
+	switch call.Type {
+	case "CREATE", "CREATE2":
+		frame.Type = "create"
+		frame.Action = flatCallAction{
+			CreationMethod: strings.ToLower(call.Type),
+			From:           call.From,
+			Gas:            call.Gas,
+			Init:           call.Input,
+			Value:          value,
+		}
+		frame.Result = &flatCallResult{Address: call.To, Code: call.Output, GasUsed: call.GasUsed}
+
+	case "SELFDESTRUCT":
+		frame.Type = "suicide"
+		frame.Action = flatCallAction{
+			Address:       call.From,
+			RefundAddress: call.To,
+			Balance:       value,
+		}
+
+	default:
+		frame.Type = "call"
+		frame.Action = flatCallAction{
+			CallType: strings.ToLower(call.Type),
+			From:     call.From,
+			To:       call.To,
+			Gas:      call.Gas,
+			Input:    call.Input,
+			Value:    value,
+		}
+		output := call.Output
+		if output == "" {
+			output = "0x"
+		}
+		frame.Result = &flatCallResult{GasUsed: call.GasUsed, Output: output}
+	}
+	if call.Error != "" {
+		frame.Error = flatCallError(call.Error)
+		frame.Result = nil
+	}
+	frames = append(frames, frame)
+
+	for i, sub := range call.Calls {
+		subAddress := make([]int, len(address)+1)
+		copy(subAddress, address)
+		subAddress[len(address)] = i
+		frames = t.flatten(sub, subAddress, frames)
+	}
+	return frames
+}
+
+// flatCallError converts an EVM error to the form Parity reports it in.
+func flatCallError(err string) string {
+	switch err {
+	case vm.ErrExecutionReverted.Error():
+		return "Reverted"
+	case vm.ErrOutOfGas.Error(), vm.ErrCodeStoreOutOfGas.Error():
+		return "Out of gas"
+	case vm.ErrDepth.Error():
+		return "Out of stack"
+	case vm.ErrWriteProtection.Error():
+		return "Mutable call in static context"
+	}
+	if strings.HasPrefix(err, "invalid opcode") {
+		return "Bad instruction"
+	}
+	if strings.HasPrefix(err, "invalid jump destination") {
+		return "Bad jump destination"
+	}
+	return err
+}
+
+// EOF
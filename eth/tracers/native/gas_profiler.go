@@ -0,0 +1,296 @@
+// gas_profiler.go
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func init() {
+	register("gasProfiler", newGasProfiler)
+	register("gasProfilerFolded", newFoldedGasProfiler)
+}
+
+// Pseudo opcodes of the gas not spent by the opcodes of a call frame
+const (
+	gasOpIntrinsic   = "INTRINSIC"   // intrinsic gas of the tx
+	gasOpPrecompile  = "PRECOMPILE"  // gas used by a precompiled contract
+	gasOpCodeDeposit = "CODEDEPOSIT" // gas of storing the code of a created contract
+	gasOpFault       = "FAULT"       // gas used by a call failing before execution
+)
+
+// gasKey identifies the gas spent on an opcode by the code of a contract,
+// called with a function selector.
+type gasKey struct {
+	addr     common.Address
+	selector string
+	op       string
+}
+
+// gasFrame is a call frame being profiled.
+type gasFrame struct {
+	addr       common.Address
+	selector   string
+	path       string // Folded stack of the frame
+	gas        uint64 // Gas available to the frame
+	create     bool
+	precompile bool
+	lastOp     string // Last opcode executed, not charged yet
+	lastGas    uint64 // Gas left before the last opcode
+	childUsed  uint64 // Gas used by the sub-calls of the last opcode
+}
+
+// gasProfiler is a native go tracer which aggregates the gas spent by a tx
+// by contract, function selector and opcode, and optionally by folded stack.
+//
+// An opcode is charged the gas left before it minus the gas left after it,
+// known at the next opcode of the frame or when the frame exits, minus the
+// gas used by the sub-calls it made. Refunds to the caller of the gas left
+// by sub-calls are thus accounted for.
+type gasProfiler struct {
+	env               *vm.EVM
+	frames            []*gasFrame
+	costs             map[gasKey]*tracers.GasProfileEntry
+	folded            map[string]uint64 // Gas per folded stack, nil if not requested
+	accessList        types.AccessList
+	gasUsed           uint64
+	refund            uint64 // Gas refunded at the end of the tx
+	activePrecompiles []common.Address
+	interrupt         uint32 // Atomic flag to signal execution interruption
+	reason            error  // Textual reason for the interruption
+}
+
+// newGasProfiler returns a native go tracer which profiles the gas spent by
+// a tx, and implements vm.EVMLogger.
+func newGasProfiler(ctx *tracers.Context) tracers.Tracer {
+	return &gasProfiler{
+		costs:      make(map[gasKey]*tracers.GasProfileEntry),
+		accessList: ctx.AccessList,
+	}
+}
+
+// newFoldedGasProfiler returns a gas profiler also reporting the gas spent
+// as folded stacks.
+func newFoldedGasProfiler(ctx *tracers.Context) tracers.Tracer {
+	return &gasProfiler{
+		costs:      make(map[gasKey]*tracers.GasProfileEntry),
+		folded:     make(map[string]uint64),
+		accessList: ctx.AccessList,
+	}
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *gasProfiler) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+
+	rules := env.ChainConfig().Rules(env.Context.BlockNumber, env.Context.Random != nil)
+	t.activePrecompiles = vm.ActivePrecompiles(rules)
+
+	t.push(to, input, gas, create, "")
+
+	// The intrinsic gas is spent before execution
+	isHomestead := env.ChainConfig().IsHomestead(env.Context.BlockNumber)
+	isIstanbul := env.ChainConfig().IsIstanbul(env.Context.BlockNumber)
+	if intrinsic, err := core.IntrinsicGas(input, t.accessList, create, isHomestead, isIstanbul); err == nil {
+		t.charge(t.frames[0], gasOpIntrinsic, intrinsic)
+		t.gasUsed += intrinsic
+	}
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *gasProfiler) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		t.env.Cancel()
+		return
+	}
+	if len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	t.settle(frame, gas)
+	frame.lastOp, frame.lastGas = op.String(), gas
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *gasProfiler) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		t.env.Cancel()
+		return
+	}
+	if len(t.frames) == 0 {
+		return
+	}
+	parent := t.frames[len(t.frames)-1]
+	t.push(to, input, gas, typ == vm.CREATE || typ == vm.CREATE2, parent.path)
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *gasProfiler) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if len(t.frames) < 2 {
+		return
+	}
+	t.pop(output, gasUsed, err)
+	t.frames[len(t.frames)-1].childUsed += gasUsed
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *gasProfiler) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, _ *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *gasProfiler) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
+	if len(t.frames) != 1 {
+		return
+	}
+	t.pop(output, gasUsed, err)
+	t.gasUsed += gasUsed
+
+	// The refund counter is applied once the call is over, capped to a
+	// quotient of the gas used
+	quotient := params.RefundQuotient
+	if t.env.ChainConfig().IsLondon(t.env.Context.BlockNumber) {
+		quotient = params.RefundQuotientEIP3529
+	}
+	t.refund = t.env.StateDB.GetRefund()
+	if limit := t.gasUsed / quotient; t.refund > limit {
+		t.refund = limit
+	}
+}
+
+// GetResult returns the json-encoded gas profile, and any error arising from
+// the encoding or forceful termination (via `Stop`).
+func (t *gasProfiler) GetResult() (json.RawMessage, error) {
+	profile := &tracers.GasProfile{
+		GasUsed: t.gasUsed - t.refund,
+		Refund:  t.refund,
+		Entries: make([]*tracers.GasProfileEntry, 0, len(t.costs)),
+	}
+	for _, entry := range t.costs {
+		if entry.Gas > 0 || entry.Count > 0 {
+			profile.Entries = append(profile.Entries, entry)
+		}
+	}
+	profile.Sort()
+	if t.folded != nil {
+		profile.Folded = tracers.FoldedStacks(t.folded)
+	}
+	res, err := json.Marshal(profile)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *gasProfiler) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// isPrecompiled returns whether the addr is a precompile.
+func (t *gasProfiler) isPrecompiled(addr common.Address) bool {
+	for _, p := range t.activePrecompiles {
+		if p == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// push enters the call frame of the code at addr.
+func (t *gasProfiler) push(addr common.Address, input []byte, gas uint64, create bool, parentPath string) {
+	frame := &gasFrame{
+		addr:       addr,
+		gas:        gas,
+		create:     create,
+		precompile: t.isPrecompiled(addr),
+	}
+	if !create && len(input) >= 4 {
+		frame.selector = bytesToHex(input[:4])
+	}
+	if t.folded != nil {
+		name := addr.Hex()
+		if frame.selector != "" {
+			name += ":" + frame.selector
+		}
+		if parentPath != "" {
+			name = parentPath + ";" + name
+		}
+		frame.path = name
+	}
+	t.frames = append(t.frames, frame)
+}
+
+// settle charges the last opcode of a frame, now that the gas left after it
+// is known.
+func (t *gasProfiler) settle(frame *gasFrame, gasLeft uint64) {
+	if frame.lastOp == "" {
+		return
+	}
+	t.charge(frame, frame.lastOp, subGas(subGas(frame.lastGas, gasLeft), frame.childUsed))
+	frame.lastOp, frame.childUsed = "", 0
+}
+
+// pop leaves the current call frame, charging its last opcode. The gas used
+// without executing opcodes is charged to a pseudo opcode: that of a
+// precompile, or of a call failing before execution. The code deposit of a
+// create is charged separately from the opcode returning the code.
+func (t *gasProfiler) pop(output []byte, gasUsed uint64, err error) {
+	frame := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+
+	gasLeft := subGas(frame.gas, gasUsed)
+	if frame.create && err == nil && len(output) > 0 {
+		deposit := uint64(len(output)) * params.CreateDataGas
+		t.charge(frame, gasOpCodeDeposit, deposit)
+		gasLeft += deposit
+	}
+	if frame.lastOp != "" {
+		t.settle(frame, gasLeft)
+		return
+	}
+	if used := subGas(frame.gas, gasLeft); used > 0 {
+		if frame.precompile {
+			t.charge(frame, gasOpPrecompile, used)
+		} else {
+			t.charge(frame, gasOpFault, used)
+		}
+	}
+}
+
+// charge adds gas spent on an opcode by a call frame.
+func (t *gasProfiler) charge(frame *gasFrame, op string, gas uint64) {
+	key := gasKey{frame.addr, frame.selector, op}
+	entry, ok := t.costs[key]
+	if !ok {
+		entry = &tracers.GasProfileEntry{Address: frame.addr, Selector: frame.selector, Op: op}
+		t.costs[key] = entry
+	}
+	entry.Gas += gas
+	entry.Count++
+
+	if t.folded != nil {
+		t.folded[frame.path+";"+op] += gas
+	}
+}
+
+// subGas returns a - b, or 0 if b exceeds a.
+func subGas(a, b uint64) uint64 {
+	if b > a {
+		return 0
+	}
+	return a - b
+}
+
+// EOF
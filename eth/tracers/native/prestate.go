@@ -32,6 +32,7 @@ import (
 
 func init() {
 	register("prestateTracer", newPrestateTracer)
+	register("stateDiffTracer", newStateDiffTracer)
 }
 
 type prestate = map[common.Address]*account
@@ -42,21 +43,49 @@ type account struct {
 	Storage map[common.Hash]common.Hash `json:"storage"`
 }
 
+// diffAccount is an account in the pre or post state of a state diff, with
+// only the fields that changed set in the post state.
+type diffAccount struct {
+	Balance string                      `json:"balance,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Code    string                      `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// stateDiff is the result of the prestate tracer in diff mode: the accounts
+// modified by a tx, before and after it.
+type stateDiff struct {
+	Pre  map[common.Address]*diffAccount `json:"pre"`
+	Post map[common.Address]*diffAccount `json:"post"`
+}
+
 type prestateTracer struct {
 	env       *vm.EVM
 	prestate  prestate
 	create    bool
 	to        common.Address
-	interrupt uint32 // Atomic flag to signal execution interruption
-	reason    error  // Textual reason for the interruption
+	diffMode  bool                    // Whether to return the pre and post states of modified accounts
+	created   map[common.Address]bool // Accounts created by the tx, in diff mode
+	interrupt uint32                  // Atomic flag to signal execution interruption
+	reason    error                   // Textual reason for the interruption
 }
 
-func newPrestateTracer() tracers.Tracer {
+func newPrestateTracer(ctx *tracers.Context) tracers.Tracer {
 	// First callframe contains tx context info
 	// and is populated on start and end.
 	return &prestateTracer{prestate: prestate{}}
 }
 
+// newStateDiffTracer returns a prestate tracer in diff mode, which returns the
+// pre and post states of the accounts and storage slots a tx modified.
+func newStateDiffTracer(ctx *tracers.Context) tracers.Tracer {
+	return &prestateTracer{
+		prestate: prestate{},
+		diffMode: true,
+		created:  make(map[common.Address]bool),
+	}
+}
+
 // CaptureStart implements the EVMLogger interface to initialize the tracing operation.
 func (t *prestateTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
 	t.env = env
@@ -74,6 +103,11 @@ func (t *prestateTracer) CaptureStart(env *vm.EVM, from common.Address, to commo
 	t.lookupAccount(from)
 	t.lookupAccount(to)
 
+	// The created contract already exists here, it has no pre-state.
+	if t.diffMode && create {
+		t.created[to] = true
+	}
+
 	// The recipient balance includes the value transferred.
 	toBal := hexutil.MustDecodeBig(t.prestate[to].Balance)
 	toBal = new(big.Int).Sub(toBal, value)
@@ -97,7 +131,7 @@ func (t *prestateTracer) CaptureStart(env *vm.EVM, from common.Address, to commo
 
 // CaptureEnd is called after the call finishes to finalize the tracing.
 func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
-	if t.create {
+	if t.create && !t.diffMode {
 		// Exclude created contract.
 		delete(t.prestate, t.to)
 	}
@@ -121,14 +155,14 @@ func (t *prestateTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64,
 	case op == vm.CREATE:
 		addr := scope.Contract.Address()
 		nonce := t.env.StateDB.GetNonce(addr)
-		t.lookupAccount(crypto.CreateAddress(addr, nonce))
+		t.lookupCreated(crypto.CreateAddress(addr, nonce))
 	case stackLen >= 4 && op == vm.CREATE2:
 		offset := stackData[stackLen-2]
 		size := stackData[stackLen-3]
 		init := scope.Memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64()))
 		inithash := crypto.Keccak256(init)
 		salt := stackData[stackLen-4]
-		t.lookupAccount(crypto.CreateAddress2(scope.Contract.Address(), salt.Bytes32(), inithash))
+		t.lookupCreated(crypto.CreateAddress2(scope.Contract.Address(), salt.Bytes32(), inithash))
 	}
 }
 
@@ -148,7 +182,15 @@ func (t *prestateTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
 // GetResult returns the json-encoded nested list of call traces, and any
 // error arising from the encoding or forceful termination (via `Stop`).
 func (t *prestateTracer) GetResult() (json.RawMessage, error) {
-	res, err := json.Marshal(t.prestate)
+	var (
+		res []byte
+		err error
+	)
+	if t.diffMode {
+		res, err = json.Marshal(t.diff())
+	} else {
+		res, err = json.Marshal(t.prestate)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -184,3 +226,84 @@ func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
 	}
 	t.prestate[addr].Storage[key] = t.env.StateDB.GetState(addr, key)
 }
+
+// lookupCreated adds an account about to be created to the prestate, and in
+// diff mode marks it created unless it already exists.
+func (t *prestateTracer) lookupCreated(addr common.Address) {
+	if t.diffMode && !t.env.StateDB.Exist(addr) {
+		if _, ok := t.prestate[addr]; !ok {
+			t.created[addr] = true
+		}
+	}
+	t.lookupAccount(addr)
+}
+
+// diff compares the prestate with the current state, and returns the pre and
+// post states of the modified accounts and storage slots. It relies on the
+// result being retrieved once the tx is applied, gas refund and fee included.
+// Created accounts are only in the post state, destructed ones only in the
+// pre state.
+func (t *prestateTracer) diff() *stateDiff {
+	diff := &stateDiff{
+		Pre:  make(map[common.Address]*diffAccount),
+		Post: make(map[common.Address]*diffAccount),
+	}
+	for addr, prev := range t.prestate {
+		created := t.created[addr]
+		if t.env.StateDB.HasSuicided(addr) {
+			if !created {
+				diff.Pre[addr] = &diffAccount{
+					Balance: prev.Balance,
+					Nonce:   prev.Nonce,
+					Code:    nonEmptyHex(prev.Code),
+					Storage: prev.Storage,
+				}
+			}
+			continue
+		}
+		var (
+			modified bool
+			pre      = &diffAccount{Storage: make(map[common.Hash]common.Hash)}
+			post     = &diffAccount{Storage: make(map[common.Hash]common.Hash)}
+
+			balance = bigToHex(t.env.StateDB.GetBalance(addr))
+			nonce   = t.env.StateDB.GetNonce(addr)
+			code    = bytesToHex(t.env.StateDB.GetCode(addr))
+		)
+		if created || balance != prev.Balance {
+			post.Balance, modified = balance, true
+		}
+		if created || nonce != prev.Nonce {
+			post.Nonce, modified = nonce, true
+		}
+		if (created && code != "0x") || (!created && code != prev.Code) {
+			post.Code, modified = nonEmptyHex(code), true
+		}
+		for key, val := range prev.Storage {
+			cur := t.env.StateDB.GetState(addr, key)
+			if created && cur == (common.Hash{}) {
+				continue
+			}
+			if created || cur != val {
+				pre.Storage[key], post.Storage[key], modified = val, cur, true
+			}
+		}
+		if !modified {
+			continue
+		}
+		diff.Post[addr] = post
+		if !created {
+			pre.Balance, pre.Nonce, pre.Code = prev.Balance, prev.Nonce, nonEmptyHex(prev.Code)
+			diff.Pre[addr] = pre
+		}
+	}
+	return diff
+}
+
+// nonEmptyHex returns the hex encoded bytes, empty if there are none.
+func nonEmptyHex(s string) string {
+	if s == "0x" {
+		return ""
+	}
+	return s
+}
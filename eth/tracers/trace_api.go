@@ -0,0 +1,195 @@
+// trace_api.go
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// maxTraceFilterBlocks is the max number of blocks trace_filter walks in
+	// one request.
+	maxTraceFilterBlocks = 1000
+)
+
+var flatCallTracerName = "flatCallTracer"
+
+// TraceAPI is the collection of Parity/OpenEthereum style trace_ methods,
+// built on the flatCallTracer.
+type TraceAPI struct {
+	api *API
+}
+
+// NewTraceAPI creates a new API definition for the trace_ methods.
+func NewTraceAPI(backend Backend) *TraceAPI {
+	return &TraceAPI{api: NewAPI(backend)}
+}
+
+// flatTraceConfig returns the trace config running the flatCallTracer.
+func flatTraceConfig() *TraceConfig {
+	return &TraceConfig{Tracer: &flatCallTracerName}
+}
+
+// TraceFilterArgs are the criteria of trace_filter. The traces of a call
+// match if the caller is one of FromAddress and the callee is one of
+// ToAddress, an empty list matching any address.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       *uint64          `json:"after"`
+	Count       *uint64          `json:"count"`
+}
+
+// Block returns the flat call traces of all the transactions in a block.
+func (api *TraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]json.RawMessage, error) {
+	block, err := api.api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	results, err := api.api.traceBlock(ctx, block, flatTraceConfig())
+	if err != nil {
+		return nil, err
+	}
+	traces := []json.RawMessage{}
+	for i, result := range results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("tracing transaction %d failed: %s", i, result.Error)
+		}
+		var txTraces []json.RawMessage
+		if err := json.Unmarshal(result.Result.(json.RawMessage), &txTraces); err != nil {
+			return nil, err
+		}
+		traces = append(traces, txTraces...)
+	}
+	return traces, nil
+}
+
+// Transaction returns the flat call traces of a transaction.
+func (api *TraceAPI) Transaction(ctx context.Context, hash common.Hash) (interface{}, error) {
+	return api.api.TraceTransaction(ctx, hash, flatTraceConfig())
+}
+
+// Filter returns the flat call traces of the calls between the given
+// addresses in a range of blocks, at most maxTraceFilterBlocks of them.
+func (api *TraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]json.RawMessage, error) {
+	from, to := rpc.EarliestBlockNumber, rpc.LatestBlockNumber
+	if args.FromBlock != nil {
+		from = *args.FromBlock
+	}
+	if args.ToBlock != nil {
+		to = *args.ToBlock
+	}
+	start, err := api.api.blockByNumber(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := api.api.blockByNumber(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	if start.NumberU64() > end.NumberU64() {
+		return nil, errors.New("fromBlock is after toBlock")
+	}
+	if end.NumberU64()-start.NumberU64() >= maxTraceFilterBlocks {
+		return nil, fmt.Errorf("block range exceeds %d blocks", maxTraceFilterBlocks)
+	}
+	var (
+		froms = addressSet(args.FromAddress)
+		tos   = addressSet(args.ToAddress)
+
+		traces  = []json.RawMessage{}
+		skipped uint64
+	)
+	if args.Count != nil && *args.Count == 0 {
+		return traces, nil
+	}
+	for n := start.NumberU64(); n <= end.NumberU64(); n++ {
+		// The genesis block has no transactions to trace
+		if n == 0 {
+			continue
+		}
+		blockTraces, err := api.Block(ctx, rpc.BlockNumber(n))
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range blockTraces {
+			if match, err := matchFlatTrace(trace, froms, tos); err != nil {
+				return nil, err
+			} else if !match {
+				continue
+			}
+			if args.After != nil && skipped < *args.After {
+				skipped++
+				continue
+			}
+			traces = append(traces, trace)
+			if args.Count != nil && uint64(len(traces)) >= *args.Count {
+				return traces, nil
+			}
+		}
+	}
+	return traces, nil
+}
+
+// addressSet returns the addresses as a set, nil if there are none.
+func addressSet(addrs []common.Address) map[common.Address]struct{} {
+	if len(addrs) == 0 {
+		return nil
+	}
+	set := make(map[common.Address]struct{}, len(addrs))
+	for _, addr := range addrs {
+		set[addr] = struct{}{}
+	}
+	return set
+}
+
+// matchFlatTrace reports whether the caller of a flat call trace is in froms
+// and the callee in tos, a nil set matching any address. The callee of a
+// create is the created contract, and that of a selfdestruct the refund
+// address.
+func matchFlatTrace(trace json.RawMessage, froms, tos map[common.Address]struct{}) (bool, error) {
+	var frame struct {
+		Action struct {
+			From          *common.Address `json:"from"`
+			To            *common.Address `json:"to"`
+			Address       *common.Address `json:"address"`
+			RefundAddress *common.Address `json:"refundAddress"`
+		} `json:"action"`
+		Result *struct {
+			Address *common.Address `json:"address"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(trace, &frame); err != nil {
+		return false, err
+	}
+	from, to := frame.Action.From, frame.Action.To
+	if frame.Action.Address != nil {
+		from, to = frame.Action.Address, frame.Action.RefundAddress
+	} else if to == nil && frame.Result != nil {
+		to = frame.Result.Address
+	}
+	return inAddressSet(froms, from) && inAddressSet(tos, to), nil
+}
+
+// inAddressSet reports whether the address is in the set, a nil set
+// containing any address.
+func inAddressSet(set map[common.Address]struct{}, addr *common.Address) bool {
+	if set == nil {
+		return true
+	}
+	if addr == nil {
+		return false
+	}
+	_, ok := set[*addr]
+	return ok
+}
+
+// EOF
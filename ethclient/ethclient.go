@@ -335,6 +335,14 @@ func (ec *Client) BalanceAt(ctx context.Context, account common.Address, blockNu
 	return (*big.Int)(&result), err
 }
 
+// BalanceAtTime returns the wei balance of the given account as of the latest
+// block with a timestamp at or before the given one.
+func (ec *Client) BalanceAtTime(ctx context.Context, account common.Address, timestamp uint64) (*big.Int, error) {
+	var result hexutil.Big
+	err := ec.c.CallContext(ctx, &result, "eth_getBalance", account, rpc.BlockNumberOrHashWithTimestamp(timestamp))
+	return (*big.Int)(&result), err
+}
+
 // StorageAt returns the value of key in the contract storage of the given account.
 // The block number can be nil, in which case the value is taken from the latest known block.
 func (ec *Client) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
@@ -343,6 +351,14 @@ func (ec *Client) StorageAt(ctx context.Context, account common.Address, key com
 	return result, err
 }
 
+// StorageAtTime returns the value of key in the contract storage of the given
+// account as of the latest block with a timestamp at or before the given one.
+func (ec *Client) StorageAtTime(ctx context.Context, account common.Address, key common.Hash, timestamp uint64) ([]byte, error) {
+	var result hexutil.Bytes
+	err := ec.c.CallContext(ctx, &result, "eth_getStorageAt", account, key, rpc.BlockNumberOrHashWithTimestamp(timestamp))
+	return result, err
+}
+
 // CodeAt returns the contract code of the given account.
 // The block number can be nil, in which case the code is taken from the latest known block.
 func (ec *Client) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
@@ -468,6 +484,17 @@ func (ec *Client) CallContractAtHash(ctx context.Context, msg ethereum.CallMsg,
 	return hex, nil
 }
 
+// CallContractAtTime is almost the same as CallContract except that it selects
+// the latest block with a timestamp at or before the given one.
+func (ec *Client) CallContractAtTime(ctx context.Context, msg ethereum.CallMsg, timestamp uint64) ([]byte, error) {
+	var hex hexutil.Bytes
+	err := ec.c.CallContext(ctx, &hex, "eth_call", toCallArg(msg), rpc.BlockNumberOrHashWithTimestamp(timestamp))
+	if err != nil {
+		return nil, err
+	}
+	return hex, nil
+}
+
 // PendingCallContract executes a message call transaction using the EVM.
 // The state seen by the contract call is the pending state.
 func (ec *Client) PendingCallContract(ctx context.Context, msg ethereum.CallMsg) ([]byte, error) {
@@ -34,6 +34,7 @@ const schema string = `
     schema {
         query: Query
         mutation: Mutation
+        subscription: Subscription
     }
 
     # Account is an Ethereum account at a particular block.
@@ -67,6 +68,8 @@ const schema string = `
         data: Bytes!
         # Transaction is the transaction that generated this log entry.
         transaction: Transaction!
+        # Removed is true if the log was removed by a chain reorganisation.
+        removed: Boolean!
     }
 
     #EIP-2718 
@@ -231,6 +234,46 @@ const schema string = `
         # EstimateGas estimates the amount of gas that will be required for
         # successful execution of a transaction at the current block's state.
         estimateGas(data: CallData!): Long!
+        # Fees is the sum of the transaction fees of this block, in wei.
+        fees: BigInt
+        # Rewards is the list of the block reward shares of this block,
+        # optionally only those credited to the given address.
+        rewards(address: Address): [Reward!]!
+        # MinerNodeId is the id of the governance node that mined this block.
+        minerNodeId: Bytes!
+        # MinerNodeSig is the signature of this block by its miner node.
+        minerNodeSig: Bytes!
+        # MinerNode is the governance node that mined this block. If the node
+        # isn't found in governance, this field will be null.
+        minerNode: MinerNode
+    }
+
+    # Reward is a share of the block rewards credited to an account.
+    type Reward {
+        # Address is the account credited with the reward.
+        address: Address!
+        # Amount is the reward, in wei.
+        amount: BigInt!
+    }
+
+    # BlockReward is a share of the rewards of a block.
+    type BlockReward {
+        # Block is the block crediting the reward.
+        block: Block!
+        # Address is the account credited with the reward.
+        address: Address!
+        # Amount is the reward, in wei.
+        amount: BigInt!
+    }
+
+    # MinerNode is a governance node that mines blocks.
+    type MinerNode {
+        # Id is the node id, the public key of the node.
+        id: Bytes!
+        # Name is the name of the node in governance.
+        name: String!
+        # Enode is the enode URL of the node in governance.
+        enode: String!
     }
 
     # CallData represents the data associated with a local contract call.
@@ -337,10 +380,25 @@ const schema string = `
         syncing: SyncState
         # ChainID returns the current chain ID for transaction replay protection.
         chainID: BigInt!
+        # Rewards returns the block reward shares of the blocks between two
+        # numbers, inclusive, optionally only those credited to the given
+        # address. If to is not supplied, it defaults to the most recent known
+        # block.
+        rewards(from: Long!, to: Long, address: Address): [BlockReward!]!
     }
 
     type Mutation {
         # SendRawTransaction sends an RLP-encoded transaction to the network.
         sendRawTransaction(data: Bytes!): Bytes32!
     }
+
+    type Subscription {
+        # NewBlocks streams the blocks added to the canonical chain.
+        newBlocks: Block!
+        # NewLogs streams the logs of new blocks matching the filter, and those
+        # removed by chain reorganisations.
+        newLogs(filter: BlockFilterCriteria!): Log!
+        # PendingTransactions streams the transactions added to the pool.
+        pendingTransactions: Transaction!
+    }
 `
@@ -22,14 +22,22 @@ import (
 
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
 	"github.com/graph-gophers/graphql-go"
 )
 
 type handler struct {
-	Schema *graphql.Schema
+	Schema   *graphql.Schema
+	upgrader *websocket.Upgrader
+	limiter  *rpc.Limiter // Limits of the websocket subscriptions, nil if unlimited
 }
 
 func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		h.serveWebsocket(w, r)
+		return
+	}
 	var params struct {
 		Query         string                 `json:"query"`
 		OperationName string                 `json:"operationName"`
@@ -55,30 +63,41 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 }
 
-// New constructs a new GraphQL service instance.
-func New(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error {
+// New constructs a new GraphQL service instance. The light mode tells whether
+// the backend is a light client, whose logs are subscribed differently.
+func New(stack *node.Node, backend ethapi.Backend, lightMode bool, cors, vhosts []string) error {
 	if backend == nil {
 		panic("missing backend")
 	}
 	// check if http server with given endpoint exists and enable graphQL on it
-	return newHandler(stack, backend, cors, vhosts)
+	return newHandler(stack, backend, lightMode, cors, vhosts)
 }
 
-// newHandler returns a new `http.Handler` that will answer GraphQL queries.
-// It additionally exports an interactive query browser on the / endpoint.
-func newHandler(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error {
-	q := Resolver{backend}
+// newHandler returns a new `http.Handler` that will answer GraphQL queries,
+// and subscriptions over websocket. It additionally exports an interactive
+// query browser on the / endpoint.
+//
+// The subscriptions are subject to the origins of the websocket RPC interface,
+// and to its API keys and rate limits.
+func newHandler(stack *node.Node, backend ethapi.Backend, lightMode bool, cors, vhosts []string) error {
+	events := &eventSystem{backend: backend, lightMode: lightMode}
+	q := Resolver{backend: backend, events: events}
 
 	s, err := graphql.ParseSchema(schema, &q)
 	if err != nil {
 		return err
 	}
-	h := handler{Schema: s}
+	limiter, err := rpc.NewLimiter(stack.Config().RPCLimits)
+	if err != nil {
+		return err
+	}
+	h := handler{Schema: s, upgrader: newUpgrader(stack.Config().WSOrigins), limiter: limiter}
 	handler := node.NewHTTPHandlerStack(h, cors, vhosts)
 
 	stack.RegisterHandler("GraphQL UI", "/graphql/ui", GraphiQL{})
 	stack.RegisterHandler("GraphQL", "/graphql", handler)
 	stack.RegisterHandler("GraphQL", "/graphql/", handler)
+	stack.RegisterLifecycle(events)
 
 	return nil
 }
@@ -0,0 +1,187 @@
+// subscription.go
+
+package graphql
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// subscriptionBuffer is the number of events buffered for a subscriber. A
+// subscriber falling further behind is dropped, not to block the event system.
+const subscriptionBuffer = 256
+
+var errServiceStopped = errors.New("GraphQL service stopped")
+
+// eventSystem is the filters event system feeding the subscriptions, created
+// on the first subscription and stopped with the node.
+type eventSystem struct {
+	backend   ethapi.Backend
+	lightMode bool
+
+	mu      sync.Mutex
+	events  *filters.EventSystem
+	stopped bool
+}
+
+// get returns the filters event system, creating it if needed.
+func (s *eventSystem) get() (*filters.EventSystem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return nil, errServiceStopped
+	}
+	if s.events == nil {
+		s.events = filters.NewEventSystem(s.backend, s.lightMode)
+	}
+	return s.events, nil
+}
+
+// Start implements node.Lifecycle.
+func (s *eventSystem) Start() error {
+	return nil
+}
+
+// Stop implements node.Lifecycle, stopping the filters event system and thus
+// ending the subscriptions.
+func (s *eventSystem) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.events != nil {
+		s.events.Stop()
+	}
+	s.stopped = true
+	return nil
+}
+
+// NewBlocks streams the blocks added to the canonical chain.
+func (r *Resolver) NewBlocks(ctx context.Context) (<-chan *Block, error) {
+	events, err := r.events.get()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		headers = make(chan *types.Header)
+		sub     = events.SubscribeNewHeads(headers)
+		blocks  = make(chan *Block, subscriptionBuffer)
+	)
+	go func() {
+		defer close(blocks)
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case header := <-headers:
+				numberOrHash := rpc.BlockNumberOrHashWithHash(header.Hash(), true)
+				block := &Block{
+					backend:      r.backend,
+					numberOrHash: &numberOrHash,
+					hash:         header.Hash(),
+					header:       header,
+				}
+				select {
+				case blocks <- block:
+				default:
+					return
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return blocks, nil
+}
+
+// NewLogs streams the logs of the new canonical blocks matching a filter, and
+// those removed by reorgs, flagged as removed.
+func (r *Resolver) NewLogs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) (<-chan *Log, error) {
+	events, err := r.events.get()
+	if err != nil {
+		return nil, err
+	}
+	var crit ethereum.FilterQuery
+	if args.Filter.Addresses != nil {
+		crit.Addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		crit.Topics = *args.Filter.Topics
+	}
+	matches := make(chan []*types.Log)
+	sub, err := events.SubscribeLogs(crit, matches)
+	if err != nil {
+		return nil, err
+	}
+	logs := make(chan *Log, subscriptionBuffer)
+	go func() {
+		defer close(logs)
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case batch := <-matches:
+				for _, log := range batch {
+					select {
+					case logs <- &Log{
+						backend:     r.backend,
+						transaction: &Transaction{backend: r.backend, hash: log.TxHash},
+						log:         log,
+					}:
+					default:
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return logs, nil
+}
+
+// PendingTransactions streams the transactions added to the transaction pool.
+func (r *Resolver) PendingTransactions(ctx context.Context) (<-chan *Transaction, error) {
+	events, err := r.events.get()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		hashes = make(chan []common.Hash)
+		sub    = events.SubscribePendingTxs(hashes)
+		txs    = make(chan *Transaction, subscriptionBuffer)
+	)
+	go func() {
+		defer close(txs)
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case batch := <-hashes:
+				for _, hash := range batch {
+					select {
+					case txs <- &Transaction{backend: r.backend, hash: hash}:
+					default:
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return txs, nil
+}
+
+// EOF
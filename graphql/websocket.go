@@ -0,0 +1,328 @@
+// websocket.go
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	qerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+const (
+	// Subprotocols of the GraphQL over websocket transports: the graphql-ws
+	// protocol and the legacy one of subscriptions-transport-ws.
+	wsProtocol       = "graphql-transport-ws"
+	wsLegacyProtocol = "graphql-ws"
+
+	wsInitTimeout      = 10 * time.Second
+	wsWriteTimeout     = 10 * time.Second
+	wsReadLimit        = 1024 * 1024
+	wsMaxSubscriptions = 100
+
+	// wsLimitMethod is the method name the operations started over websocket
+	// are rate limited as, by the limits of the method, the "graphql"
+	// namespace or any method.
+	wsLimitMethod = "graphql_subscribe"
+
+	// Close codes of the graphql-ws protocol.
+	wsCloseInvalidMessage = 4400
+	wsCloseUnauthorized   = 4401
+	wsCloseBadSubprotocol = 4406
+	wsCloseInitTimeout    = 4408
+	wsCloseDuplicateID    = 4409
+	wsCloseDuplicateInit  = 4429
+)
+
+// wsMessage is a message of the GraphQL over websocket transports.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsParams are the parameters of an operation started over websocket.
+type wsParams struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// newUpgrader creates the websocket upgrader of the GraphQL endpoint, accepting
+// the requests from the given origins as the websocket RPC interface does.
+func newUpgrader(origins []string) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		Subprotocols:    []string{wsProtocol, wsLegacyProtocol},
+		CheckOrigin:     rpc.WebsocketOriginValidator(origins),
+	}
+}
+
+// wsConn is a GraphQL over websocket connection.
+type wsConn struct {
+	conn    *websocket.Conn
+	schema  *graphql.Schema
+	legacy  bool // Whether the connection uses the legacy protocol
+	limiter *rpc.Limiter
+	peer    rpc.PeerInfo // Client of the connection, for the rate limits
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	subs    map[string]context.CancelFunc // Running operations by id
+	wg      sync.WaitGroup
+}
+
+// serveWebsocket upgrades a request to a websocket connection and serves the
+// operations sent over it until it is closed.
+func (h handler) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	apiKey, err := h.limiter.Authorize(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug("GraphQL websocket upgrade failed", "err", err)
+		return
+	}
+	c := &wsConn{
+		conn:    conn,
+		schema:  h.Schema,
+		legacy:  conn.Subprotocol() == wsLegacyProtocol,
+		limiter: h.limiter,
+		peer:    rpc.PeerInfo{Transport: "ws", RemoteAddr: r.RemoteAddr},
+		subs:    make(map[string]context.CancelFunc),
+	}
+	c.peer.HTTP.Host = r.Host
+	c.peer.HTTP.Origin = r.Header.Get("Origin")
+	c.peer.HTTP.UserAgent = r.Header.Get("User-Agent")
+	c.peer.HTTP.APIKey = apiKey
+
+	if conn.Subprotocol() == "" {
+		c.close(wsCloseBadSubprotocol, "Subprotocol not acceptable")
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		c.wg.Wait()
+		conn.Close()
+	}()
+	c.serve(ctx)
+}
+
+// serve reads and handles the messages of the connection until it fails or a
+// protocol violation closes it.
+func (c *wsConn) serve(ctx context.Context) {
+	c.conn.SetReadLimit(wsReadLimit)
+	c.conn.SetReadDeadline(time.Now().Add(wsInitTimeout))
+
+	acked := false
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if netErr, ok := err.(interface{ Timeout() bool }); ok && netErr.Timeout() && !acked {
+				c.close(wsCloseInitTimeout, "Connection initialisation timeout")
+			}
+			return
+		}
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.close(wsCloseInvalidMessage, "Invalid message received")
+			return
+		}
+		switch msg.Type {
+		case "connection_init":
+			if acked {
+				if c.legacy {
+					continue
+				}
+				c.close(wsCloseDuplicateInit, "Too many initialisation requests")
+				return
+			}
+			acked = true
+			c.conn.SetReadDeadline(time.Time{})
+			c.write(&wsMessage{Type: "connection_ack"})
+
+		case "ping":
+			c.write(&wsMessage{Type: "pong", Payload: msg.Payload})
+
+		case "pong":
+
+		case "subscribe", "start":
+			if (msg.Type == "start") != c.legacy || msg.ID == "" {
+				c.close(wsCloseInvalidMessage, "Invalid message received")
+				return
+			}
+			if !acked {
+				c.close(wsCloseUnauthorized, "Unauthorized")
+				return
+			}
+			var params wsParams
+			if err := json.Unmarshal(msg.Payload, &params); err != nil {
+				c.close(wsCloseInvalidMessage, "Invalid message received")
+				return
+			}
+			if !c.start(ctx, msg.ID, &params) {
+				return
+			}
+
+		case "complete", "stop":
+			c.stop(msg.ID)
+
+		case "connection_terminate":
+			c.close(websocket.CloseNormalClosure, "")
+			return
+
+		default:
+			c.close(wsCloseInvalidMessage, "Invalid message received")
+			return
+		}
+	}
+}
+
+// start runs an operation, streaming its results to the client. It returns
+// false if the connection was closed for reusing the id of a running operation.
+func (c *wsConn) start(ctx context.Context, id string, params *wsParams) bool {
+	c.mu.Lock()
+	if _, ok := c.subs[id]; ok {
+		c.mu.Unlock()
+		if c.legacy {
+			c.writeErrors(id, qerrors.Errorf("operation %s already exists", id))
+			return true
+		}
+		c.close(wsCloseDuplicateID, "Subscriber for "+id+" already exists")
+		return false
+	}
+	if len(c.subs) >= wsMaxSubscriptions {
+		c.mu.Unlock()
+		c.writeErrors(id, qerrors.Errorf("too many operations, max %d", wsMaxSubscriptions))
+		return true
+	}
+	if err := c.limiter.Allow(c.peer, wsLimitMethod); err != nil {
+		c.mu.Unlock()
+		c.writeErrors(id, qerrors.Errorf("%s", err))
+		return true
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.subs[id] = cancel
+	c.mu.Unlock()
+
+	responses, err := c.schema.Subscribe(ctx, params.Query, params.OperationName, params.Variables)
+	if err != nil {
+		c.remove(id)
+		c.writeErrors(id, qerrors.Errorf("%s", err))
+		return true
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		first := true
+		for resp := range responses {
+			if ctx.Err() != nil {
+				continue // drain the responses until the channel closes
+			}
+			response := resp.(*graphql.Response)
+			if first && response.Data == nil && len(response.Errors) > 0 {
+				// The operation failed before running, report it as an
+				// error not followed by a complete message
+				if c.remove(id) {
+					c.writeErrors(id, response.Errors...)
+				}
+				cancel()
+				continue
+			}
+			first = false
+			payload, err := json.Marshal(response)
+			if err != nil {
+				log.Warn("Failed to encode GraphQL response", "err", err)
+				continue
+			}
+			typ := "next"
+			if c.legacy {
+				typ = "data"
+			}
+			c.write(&wsMessage{ID: id, Type: typ, Payload: payload})
+		}
+		if c.remove(id) {
+			c.write(&wsMessage{ID: id, Type: "complete"})
+		}
+		cancel()
+	}()
+	return true
+}
+
+// stop cancels an operation at the request of the client.
+func (c *wsConn) stop(id string) {
+	c.mu.Lock()
+	cancel, ok := c.subs[id]
+	delete(c.subs, id)
+	c.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
+
+// remove removes a finished operation, returning false if it was already
+// removed by the client.
+func (c *wsConn) remove(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.subs[id]
+	delete(c.subs, id)
+	return ok
+}
+
+// writeErrors sends the errors failing an operation.
+func (c *wsConn) writeErrors(id string, errs ...*qerrors.QueryError) {
+	var (
+		payload []byte
+		err     error
+	)
+	if c.legacy {
+		// The legacy protocol sends a single error object
+		payload, err = json.Marshal(errs[0])
+	} else {
+		payload, err = json.Marshal(errs)
+	}
+	if err != nil {
+		log.Warn("Failed to encode GraphQL errors", "err", err)
+		return
+	}
+	c.write(&wsMessage{ID: id, Type: "error", Payload: payload})
+}
+
+// write sends a message to the client.
+func (c *wsConn) write(msg *wsMessage) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		log.Debug("Failed to write GraphQL websocket message", "err", err)
+		c.conn.Close()
+	}
+}
+
+// close closes the connection with the given close code and reason.
+func (c *wsConn) close(code int, reason string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	deadline := time.Now().Add(wsWriteTimeout)
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+	c.conn.Close()
+}
+
+// EOF
@@ -0,0 +1,252 @@
+// websocket_test.go
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+const wsTestSchema = `
+    schema {
+        query: Query
+        subscription: Subscription
+    }
+    type Query {
+        answer: Int!
+    }
+    type Subscription {
+        ticks(count: Int!): Int!
+    }
+`
+
+type wsTestResolver struct{}
+
+func (r *wsTestResolver) Answer() int32 { return 42 }
+
+func (r *wsTestResolver) Ticks(ctx context.Context, args struct{ Count int32 }) <-chan int32 {
+	ticks := make(chan int32)
+	go func() {
+		defer close(ticks)
+		for i := int32(0); i < args.Count; i++ {
+			select {
+			case ticks <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ticks
+}
+
+func newWSTestServer(t *testing.T) *httptest.Server {
+	s, err := graphql.ParseSchema(wsTestSchema, new(wsTestResolver))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(handler{Schema: s, upgrader: newUpgrader(nil)})
+}
+
+func dialWSTest(t *testing.T, url, protocol string) *websocket.Conn {
+	dialer := websocket.Dialer{Subprotocols: []string{protocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(url, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func readWSTest(t *testing.T, conn *websocket.Conn) *wsMessage {
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	return &msg
+}
+
+func TestWebsocketSubscription(t *testing.T) {
+	for _, protocol := range []string{wsProtocol, wsLegacyProtocol} {
+		ts := newWSTestServer(t)
+		conn := dialWSTest(t, ts.URL, protocol)
+
+		start, next := "subscribe", "next"
+		if protocol == wsLegacyProtocol {
+			start, next = "start", "data"
+		}
+		conn.WriteJSON(&wsMessage{Type: "connection_init"})
+		if msg := readWSTest(t, conn); msg.Type != "connection_ack" {
+			t.Fatalf("%s: expected connection_ack, got %s", protocol, msg.Type)
+		}
+		conn.WriteJSON(&wsMessage{ID: "1", Type: start, Payload: json.RawMessage(`{"query":"subscription { ticks(count: 3) }"}`)})
+		for i := 0; i < 3; i++ {
+			msg := readWSTest(t, conn)
+			if msg.Type != next || msg.ID != "1" {
+				t.Fatalf("%s: expected %s message, got %s %s", protocol, next, msg.Type, msg.Payload)
+			}
+			var result struct{ Data struct{ Ticks int } }
+			if err := json.Unmarshal(msg.Payload, &result); err != nil {
+				t.Fatal(err)
+			}
+			if result.Data.Ticks != i {
+				t.Errorf("%s: tick %d: got %d", protocol, i, result.Data.Ticks)
+			}
+		}
+		if msg := readWSTest(t, conn); msg.Type != "complete" || msg.ID != "1" {
+			t.Fatalf("%s: expected complete, got %s", protocol, msg.Type)
+		}
+		// Invalid operations fail with an error message
+		conn.WriteJSON(&wsMessage{ID: "2", Type: start, Payload: json.RawMessage(`{"query":"subscription { unknown }"}`)})
+		if msg := readWSTest(t, conn); msg.Type != "error" || msg.ID != "2" {
+			t.Fatalf("%s: expected error, got %s", protocol, msg.Type)
+		}
+		conn.Close()
+		ts.Close()
+	}
+}
+
+func TestWebsocketQuery(t *testing.T) {
+	ts := newWSTestServer(t)
+	defer ts.Close()
+	conn := dialWSTest(t, ts.URL, wsProtocol)
+	defer conn.Close()
+
+	conn.WriteJSON(&wsMessage{Type: "connection_init"})
+	readWSTest(t, conn)
+	conn.WriteJSON(&wsMessage{ID: "1", Type: "subscribe", Payload: json.RawMessage(`{"query":"{ answer }"}`)})
+	if msg := readWSTest(t, conn); msg.Type != "next" || string(msg.Payload) != `{"data":{"answer":42}}` {
+		t.Fatalf("unexpected response %s %s", msg.Type, msg.Payload)
+	}
+	if msg := readWSTest(t, conn); msg.Type != "complete" {
+		t.Fatalf("expected complete, got %s", msg.Type)
+	}
+}
+
+func TestWebsocketProtocolErrors(t *testing.T) {
+	ts := newWSTestServer(t)
+	defer ts.Close()
+
+	// Subscribing before the connection is initialised is unauthorized
+	conn := dialWSTest(t, ts.URL, wsProtocol)
+	conn.WriteJSON(&wsMessage{ID: "1", Type: "subscribe", Payload: json.RawMessage(`{"query":"{ answer }"}`)})
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, wsCloseUnauthorized) {
+		t.Errorf("expected close %d, got %v", wsCloseUnauthorized, err)
+	}
+	conn.Close()
+
+	// Reusing the id of a running subscription closes the connection
+	conn = dialWSTest(t, ts.URL, wsProtocol)
+	conn.WriteJSON(&wsMessage{Type: "connection_init"})
+	readWSTest(t, conn)
+	payload := json.RawMessage(`{"query":"subscription { ticks(count: 1000000) }"}`)
+	conn.WriteJSON(&wsMessage{ID: "1", Type: "subscribe", Payload: payload})
+	conn.WriteJSON(&wsMessage{ID: "1", Type: "subscribe", Payload: payload})
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		if !websocket.IsCloseError(err, wsCloseDuplicateID) {
+			t.Errorf("expected close %d, got %v", wsCloseDuplicateID, err)
+		}
+		break
+	}
+	conn.Close()
+
+	// Connections without subprotocol are refused
+	conn = dialWSTest(t, ts.URL, "")
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, wsCloseBadSubprotocol) {
+		t.Errorf("expected close %d, got %v", wsCloseBadSubprotocol, err)
+	}
+	conn.Close()
+}
+
+// Tests that the websocket connections are subject to the origins, API keys
+// and rate limits of the websocket RPC interface.
+func TestWebsocketLimits(t *testing.T) {
+	schema, err := graphql.ParseSchema(wsTestSchema, new(wsTestResolver))
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter, err := rpc.NewLimiter(&rpc.LimitConfig{
+		APIKeys:       map[string]string{"secret": "client"},
+		RequireAPIKey: true,
+		KeyLimits:     map[string]rpc.RateLimit{"graphql": {Rate: 0.001, Burst: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(handler{Schema: schema, upgrader: newUpgrader([]string{"http://allowed"}), limiter: limiter})
+	defer ts.Close()
+
+	dial := func(origin, key string) (*websocket.Conn, int) {
+		header := make(http.Header)
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+		if key != "" {
+			header.Set(rpc.APIKeyHeader, key)
+		}
+		dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+		conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), header)
+		if err != nil {
+			if resp == nil {
+				t.Fatal(err)
+			}
+			return nil, resp.StatusCode
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		return conn, http.StatusSwitchingProtocols
+	}
+	if _, code := dial("", ""); code != http.StatusUnauthorized {
+		t.Errorf("without API key: have status %d, want %d", code, http.StatusUnauthorized)
+	}
+	if _, code := dial("", "wrong"); code != http.StatusUnauthorized {
+		t.Errorf("with invalid API key: have status %d, want %d", code, http.StatusUnauthorized)
+	}
+	if _, code := dial("http://denied", "secret"); code != http.StatusForbidden {
+		t.Errorf("from denied origin: have status %d, want %d", code, http.StatusForbidden)
+	}
+	conn, code := dial("http://allowed", "secret")
+	if conn == nil {
+		t.Fatalf("from allowed origin with API key: have status %d", code)
+	}
+	defer conn.Close()
+
+	// The second operation exceeds the rate limit of the "graphql" namespace
+	conn.WriteJSON(&wsMessage{Type: "connection_init"})
+	readWSTest(t, conn)
+	conn.WriteJSON(&wsMessage{ID: "1", Type: "subscribe", Payload: json.RawMessage(`{"query":"{ answer }"}`)})
+	if msg := readWSTest(t, conn); msg.Type != "next" || msg.ID != "1" {
+		t.Fatalf("expected next message, got %s %s", msg.Type, msg.Payload)
+	}
+	if msg := readWSTest(t, conn); msg.Type != "complete" || msg.ID != "1" {
+		t.Fatalf("expected complete, got %s %s", msg.Type, msg.Payload)
+	}
+	conn.WriteJSON(&wsMessage{ID: "2", Type: "subscribe", Payload: json.RawMessage(`{"query":"{ answer }"}`)})
+	if msg := readWSTest(t, conn); msg.Type != "error" || msg.ID != "2" || !strings.Contains(string(msg.Payload), "rate limit exceeded") {
+		t.Fatalf("expected rate limit error, got %s %s", msg.Type, msg.Payload)
+	}
+}
+
+// Tests that no subscription can be made once the event system is stopped.
+func TestEventSystemStopped(t *testing.T) {
+	events := new(eventSystem)
+	if err := events.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&Resolver{events: events}).NewBlocks(context.Background()); err != errServiceStopped {
+		t.Errorf("subscription error mismatch: have %v, want %v", err, errServiceStopped)
+	}
+}
+
+// EOF
@@ -0,0 +1,186 @@
+// wemix.go
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	wemixminer "github.com/ethereum/go-ethereum/wemix/miner"
+)
+
+// maxRewardBlocks is the max number of blocks a rewards query scans.
+const maxRewardBlocks = 10000
+
+// Reward is a share of the block rewards credited to an account.
+type Reward struct {
+	address common.Address
+	amount  *big.Int
+}
+
+func (r *Reward) Address(ctx context.Context) common.Address {
+	return r.address
+}
+
+func (r *Reward) Amount(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*r.amount)
+}
+
+// BlockReward is a share of the rewards of a block.
+type BlockReward struct {
+	Reward
+	block *Block
+}
+
+func (r *BlockReward) Block(ctx context.Context) *Block {
+	return r.block
+}
+
+// MinerNode is the governance node that mined a block.
+type MinerNode struct {
+	id    []byte
+	name  string
+	enode string
+}
+
+func (n *MinerNode) Id(ctx context.Context) hexutil.Bytes {
+	return n.id
+}
+
+func (n *MinerNode) Name(ctx context.Context) string {
+	return n.name
+}
+
+func (n *MinerNode) Enode(ctx context.Context) string {
+	return n.enode
+}
+
+// decodeRewards decodes the rewards of a header, a JSON list of address and
+// amount pairs, keeping those of the given address if any.
+func decodeRewards(header *types.Header, address *common.Address) ([]*Reward, error) {
+	if len(header.Rewards) == 0 {
+		return []*Reward{}, nil
+	}
+	var entries []struct {
+		Addr   common.Address `json:"addr"`
+		Reward *big.Int       `json:"reward"`
+	}
+	if err := json.Unmarshal(header.Rewards, &entries); err != nil {
+		return nil, fmt.Errorf("invalid rewards in block %d: %v", header.Number, err)
+	}
+	rewards := make([]*Reward, 0, len(entries))
+	for _, entry := range entries {
+		if address != nil && entry.Addr != *address {
+			continue
+		}
+		amount := entry.Reward
+		if amount == nil {
+			amount = new(big.Int)
+		}
+		rewards = append(rewards, &Reward{address: entry.Addr, amount: amount})
+	}
+	return rewards, nil
+}
+
+func (b *Block) Fees(ctx context.Context) (*hexutil.Big, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if header.Fees == nil {
+		return nil, nil
+	}
+	return (*hexutil.Big)(header.Fees), nil
+}
+
+func (b *Block) Rewards(ctx context.Context, args struct{ Address *common.Address }) ([]*Reward, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return decodeRewards(header, args.Address)
+}
+
+func (b *Block) MinerNodeId(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return header.MinerNodeId, nil
+}
+
+func (b *Block) MinerNodeSig(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return header.MinerNodeSig, nil
+}
+
+func (b *Block) MinerNode(ctx context.Context) (*MinerNode, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if header.Number.Sign() == 0 {
+		return nil, nil
+	}
+	id, name, enode, err := wemixminer.GetMinerNode(header.Number, header.Coinbase, header.MinerNodeId)
+	if err == wemixminer.ErrNotInitialized || (err == nil && id == nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &MinerNode{id: id, name: name, enode: enode}, nil
+}
+
+func (r *Resolver) Rewards(ctx context.Context, args struct {
+	From    Long
+	To      *Long
+	Address *common.Address
+}) ([]*BlockReward, error) {
+	from := rpc.BlockNumber(args.From)
+	to := rpc.BlockNumber(r.backend.CurrentBlock().Number().Int64())
+	if args.To != nil && rpc.BlockNumber(*args.To) < to {
+		to = rpc.BlockNumber(*args.To)
+	}
+	if from < 0 || to < from {
+		return []*BlockReward{}, nil
+	}
+	if to-from >= maxRewardBlocks {
+		return nil, fmt.Errorf("block range too large: %d blocks, max %d", to-from+1, maxRewardBlocks)
+	}
+	ret := []*BlockReward{}
+	for i := from; i <= to; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		numberOrHash := rpc.BlockNumberOrHashWithNumber(i)
+		block := &Block{
+			backend:      r.backend,
+			numberOrHash: &numberOrHash,
+		}
+		header, err := block.resolveHeader(ctx)
+		if err != nil {
+			return nil, err
+		} else if header == nil {
+			break
+		}
+		rewards, err := decodeRewards(header, args.Address)
+		if err != nil {
+			return nil, err
+		}
+		for _, reward := range rewards {
+			ret = append(ret, &BlockReward{Reward: *reward, block: block})
+		}
+	}
+	return ret, nil
+}
+
+// EOF
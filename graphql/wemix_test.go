@@ -0,0 +1,48 @@
+// wemix_test.go
+
+package graphql
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// Tests that the schema matches the resolvers, including the Wemix ones.
+func TestGraphQLSchema(t *testing.T) {
+	if _, err := graphql.ParseSchema(schema, new(Resolver)); err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+}
+
+func TestDecodeRewards(t *testing.T) {
+	var (
+		a      = common.HexToAddress("0x01")
+		b      = common.HexToAddress("0x02")
+		header = &types.Header{
+			Number:  big.NewInt(1),
+			Rewards: []byte(`[{"addr":"0x0000000000000000000000000000000000000001","reward":100},{"addr":"0x0000000000000000000000000000000000000002","reward":200000000000000000000}]`),
+		}
+	)
+	rewards, err := decodeRewards(header, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rewards) != 2 || rewards[0].address != a || rewards[0].amount.Int64() != 100 || rewards[1].address != b {
+		t.Fatalf("wrong rewards: %+v", rewards)
+	}
+	if want, _ := new(big.Int).SetString("200000000000000000000", 10); rewards[1].amount.Cmp(want) != 0 {
+		t.Errorf("wrong reward amount: have %v, want %v", rewards[1].amount, want)
+	}
+	if rewards, err = decodeRewards(header, &b); err != nil || len(rewards) != 1 || rewards[0].address != b {
+		t.Errorf("wrong filtered rewards: %+v, %v", rewards, err)
+	}
+	if rewards, err = decodeRewards(&types.Header{Number: big.NewInt(1)}, nil); err != nil || len(rewards) != 0 {
+		t.Errorf("wrong rewards of a block without rewards: %+v, %v", rewards, err)
+	}
+}
+
+// EOF
@@ -0,0 +1,401 @@
+// api_bundle.go
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxBundleCalls is the max number of transactions or calls simulated by a
+// single eth_callBundle or eth_callMany.
+const maxBundleCalls = 256
+
+// BlockOverrides overrides the fields of the block a bundle is simulated in.
+type BlockOverrides struct {
+	Number   *hexutil.Big    `json:"number"`
+	Time     *hexutil.Uint64 `json:"time"`
+	GasLimit *hexutil.Uint64 `json:"gasLimit"`
+	Coinbase *common.Address `json:"coinbase"`
+	BaseFee  *hexutil.Big    `json:"baseFee"`
+}
+
+// Apply overrides the fields of the given header.
+func (o *BlockOverrides) Apply(header *types.Header) {
+	if o == nil {
+		return
+	}
+	if o.Number != nil {
+		header.Number = o.Number.ToInt()
+	}
+	if o.Time != nil {
+		header.Time = uint64(*o.Time)
+	}
+	if o.GasLimit != nil {
+		header.GasLimit = uint64(*o.GasLimit)
+	}
+	if o.Coinbase != nil {
+		header.Coinbase = *o.Coinbase
+	}
+	if o.BaseFee != nil {
+		header.BaseFee = o.BaseFee.ToInt()
+	}
+}
+
+// BalanceChange is the change of the balance of an account.
+type BalanceChange struct {
+	From *hexutil.Big `json:"from"`
+	To   *hexutil.Big `json:"to"`
+}
+
+// NonceChange is the change of the nonce of an account.
+type NonceChange struct {
+	From hexutil.Uint64 `json:"from"`
+	To   hexutil.Uint64 `json:"to"`
+}
+
+// CodeChange is the change of the code of an account.
+type CodeChange struct {
+	From hexutil.Bytes `json:"from"`
+	To   hexutil.Bytes `json:"to"`
+}
+
+// StorageChange is the change of a storage slot.
+type StorageChange struct {
+	From common.Hash `json:"from"`
+	To   common.Hash `json:"to"`
+}
+
+// AccountChange is the change of an account made by a call.
+type AccountChange struct {
+	Balance *BalanceChange                 `json:"balance,omitempty"`
+	Nonce   *NonceChange                   `json:"nonce,omitempty"`
+	Code    *CodeChange                    `json:"code,omitempty"`
+	Storage map[common.Hash]*StorageChange `json:"storage,omitempty"`
+}
+
+// BundleCallResult is the result of a transaction or call of a bundle.
+type BundleCallResult struct {
+	TxHash       *common.Hash                      `json:"txHash,omitempty"`
+	GasUsed      hexutil.Uint64                    `json:"gasUsed"`
+	ReturnData   hexutil.Bytes                     `json:"returnData"`
+	Error        string                            `json:"error,omitempty"`
+	RevertReason string                            `json:"revertReason,omitempty"`
+	Logs         []*types.Log                      `json:"logs"`
+	StateChanges map[common.Address]*AccountChange `json:"stateChanges"`
+}
+
+// BundleResult is the result of a bundle simulation.
+type BundleResult struct {
+	BlockNumber *hexutil.Big        `json:"blockNumber"`
+	GasUsed     hexutil.Uint64      `json:"gasUsed"`
+	Results     []*BundleCallResult `json:"results"`
+}
+
+// CallBundle simulates signed transactions in order on top of a block, each
+// on the state left by the previous ones, the block fields being optionally
+// overridden. The transactions are validated as in a block, and
+// the simulation fails if one of them is invalid; reverted ones are reported
+// in their results.
+func (s *PublicBlockChainAPI) CallBundle(ctx context.Context, txs []hexutil.Bytes, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides) (*BundleResult, error) {
+	if len(txs) == 0 {
+		return nil, errors.New("empty bundle")
+	}
+	if len(txs) > maxBundleCalls {
+		return nil, fmt.Errorf("bundle too large: %d transactions, max %d", len(txs), maxBundleCalls)
+	}
+	bundle := make([]*types.Transaction, len(txs))
+	for i, input := range txs {
+		bundle[i] = new(types.Transaction)
+		if err := bundle[i].UnmarshalBinary(input); err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+	}
+	return doCallBundle(ctx, s.b, blockNrOrHash, overrides, blockOverrides, len(bundle), false,
+		func(i int, header *types.Header) (core.Message, common.Hash, error) {
+			signer := types.MakeSigner(s.b.ChainConfig(), header.Number)
+			msg, err := bundle[i].AsMessage(signer, header.BaseFee)
+			return msg, bundle[i].Hash(), err
+		})
+}
+
+// CallMany executes calls in order on top of a block, each on the state left
+// by the previous ones, the block fields being optionally overridden. Like
+// eth_call, the calls aren't validated as transactions.
+func (s *PublicBlockChainAPI) CallMany(ctx context.Context, calls []TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides) (*BundleResult, error) {
+	if len(calls) == 0 {
+		return nil, errors.New("empty bundle")
+	}
+	if len(calls) > maxBundleCalls {
+		return nil, fmt.Errorf("bundle too large: %d calls, max %d", len(calls), maxBundleCalls)
+	}
+	return doCallBundle(ctx, s.b, blockNrOrHash, overrides, blockOverrides, len(calls), true,
+		func(i int, header *types.Header) (core.Message, common.Hash, error) {
+			msg, err := calls[i].ToMessage(s.b.RPCGasCap(), header.BaseFee)
+			return msg, common.Hash{}, err
+		})
+}
+
+// doCallBundle executes the n messages returned by next in order on the
+// state of the given block. Fake messages, those of calls, are executed like
+// eth_call, without base fee and with unlimited block gas.
+func doCallBundle(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides, n int, fake bool, next func(int, *types.Header) (core.Message, common.Hash, error)) (*BundleResult, error) {
+	defer func(start time.Time) {
+		log.Debug("Executing EVM bundle finished", "calls", n, "runtime", time.Since(start))
+	}(time.Now())
+
+	statedb, parent, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	if err := overrides.Apply(statedb); err != nil {
+		return nil, err
+	}
+	header := types.CopyHeader(parent)
+	blockOverrides.Apply(header)
+
+	// The timeout applies to the whole bundle
+	timeout := b.RPCEVMTimeout()
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	var (
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+		vmConfig = &vm.Config{}
+		res      = &BundleResult{BlockNumber: (*hexutil.Big)(header.Number)}
+		eip158   = b.ChainConfig().IsEIP158(header.Number)
+		txLogs   int // Logs of the calls so far, all with the zero tx hash
+	)
+	if fake {
+		gp = new(core.GasPool).AddGas(math.MaxUint64)
+		vmConfig.NoBaseFee = true
+	}
+	// A single watcher aborts the call running when the bundle times out
+	var (
+		evmLock sync.Mutex
+		running *vm.EVM
+	)
+	go func() {
+		<-ctx.Done()
+		evmLock.Lock()
+		defer evmLock.Unlock()
+		if running != nil {
+			running.Cancel()
+		}
+	}()
+	for i := 0; i < n; i++ {
+		msg, hash, err := next(i, header)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %v", i, err)
+		}
+		changes := newStateChangeTracer(statedb, msg, header.Coinbase)
+		vmConfig.Debug, vmConfig.Tracer = true, changes
+
+		evm, vmError, err := b.GetEVM(ctx, msg, statedb, header, vmConfig)
+		if err != nil {
+			return nil, err
+		}
+		evmLock.Lock()
+		running = evm
+		if ctx.Err() != nil {
+			evm.Cancel()
+		}
+		evmLock.Unlock()
+
+		statedb.Prepare(hash, i)
+		result, err := core.ApplyMessage(evm, msg, gp)
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w (supplied gas %d)", i, err, msg.Gas())
+		}
+		statedb.Finalise(eip158)
+
+		logs := statedb.GetLogs(hash, common.Hash{})
+		if hash == (common.Hash{}) {
+			logs, txLogs = logs[txLogs:], len(logs)
+		}
+		callResult := &BundleCallResult{
+			GasUsed:      hexutil.Uint64(result.UsedGas),
+			ReturnData:   result.Return(),
+			Logs:         logs,
+			StateChanges: changes.changes(),
+		}
+		if callResult.Logs == nil {
+			callResult.Logs = []*types.Log{}
+		}
+		if hash != (common.Hash{}) {
+			callResult.TxHash = &hash
+		}
+		if result.Err != nil {
+			callResult.Error = result.Err.Error()
+			if len(result.Revert()) > 0 {
+				callResult.ReturnData = result.Revert()
+				if reason, err := abi.UnpackRevert(result.Revert()); err == nil {
+					callResult.RevertReason = reason
+				}
+			}
+		}
+		res.GasUsed += callResult.GasUsed
+		res.Results = append(res.Results, callResult)
+	}
+	return res, nil
+}
+
+// stateChangeTracer records the accounts and storage slots a call touches,
+// before it modifies them, to report the changes the call made.
+type stateChangeTracer struct {
+	statedb  *state.StateDB
+	accounts map[common.Address]*stateChangeAccount
+}
+
+// stateChangeAccount is an account before a call.
+type stateChangeAccount struct {
+	balance *big.Int
+	nonce   uint64
+	code    []byte
+	storage map[common.Hash]common.Hash
+}
+
+// newStateChangeTracer creates a tracer of the changes made by a message,
+// recording the accounts charged or paid by it before it executes.
+func newStateChangeTracer(statedb *state.StateDB, msg core.Message, coinbase common.Address) *stateChangeTracer {
+	t := &stateChangeTracer{
+		statedb:  statedb,
+		accounts: make(map[common.Address]*stateChangeAccount),
+	}
+	t.touch(msg.From())
+	t.touch(coinbase)
+	if msg.To() != nil {
+		t.touch(*msg.To())
+	} else {
+		t.touch(crypto.CreateAddress(msg.From(), statedb.GetNonce(msg.From())))
+	}
+	return t
+}
+
+// touch records an account before it is modified, if not already recorded.
+func (t *stateChangeTracer) touch(addr common.Address) *stateChangeAccount {
+	if account, ok := t.accounts[addr]; ok {
+		return account
+	}
+	account := &stateChangeAccount{
+		balance: new(big.Int).Set(t.statedb.GetBalance(addr)),
+		nonce:   t.statedb.GetNonce(addr),
+		code:    t.statedb.GetCode(addr),
+		storage: make(map[common.Hash]common.Hash),
+	}
+	t.accounts[addr] = account
+	return account
+}
+
+// changes returns the changes made to the recorded accounts.
+func (t *stateChangeTracer) changes() map[common.Address]*AccountChange {
+	changes := make(map[common.Address]*AccountChange)
+	for addr, prev := range t.accounts {
+		var (
+			change  = new(AccountChange)
+			changed bool
+		)
+		if balance := t.statedb.GetBalance(addr); balance.Cmp(prev.balance) != 0 {
+			change.Balance = &BalanceChange{From: (*hexutil.Big)(prev.balance), To: (*hexutil.Big)(new(big.Int).Set(balance))}
+			changed = true
+		}
+		if nonce := t.statedb.GetNonce(addr); nonce != prev.nonce {
+			change.Nonce = &NonceChange{From: hexutil.Uint64(prev.nonce), To: hexutil.Uint64(nonce)}
+			changed = true
+		}
+		if code := t.statedb.GetCode(addr); string(code) != string(prev.code) {
+			change.Code = &CodeChange{From: prev.code, To: code}
+			changed = true
+		}
+		for key, val := range prev.storage {
+			if cur := t.statedb.GetState(addr, key); cur != val {
+				if change.Storage == nil {
+					change.Storage = make(map[common.Hash]*StorageChange)
+				}
+				change.Storage[key] = &StorageChange{From: val, To: cur}
+				changed = true
+			}
+		}
+		if changed {
+			changes[addr] = change
+		}
+	}
+	return changes
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *stateChangeTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+}
+
+// CaptureState implements the EVMLogger interface to record the accounts and
+// slots an opcode is about to modify.
+func (t *stateChangeTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	stack := scope.Stack.Data()
+	switch {
+	case op == vm.SSTORE && len(stack) >= 1:
+		account, key := t.touch(scope.Contract.Address()), common.Hash(stack[len(stack)-1].Bytes32())
+		if _, ok := account.storage[key]; !ok {
+			account.storage[key] = t.statedb.GetState(scope.Contract.Address(), key)
+		}
+	case (op == vm.CALL || op == vm.CALLCODE) && len(stack) >= 3:
+		t.touch(common.Address(stack[len(stack)-2].Bytes20()))
+	case op == vm.SELFDESTRUCT && len(stack) >= 1:
+		t.touch(scope.Contract.Address())
+		t.touch(common.Address(stack[len(stack)-1].Bytes20()))
+	case op == vm.CREATE:
+		t.touch(crypto.CreateAddress(scope.Contract.Address(), t.statedb.GetNonce(scope.Contract.Address())))
+	case op == vm.CREATE2 && len(stack) >= 4:
+		offset, size := stack[len(stack)-2], stack[len(stack)-3]
+		init := scope.Memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64()))
+		salt := stack[len(stack)-4]
+		t.touch(crypto.CreateAddress2(scope.Contract.Address(), salt.Bytes32(), crypto.Keccak256(init)))
+	}
+	if op == vm.CALL || op == vm.CALLCODE || op == vm.CREATE || op == vm.CREATE2 {
+		t.touch(scope.Contract.Address())
+	}
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *stateChangeTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+
+// CaptureExit is called when EVM exits a scope.
+func (t *stateChangeTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *stateChangeTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *stateChangeTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
+}
+
+// EOF
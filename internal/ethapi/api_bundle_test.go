@@ -0,0 +1,245 @@
+// api_bundle_test.go
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// bundleBackend serves a fixed state and header to the bundle APIs, the
+// other Backend methods are not implemented.
+type bundleBackend struct {
+	Backend
+	statedb *state.StateDB
+	header  *types.Header
+	timeout time.Duration
+}
+
+func (b *bundleBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	return b.statedb.Copy(), b.header, nil
+}
+
+func (b *bundleBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config) (*vm.EVM, func() error, error) {
+	context := core.NewEVMBlockContext(header, nil, &header.Coinbase)
+	return vm.NewEVM(context, core.NewEVMTxContext(msg), state, b.ChainConfig(), *vmConfig), func() error { return nil }, nil
+}
+
+func (b *bundleBackend) ChainConfig() *params.ChainConfig { return params.TestChainConfig }
+func (b *bundleBackend) RPCGasCap() uint64                { return 50000000 }
+func (b *bundleBackend) RPCEVMTimeout() time.Duration     { return b.timeout }
+
+var (
+	bundleKey, _  = crypto.GenerateKey()
+	bundleFrom    = crypto.PubkeyToAddress(bundleKey.PublicKey)
+	bundleLogger  = common.HexToAddress("0x1001") // LOG0
+	bundleStorer  = common.HexToAddress("0x1002") // stores 0x2a at slot 0
+	bundlePayer   = common.HexToAddress("0x1003") // sends 1 wei to bundlePayee
+	bundleCreator = common.HexToAddress("0x1004") // CREATE, then CREATE2 with salt 1
+	bundleDoomed  = common.HexToAddress("0x1005") // self-destructs to bundleHeir
+	bundleInfo    = common.HexToAddress("0x1006") // returns the block number and coinbase
+	bundleLoop    = common.HexToAddress("0x1007") // loops forever
+	bundlePayee   = common.HexToAddress("0x2001")
+	bundleHeir    = common.HexToAddress("0x2002")
+)
+
+func newBundleBackend() *bundleBackend {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetBalance(bundleFrom, big.NewInt(params.Ether))
+	statedb.SetCode(bundleLogger, common.FromHex("0x60006000a000"))
+	statedb.SetCode(bundleStorer, common.FromHex("0x602a60005500"))
+	statedb.SetCode(bundlePayer, common.FromHex("0x600060006000600060017300000000000000000000000000000000000020015af100"))
+	statedb.SetBalance(bundlePayer, big.NewInt(10))
+	statedb.SetCode(bundleCreator, common.FromHex("0x600060006000f0506001600060006000f500"))
+	statedb.SetCode(bundleDoomed, common.FromHex("0x730000000000000000000000000000000000002002ff"))
+	statedb.SetBalance(bundleDoomed, big.NewInt(5))
+	statedb.SetCode(bundleInfo, common.FromHex("0x436000524160205260406000f3"))
+	statedb.SetCode(bundleLoop, common.FromHex("0x5b600056"))
+	statedb.Finalise(true)
+
+	return &bundleBackend{
+		statedb: statedb,
+		header: &types.Header{
+			Number:     big.NewInt(1),
+			Coinbase:   common.HexToAddress("0xc0ffee"),
+			GasLimit:   10000000,
+			Time:       1000,
+			Difficulty: big.NewInt(1),
+			BaseFee:    big.NewInt(params.InitialBaseFee),
+		},
+	}
+}
+
+func TestCallMany(t *testing.T) {
+	api := NewPublicBlockChainAPI(newBundleBackend())
+	call := func(to common.Address) TransactionArgs {
+		return TransactionArgs{From: &bundleFrom, To: &to}
+	}
+	res, err := api.CallMany(context.Background(), []TransactionArgs{
+		call(bundleLogger), call(bundleLogger), call(bundleStorer), call(bundlePayer), call(bundleCreator), call(bundleDoomed),
+	}, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil, nil)
+	if err != nil {
+		t.Fatalf("failed to execute calls: %v", err)
+	}
+	if len(res.Results) != 6 {
+		t.Fatalf("result count mismatch: have %d, want 6", len(res.Results))
+	}
+	var gasUsed hexutil.Uint64
+	for i, result := range res.Results {
+		if result.Error != "" || result.TxHash != nil {
+			t.Errorf("call %d: unexpected error %q or tx hash %v", i, result.Error, result.TxHash)
+		}
+		gasUsed += result.GasUsed
+	}
+	if res.GasUsed != gasUsed {
+		t.Errorf("gas used mismatch: have %d, want %d", res.GasUsed, gasUsed)
+	}
+
+	// The calls share the zero tx hash, each has its own logs only
+	for i := 0; i < 2; i++ {
+		if logs := res.Results[i].Logs; len(logs) != 1 || logs[0].Address != bundleLogger {
+			t.Errorf("call %d: logs mismatch: have %v", i, logs)
+		}
+	}
+	if logs := res.Results[2].Logs; logs == nil || len(logs) != 0 {
+		t.Errorf("call 2: logs mismatch: have %v", logs)
+	}
+
+	// SSTORE
+	if change := res.Results[2].StateChanges[bundleStorer]; change == nil || change.Storage[common.Hash{}] == nil ||
+		change.Storage[common.Hash{}].From != (common.Hash{}) || change.Storage[common.Hash{}].To != common.BigToHash(big.NewInt(0x2a)) {
+		t.Errorf("storage change mismatch: have %+v", change)
+	}
+	// CALL with value
+	balance := func(changes map[common.Address]*AccountChange, addr common.Address, from, to int64) {
+		t.Helper()
+		if change := changes[addr]; change == nil || change.Balance == nil || change.Balance.From.ToInt().Int64() != from || change.Balance.To.ToInt().Int64() != to {
+			t.Errorf("%x: balance change mismatch: have %+v, want %d -> %d", addr, change, from, to)
+		}
+	}
+	balance(res.Results[3].StateChanges, bundlePayer, 10, 9)
+	balance(res.Results[3].StateChanges, bundlePayee, 0, 1)
+
+	// CREATE and CREATE2
+	nonce := func(changes map[common.Address]*AccountChange, addr common.Address, from, to uint64) {
+		t.Helper()
+		if change := changes[addr]; change == nil || change.Nonce == nil || uint64(change.Nonce.From) != from || uint64(change.Nonce.To) != to {
+			t.Errorf("%x: nonce change mismatch: have %+v, want %d -> %d", addr, change, from, to)
+		}
+	}
+	var (
+		created  = crypto.CreateAddress(bundleCreator, 0)
+		created2 = crypto.CreateAddress2(bundleCreator, common.BigToHash(common.Big1), crypto.Keccak256(nil))
+	)
+	nonce(res.Results[4].StateChanges, bundleCreator, 0, 2)
+	nonce(res.Results[4].StateChanges, created, 0, 1)
+	nonce(res.Results[4].StateChanges, created2, 0, 1)
+
+	// SELFDESTRUCT
+	balance(res.Results[5].StateChanges, bundleDoomed, 5, 0)
+	balance(res.Results[5].StateChanges, bundleHeir, 0, 5)
+	if change := res.Results[5].StateChanges[bundleDoomed]; change == nil || change.Code == nil || len(change.Code.To) != 0 {
+		t.Errorf("destructed code change mismatch: have %+v", change)
+	}
+
+	// The sender pays no fees for calls
+	if change := res.Results[0].StateChanges[bundleFrom]; change != nil && change.Balance != nil {
+		t.Errorf("call charged the sender: %+v", change.Balance)
+	}
+}
+
+func TestCallManyBlockOverrides(t *testing.T) {
+	api := NewPublicBlockChainAPI(newBundleBackend())
+	var (
+		number   = (*hexutil.Big)(big.NewInt(100))
+		coinbase = common.HexToAddress("0xbeef")
+	)
+	res, err := api.CallMany(context.Background(), []TransactionArgs{{From: &bundleFrom, To: &bundleInfo}},
+		rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil, &BlockOverrides{Number: number, Coinbase: &coinbase})
+	if err != nil {
+		t.Fatalf("failed to execute calls: %v", err)
+	}
+	if res.BlockNumber.ToInt().Cmp(number.ToInt()) != 0 {
+		t.Errorf("block number mismatch: have %v, want %v", res.BlockNumber, number)
+	}
+	ret := res.Results[0].ReturnData
+	if len(ret) != 64 || new(big.Int).SetBytes(ret[:32]).Cmp(number.ToInt()) != 0 || common.BytesToAddress(ret[32:]) != coinbase {
+		t.Errorf("block fields mismatch: have %x", ret)
+	}
+}
+
+func TestCallBundle(t *testing.T) {
+	api := NewPublicBlockChainAPI(newBundleBackend())
+	signer := types.LatestSigner(params.TestChainConfig)
+	sign := func(nonce uint64, to common.Address) hexutil.Bytes {
+		tx := types.MustSignNewTx(bundleKey, signer, &types.LegacyTx{Nonce: nonce, To: &to, Gas: 100000, GasPrice: big.NewInt(params.InitialBaseFee)})
+		blob, _ := tx.MarshalBinary()
+		return blob
+	}
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+
+	txs := []hexutil.Bytes{sign(0, bundleLogger), sign(1, bundleStorer)}
+	res, err := api.CallBundle(context.Background(), txs, latest, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to execute bundle: %v", err)
+	}
+	for i, blob := range txs {
+		tx := new(types.Transaction)
+		tx.UnmarshalBinary(blob)
+		result := res.Results[i]
+		if result.TxHash == nil || *result.TxHash != tx.Hash() {
+			t.Errorf("tx %d: hash mismatch: have %v, want %x", i, result.TxHash, tx.Hash())
+		}
+		if change := result.StateChanges[bundleFrom]; change == nil || change.Nonce == nil || uint64(change.Nonce.To) != uint64(i)+1 || change.Balance == nil {
+			t.Errorf("tx %d: sender change mismatch: have %+v", i, change)
+		}
+	}
+	if logs := res.Results[0].Logs; len(logs) != 1 || logs[0].TxHash != *res.Results[0].TxHash {
+		t.Errorf("tx logs mismatch: have %v", logs)
+	}
+	if change := res.Results[1].StateChanges[bundleStorer]; change == nil || len(change.Storage) != 1 {
+		t.Errorf("storage change mismatch: have %+v", change)
+	}
+
+	// Invalid transactions fail the bundle
+	if _, err := api.CallBundle(context.Background(), []hexutil.Bytes{sign(0, bundleLogger), sign(5, bundleStorer)}, latest, nil, nil); err == nil || !strings.Contains(err.Error(), core.ErrNonceTooHigh.Error()) {
+		t.Errorf("nonce too high error mismatch: have %v", err)
+	}
+	if _, err := api.CallBundle(context.Background(), []hexutil.Bytes{{0x1}}, latest, nil, nil); err == nil || !strings.HasPrefix(err.Error(), "transaction 0") {
+		t.Errorf("undecodable transaction error mismatch: have %v", err)
+	}
+	if _, err := api.CallBundle(context.Background(), nil, latest, nil, nil); err == nil {
+		t.Errorf("empty bundle accepted")
+	}
+}
+
+func TestCallManyTimeout(t *testing.T) {
+	backend := newBundleBackend()
+	backend.timeout = 20 * time.Millisecond
+	api := NewPublicBlockChainAPI(backend)
+
+	calls := []TransactionArgs{{From: &bundleFrom, To: &bundleStorer}, {From: &bundleFrom, To: &bundleLoop}}
+	start := time.Now()
+	if _, err := api.CallMany(context.Background(), calls, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil, nil); err == nil || !strings.Contains(err.Error(), "execution aborted") {
+		t.Errorf("timeout error mismatch: have %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("call not aborted in time: %v", elapsed)
+	}
+}
+
+// EOF
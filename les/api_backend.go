@@ -19,6 +19,7 @@ package les
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/big"
 	"time"
 
@@ -73,7 +74,25 @@ func (b *LesApiBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumb
 	return b.eth.blockchain.GetHeaderByNumberOdr(ctx, uint64(number))
 }
 
+// resolveTimestamp replaces a timestamp selector by the hash of the latest
+// canonical block at or before the timestamp.
+func (b *LesApiBackend) resolveTimestamp(blockNrOrHash rpc.BlockNumberOrHash) (rpc.BlockNumberOrHash, error) {
+	timestamp, ok := blockNrOrHash.Timestamp()
+	if !ok {
+		return blockNrOrHash, nil
+	}
+	header := b.eth.blockchain.GetHeaderByTime(timestamp)
+	if header == nil {
+		return blockNrOrHash, fmt.Errorf("no block at or before timestamp %d", timestamp)
+	}
+	return rpc.BlockNumberOrHashWithHash(header.Hash(), false), nil
+}
+
 func (b *LesApiBackend) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	blockNrOrHash, err := b.resolveTimestamp(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
 	if blockNr, ok := blockNrOrHash.Number(); ok {
 		return b.HeaderByNumber(ctx, blockNr)
 	}
@@ -110,6 +129,10 @@ func (b *LesApiBackend) BlockByHash(ctx context.Context, hash common.Hash) (*typ
 }
 
 func (b *LesApiBackend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	blockNrOrHash, err := b.resolveTimestamp(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
 	if blockNr, ok := blockNrOrHash.Number(); ok {
 		return b.BlockByNumber(ctx, blockNr)
 	}
@@ -145,6 +168,10 @@ func (b *LesApiBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.B
 }
 
 func (b *LesApiBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	blockNrOrHash, err := b.resolveTimestamp(blockNrOrHash)
+	if err != nil {
+		return nil, nil, err
+	}
 	if blockNr, ok := blockNrOrHash.Number(); ok {
 		return b.StateAndHeaderByNumber(ctx, blockNr)
 	}
@@ -195,6 +222,10 @@ func (b *LesApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.eth.txPool.Add(ctx, signedTx)
 }
 
+func (b *LesApiBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction, expiry uint64) error {
+	return errors.New("private transactions are not supported in light mode")
+}
+
 func (b *LesApiBackend) RemoveTx(txHash common.Hash) {
 	b.eth.txPool.RemoveTx(txHash)
 }
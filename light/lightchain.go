@@ -466,6 +466,12 @@ func (lc *LightChain) GetTd(hash common.Hash, number uint64) *big.Int {
 	return lc.hc.GetTd(hash, number)
 }
 
+// GetHeaderByTime retrieves the header of the latest canonical block with a
+// timestamp at or before the given one, nil if the chain starts after it.
+func (lc *LightChain) GetHeaderByTime(time uint64) *types.Header {
+	return lc.hc.GetHeaderByTime(time)
+}
+
 // GetHeaderByNumberOdr retrieves the total difficult from the database or
 // network by hash and number, caching it (associated with its hash) if found.
 func (lc *LightChain) GetTdOdr(ctx context.Context, hash common.Hash, number uint64) *big.Int {
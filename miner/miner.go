@@ -54,6 +54,9 @@ type Config struct {
 	GasPrice   *big.Int       // Minimum gas price for mining a transaction
 	Recommit   time.Duration  // The time interval for miner to re-create mining work.
 	Noverify   bool           // Disable remote mining solution verification(only useful in ethash).
+
+	TxOrdering  string `toml:",omitempty"` // Transaction ordering policy, empty for the default depending on prefetching
+	TxSenderCap int    `toml:",omitempty"` // Max # of transactions per sender in a block, 0 for no limit
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -188,6 +191,32 @@ func (miner *Miner) SetRecommitInterval(interval time.Duration) {
 	miner.worker.setRecommitInterval(interval)
 }
 
+// TxOrdering returns the transaction ordering policy and the per sender cap.
+func (miner *Miner) TxOrdering() (string, int) {
+	return miner.worker.txOrdering()
+}
+
+// SetTxOrdering sets the transaction ordering policy and the per sender cap
+// for the blocks to be built.
+func (miner *Miner) SetTxOrdering(policy string, senderCap int) error {
+	if policy != "" {
+		if _, err := TxOrderingPolicyByName(policy); err != nil {
+			return err
+		}
+	}
+	if senderCap < 0 {
+		return fmt.Errorf("negative sender cap %d", senderCap)
+	}
+	miner.worker.setTxOrdering(policy, senderCap)
+	return nil
+}
+
+// SimulateBlock returns what the next block would contain if it were built
+// now, without sealing or broadcasting it.
+func (miner *Miner) SimulateBlock() (*SimulatedBlock, error) {
+	return miner.worker.simulateBlock()
+}
+
 // Pending returns the currently pending block and associated state.
 func (miner *Miner) Pending() (*types.Block, *state.StateDB) {
 	return miner.worker.pending()
@@ -0,0 +1,169 @@
+// simulate.go
+
+package miner
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// SimulatedTx is a transaction that would be included in the next block.
+type SimulatedTx struct {
+	Hash    common.Hash     `json:"hash"`
+	From    common.Address  `json:"from"`
+	To      *common.Address `json:"to"`
+	Nonce   hexutil.Uint64  `json:"nonce"`
+	Gas     hexutil.Uint64  `json:"gas"`
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Fee     *hexutil.Big    `json:"fee"`
+	Status  hexutil.Uint64  `json:"status"`
+}
+
+// SimulatedReward is a payout made when the next block is finalized.
+type SimulatedReward struct {
+	Addr   common.Address `json:"addr"`
+	Reward *hexutil.Big   `json:"reward"`
+}
+
+// SimulatedBlock is what the next block would look like if it were built
+// now, from the current pool contents.
+type SimulatedBlock struct {
+	Number        *hexutil.Big      `json:"number"`
+	ParentHash    common.Hash       `json:"parentHash"`
+	Coinbase      common.Address    `json:"coinbase"`
+	Timestamp     hexutil.Uint64    `json:"timestamp"`
+	GasLimit      hexutil.Uint64    `json:"gasLimit"`
+	GasUsed       hexutil.Uint64    `json:"gasUsed"`
+	BaseFeePerGas *hexutil.Big      `json:"baseFeePerGas,omitempty"`
+	Fees          *hexutil.Big      `json:"fees"`
+	StateRoot     common.Hash       `json:"stateRoot"`
+	Transactions  []*SimulatedTx    `json:"transactions"`
+	Rewards       []SimulatedReward `json:"rewards"`
+	Elapsed       string            `json:"elapsed"`
+}
+
+// simulateBlock builds the next block on top of the current head with the
+// pending transactions and the governance block build parameters, the way
+// the sealer would, without signing, broadcasting or touching the pending
+// snapshot.
+func (w *worker) simulateBlock() (*SimulatedBlock, error) {
+	tstart := time.Now()
+
+	w.mu.RLock()
+	env, err := w.makePendingEnv(w.chain.CurrentBlock())
+	ordering, senderCap := w.config.TxOrdering, w.config.TxSenderCap
+	w.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	defer env.discard()
+	env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+
+	// Same ordering as commitTransactionsEx uses
+	var policy TxOrderingPolicy = roundRobinPolicy{}
+	if ordering != "" {
+		if policy, err = TxOrderingPolicyByName(ordering); err != nil {
+			policy = roundRobinPolicy{}
+		}
+	} else if params.PrefetchCount == 0 {
+		policy = pricePolicy{}
+	}
+	pending := capPending(env.signer, w.eth.TxPool().Pending(true), nil, senderCap)
+	iter := policy.Order(env.signer, pending, env.header.BaseFee)
+
+	var (
+		sim = &SimulatedBlock{
+			Transactions: []*SimulatedTx{},
+			Rewards:      []SimulatedReward{},
+		}
+		failed = make(map[common.Address]struct{})
+	)
+	for tx := iter.Next(); tx != nil; tx = iter.Next() {
+		if env.gasPool.Gas() < params.TxGas {
+			break
+		}
+		if params.MaxTxsPerBlock > 0 && env.tcount >= params.MaxTxsPerBlock {
+			break
+		}
+		from, _ := types.Sender(env.signer, tx)
+		if _, ok := failed[from]; ok {
+			continue
+		}
+		if tx.Protected() && !w.chainConfig.IsEIP155(env.header.Number) {
+			failed[from] = struct{}{}
+			continue
+		}
+		env.state.Prepare(tx.Hash(), env.tcount)
+		fees := new(big.Int).Set(env.header.Fees)
+		_, err := w.commitTransaction(env, tx)
+		switch {
+		case errors.Is(err, core.ErrGasLimitReached),
+			errors.Is(err, core.ErrNonceTooHigh),
+			errors.Is(err, core.ErrTxTypeNotSupported):
+			// Pop: skip the rest of the sender's transactions
+			failed[from] = struct{}{}
+			continue
+
+		case err != nil:
+			// Shift: nonce too low or a strange error, go on with the
+			// sender's next transaction
+			continue
+		}
+		env.tcount++
+
+		receipt := env.receipts[len(env.receipts)-1]
+		sim.Transactions = append(sim.Transactions, &SimulatedTx{
+			Hash:    tx.Hash(),
+			From:    from,
+			To:      tx.To(),
+			Nonce:   hexutil.Uint64(tx.Nonce()),
+			Gas:     hexutil.Uint64(tx.Gas()),
+			GasUsed: hexutil.Uint64(receipt.GasUsed),
+			Fee:     (*hexutil.Big)(fees.Sub(env.header.Fees, fees)),
+			Status:  hexutil.Uint64(receipt.Status),
+		})
+	}
+
+	// Pay out the rewards. Only Finalize, FinalizeAndAssemble would sign.
+	coinbase := env.header.Coinbase
+	balance := env.state.GetBalance(coinbase)
+	if err := w.engine.Finalize(w.chain, env.header, env.state, env.txs, nil); err != nil {
+		return nil, err
+	}
+	if len(env.header.Rewards) > 0 {
+		var rewards []struct {
+			Addr   common.Address `json:"addr"`
+			Reward *big.Int       `json:"reward"`
+		}
+		if err := json.Unmarshal(env.header.Rewards, &rewards); err != nil {
+			return nil, err
+		}
+		for _, r := range rewards {
+			sim.Rewards = append(sim.Rewards, SimulatedReward{Addr: r.Addr, Reward: (*hexutil.Big)(r.Reward)})
+		}
+	} else if reward := new(big.Int).Sub(env.state.GetBalance(coinbase), balance); reward.Sign() > 0 {
+		sim.Rewards = append(sim.Rewards, SimulatedReward{Addr: coinbase, Reward: (*hexutil.Big)(reward)})
+	}
+
+	sim.Number = (*hexutil.Big)(env.header.Number)
+	sim.ParentHash = env.header.ParentHash
+	sim.Coinbase = env.header.Coinbase
+	sim.Timestamp = hexutil.Uint64(env.header.Time)
+	sim.GasLimit = hexutil.Uint64(env.header.GasLimit)
+	sim.GasUsed = hexutil.Uint64(env.header.GasUsed)
+	sim.BaseFeePerGas = (*hexutil.Big)(env.header.BaseFee)
+	sim.Fees = (*hexutil.Big)(new(big.Int).Set(env.header.Fees))
+	sim.StateRoot = env.header.Root
+	sim.Elapsed = common.PrettyDuration(time.Since(tstart)).String()
+	return sim, nil
+}
+
+// EOF
@@ -0,0 +1,56 @@
+// simulate_test.go
+
+package miner
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestSimulateBlock(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	b.txPool.AddLocals(newTxs)
+
+	head := b.chain.CurrentBlock()
+	sim, err := w.simulateBlock()
+	if err != nil {
+		t.Fatalf("failed to simulate block: %v", err)
+	}
+	if sim.Number.ToInt().Uint64() != head.NumberU64()+1 || sim.ParentHash != head.Hash() {
+		t.Fatalf("parent mismatch: have %d %x, want %d %x", sim.Number.ToInt(), sim.ParentHash, head.NumberU64()+1, head.Hash())
+	}
+	want := []*types.Transaction{pendingTxs[0], newTxs[0]}
+	if len(sim.Transactions) != len(want) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(sim.Transactions), len(want))
+	}
+	for i, tx := range want {
+		have := sim.Transactions[i]
+		if have.Hash != tx.Hash() || have.From != testBankAddress {
+			t.Errorf("tx %d: mismatch: have %x from %x, want %x from %x", i, have.Hash, have.From, tx.Hash(), testBankAddress)
+		}
+		if uint64(have.GasUsed) != params.TxGas || uint64(have.Status) != types.ReceiptStatusSuccessful {
+			t.Errorf("tx %d: gas used %d status %d, want %d %d", i, have.GasUsed, have.Status, params.TxGas, types.ReceiptStatusSuccessful)
+		}
+	}
+	if uint64(sim.GasUsed) != 2*params.TxGas {
+		t.Errorf("gas used mismatch: have %d, want %d", sim.GasUsed, 2*params.TxGas)
+	}
+	// Simulating doesn't touch the chain or the pool
+	if cur := b.chain.CurrentBlock(); cur.Hash() != head.Hash() {
+		t.Errorf("head moved: have %x, want %x", cur.Hash(), head.Hash())
+	}
+	if pending, _ := b.txPool.Stats(); pending != 2 {
+		t.Errorf("pending mismatch: have %d, want %d", pending, 2)
+	}
+}
+
+// EOF
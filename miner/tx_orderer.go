@@ -3,7 +3,9 @@
 package miner
 
 import (
+	"math/big"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -18,61 +20,41 @@ type TxOrdererList struct {
 	prev, next *TxOrdererList
 }
 
-func (to *TxOrderer) listAppend(l *TxOrdererList) {
-	if to.head == nil {
-		to.head, to.tail, l.prev, l.next = l, l, nil, nil
-	} else {
-		l.prev, l.next = to.tail, nil
-		to.tail.next, to.tail = l, l
-	}
-}
-
-func (to *TxOrderer) listDelete(l *TxOrdererList) {
-	if l.prev != nil {
-		l.prev.next = l.next
-	}
-	if l.next != nil {
-		l.next.prev = l.prev
-	}
-	if to.head == l {
-		to.head = l.next
-	}
-	if to.tail == l {
-		to.tail = l.prev
-	}
-}
-
 type TxOrderer struct {
-	lock             *sync.Mutex
-	txs              []*types.Transaction
-	head, tail, curr *TxOrdererList
-	committedTxs     map[common.Hash]*types.Transaction
-	done             bool // set when block generation is done, so that pre-fetching is not needed
-	ix               int  // current index for real execution
-	pix              int  // current index for pre-fetching
-	sz               int
+	lock            *sync.Mutex
+	txs             []*types.Transaction
+	iter            TxIterator // pending transactions in the order of the policy
+	committedTxs    map[common.Hash]*types.Transaction
+	committedAccess *txAccess            // state touched by the transactions committed so far
+	deferred        []*types.Transaction // conflicting transactions to prefetch last
+	hits, misses    int64                // prefetch hits & misses of committed transactions
+	done            bool                 // set when block generation is done, so that pre-fetching is not needed
+	ix              int                  // current index for real execution
+	pix             int                  // current index for pre-fetching
+	sz              int
 }
 
 func NewTxOrderer(pending map[common.Address]types.Transactions, committedTxs map[common.Hash]*types.Transaction) *TxOrderer {
+	return NewTxOrdererWithPolicy(roundRobinPolicy{}, nil, nil, pending, committedTxs, 0)
+}
+
+// NewTxOrdererWithPolicy creates a TxOrderer offering the pending
+// transactions in the order of the given policy, with at most senderCap
+// transactions per sender in the block if positive.
+func NewTxOrdererWithPolicy(policy TxOrderingPolicy, signer types.Signer, baseFee *big.Int, pending map[common.Address]types.Transactions, committedTxs map[common.Hash]*types.Transaction, senderCap int) *TxOrderer {
 	to := &TxOrderer{
-		lock:         &sync.Mutex{},
-		committedTxs: committedTxs,
-		done:         false,
-		ix:           0,
-		pix:          0,
+		lock:            &sync.Mutex{},
+		committedTxs:    committedTxs,
+		committedAccess: newTxAccess(),
+		done:            false,
+		ix:              0,
+		pix:             0,
 	}
-	for a, i := range pending {
-		to.listAppend(&TxOrdererList{
-			addr: a,
-			txs:  i,
-			ix:   0,
-			sz:   len(i),
-			prev: nil,
-			next: nil,
-		})
+	pending = capPending(signer, pending, committedTxs, senderCap)
+	for _, i := range pending {
 		to.sz += len(i)
 	}
-	to.curr = to.head
+	to.iter = policy.Order(signer, pending, baseFee)
 	return to
 }
 
@@ -82,30 +64,12 @@ func (to *TxOrderer) Close() {
 
 // lock should be held by the caller
 func (to *TxOrderer) pull(count int) {
-	for count > 0 {
-		if to.curr == nil {
+	for ; count > 0; count-- {
+		tx := to.iter.Next()
+		if tx == nil {
 			break
 		}
-
-		if to.curr.ix >= to.curr.sz {
-			curr := to.curr.next
-			to.listDelete(to.curr)
-			to.curr = curr
-			if to.curr == nil {
-				to.curr = to.head
-			}
-			continue
-		}
-
-		to.txs = append(to.txs, to.curr.txs[to.curr.ix])
-		to.curr.ix++
-
-		to.curr = to.curr.next
-		if to.curr == nil {
-			to.curr = to.head
-		}
-
-		count--
+		to.txs = append(to.txs, tx)
 	}
 }
 
@@ -155,13 +119,53 @@ func (to *TxOrderer) CommittedLength() int {
 	return len(to.committedTxs)
 }
 
-func (to *TxOrderer) NextForPrefetch() *types.Transaction {
+func (to *TxOrderer) Len() int {
+	to.lock.Lock()
+	defer to.lock.Unlock()
+	return to.sz
+}
+
+// MarkAccessed records the state touched by a committed transaction.
+func (to *TxOrderer) MarkAccessed(a *txAccess) {
+	to.lock.Lock()
+	defer to.lock.Unlock()
+	to.committedAccess.merge(a)
+}
+
+// Conflicts tells if the given state was touched by committed transactions.
+func (to *TxOrderer) Conflicts(a *txAccess) bool {
+	to.lock.Lock()
+	defer to.lock.Unlock()
+	return to.committedAccess.conflicts(a)
+}
+
+// Defer puts off prefetching of the transaction until the others in the
+// prefetch window are done.
+func (to *TxOrderer) Defer(tx *types.Transaction) {
+	to.lock.Lock()
+	defer to.lock.Unlock()
+	to.deferred = append(to.deferred, tx)
+}
+
+// HitRate returns the percentage of committed transactions that were
+// prefetched as they were executed.
+func (to *TxOrderer) HitRate() int64 {
+	hits, misses := atomic.LoadInt64(&to.hits), atomic.LoadInt64(&to.misses)
+	if hits+misses == 0 {
+		return 0
+	}
+	return hits * 100 / (hits + misses)
+}
+
+// NextForPrefetch returns the next transaction to prefetch, and whether it
+// was deferred before.
+func (to *TxOrderer) NextForPrefetch() (*types.Transaction, bool) {
 	to.lock.Lock()
 	defer to.lock.Unlock()
 
 	for {
 		if to.done {
-			return nil
+			return nil, false
 		}
 		if to.ix >= to.pix {
 			to.pix = to.ix + 1
@@ -174,18 +178,30 @@ func (to *TxOrderer) NextForPrefetch() *types.Transaction {
 		if to.pix >= len(to.txs) {
 			to.pull(1000)
 			if to.pix >= len(to.txs) {
-				return nil
+				return to.nextDeferred(), true
 			}
 		}
 
 		tx := to.txs[to.pix]
 		to.pix++
 		if _, ok := to.committedTxs[tx.Hash()]; !ok {
-			return tx
+			return tx, false
 		} else {
 			continue
 		}
 	}
 }
 
+// lock should be held by the caller
+func (to *TxOrderer) nextDeferred() *types.Transaction {
+	for len(to.deferred) > 0 {
+		tx := to.deferred[0]
+		to.deferred = to.deferred[1:]
+		if _, ok := to.committedTxs[tx.Hash()]; !ok {
+			return tx
+		}
+	}
+	return nil
+}
+
 // EOF
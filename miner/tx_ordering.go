@@ -0,0 +1,257 @@
+// tx_ordering.go
+
+package miner
+
+import (
+	"bytes"
+	"container/heap"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Names of the built-in transaction ordering policies
+const (
+	TxOrderingRoundRobin = "roundrobin" // one transaction per sender in turn
+	TxOrderingPrice      = "price"      // highest effective tip first
+	TxOrderingFIFO       = "fifo"       // earliest arrival first
+)
+
+// TxIterator yields transactions one by one, nil when there are no more.
+type TxIterator interface {
+	Next() *types.Transaction
+}
+
+// TxOrderingPolicy decides the order in which pending transactions are
+// offered for inclusion in a block.
+type TxOrderingPolicy interface {
+	// Order returns an iterator over the pending transactions. Transactions
+	// of a sender are to be yielded in nonce order. The policy owns the
+	// given map.
+	Order(signer types.Signer, pending map[common.Address]types.Transactions, baseFee *big.Int) TxIterator
+}
+
+var txOrderingPolicies = map[string]TxOrderingPolicy{
+	TxOrderingRoundRobin: roundRobinPolicy{},
+	TxOrderingPrice:      pricePolicy{},
+	TxOrderingFIFO:       fifoPolicy{},
+}
+
+// TxOrderingPolicyByName returns the transaction ordering policy of the given
+// name.
+func TxOrderingPolicyByName(name string) (TxOrderingPolicy, error) {
+	if policy, ok := txOrderingPolicies[name]; ok {
+		return policy, nil
+	}
+	return nil, fmt.Errorf("unknown transaction ordering %q, want one of %v", name, TxOrderingPolicies())
+}
+
+// TxOrderingPolicies returns the names of the transaction ordering policies.
+func TxOrderingPolicies() []string {
+	names := make([]string, 0, len(txOrderingPolicies))
+	for name := range txOrderingPolicies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OrderTransactions returns the pending transactions in the order the given
+// policy offers them for inclusion, capped to senderCap transactions per
+// sender if positive. It leaves 'pending' intact.
+func OrderTransactions(policy TxOrderingPolicy, signer types.Signer, pending map[common.Address]types.Transactions, baseFee *big.Int, senderCap int) []*types.Transaction {
+	var (
+		txs  []*types.Transaction
+		iter = policy.Order(signer, capPending(signer, pending, nil, senderCap), baseFee)
+	)
+	for tx := iter.Next(); tx != nil; tx = iter.Next() {
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+// capPending returns a copy of the pending transactions, with at most
+// senderCap transactions per sender including the ones already processed
+// for the block.
+func capPending(signer types.Signer, pending map[common.Address]types.Transactions, processed map[common.Hash]*types.Transaction, senderCap int) map[common.Address]types.Transactions {
+	var counts map[common.Address]int
+	if senderCap > 0 && len(processed) > 0 {
+		counts = make(map[common.Address]int)
+		for _, tx := range processed {
+			if from, err := types.Sender(signer, tx); err == nil {
+				counts[from]++
+			}
+		}
+	}
+	capped := make(map[common.Address]types.Transactions, len(pending))
+	for addr, txs := range pending {
+		if senderCap > 0 {
+			n := senderCap - counts[addr]
+			if n <= 0 {
+				continue
+			}
+			// processed transactions stay in the pool until the block is in
+			for len(txs) > 0 && processed[txs[0].Hash()] != nil {
+				txs = txs[1:]
+			}
+			if len(txs) > n {
+				txs = txs[:n]
+			}
+		}
+		capped[addr] = txs
+	}
+	return capped
+}
+
+// roundRobinPolicy takes one transaction per sender in turn.
+type roundRobinPolicy struct{}
+
+type roundRobinTxs struct {
+	head, tail, curr *TxOrdererList
+}
+
+func (roundRobinPolicy) Order(signer types.Signer, pending map[common.Address]types.Transactions, baseFee *big.Int) TxIterator {
+	rr := &roundRobinTxs{}
+	for a, i := range pending {
+		rr.listAppend(&TxOrdererList{
+			addr: a,
+			txs:  i,
+			ix:   0,
+			sz:   len(i),
+			prev: nil,
+			next: nil,
+		})
+	}
+	rr.curr = rr.head
+	return rr
+}
+
+func (rr *roundRobinTxs) listAppend(l *TxOrdererList) {
+	if rr.head == nil {
+		rr.head, rr.tail, l.prev, l.next = l, l, nil, nil
+	} else {
+		l.prev, l.next = rr.tail, nil
+		rr.tail.next, rr.tail = l, l
+	}
+}
+
+func (rr *roundRobinTxs) listDelete(l *TxOrdererList) {
+	if l.prev != nil {
+		l.prev.next = l.next
+	}
+	if l.next != nil {
+		l.next.prev = l.prev
+	}
+	if rr.head == l {
+		rr.head = l.next
+	}
+	if rr.tail == l {
+		rr.tail = l.prev
+	}
+}
+
+func (rr *roundRobinTxs) Next() *types.Transaction {
+	for rr.curr != nil {
+		if rr.curr.ix >= rr.curr.sz {
+			curr := rr.curr.next
+			rr.listDelete(rr.curr)
+			rr.curr = curr
+			if rr.curr == nil {
+				rr.curr = rr.head
+			}
+			continue
+		}
+
+		tx := rr.curr.txs[rr.curr.ix]
+		rr.curr.ix++
+
+		rr.curr = rr.curr.next
+		if rr.curr == nil {
+			rr.curr = rr.head
+		}
+		return tx
+	}
+	return nil
+}
+
+// pricePolicy takes the transaction with the highest effective tip first,
+// like types.TransactionsByPriceAndNonce does.
+type pricePolicy struct{}
+
+type priceTxs struct {
+	txs *types.TransactionsByPriceAndNonce
+}
+
+func (pricePolicy) Order(signer types.Signer, pending map[common.Address]types.Transactions, baseFee *big.Int) TxIterator {
+	return &priceTxs{txs: types.NewTransactionsByPriceAndNonce(signer, pending, baseFee)}
+}
+
+func (p *priceTxs) Next() *types.Transaction {
+	tx := p.txs.Peek()
+	if tx != nil {
+		p.txs.Shift()
+	}
+	return tx
+}
+
+// fifoPolicy takes the transaction that arrived first, among the lowest
+// nonce ones of the senders.
+type fifoPolicy struct{}
+
+type fifoTxs struct {
+	heads []types.Transactions // remaining transactions of the senders, in a heap by arrival of the first one
+}
+
+func (fifoPolicy) Order(signer types.Signer, pending map[common.Address]types.Transactions, baseFee *big.Int) TxIterator {
+	f := &fifoTxs{heads: make([]types.Transactions, 0, len(pending))}
+	for _, txs := range pending {
+		if len(txs) > 0 {
+			f.heads = append(f.heads, txs)
+		}
+	}
+	heap.Init(f)
+	return f
+}
+
+func (f *fifoTxs) Len() int { return len(f.heads) }
+func (f *fifoTxs) Less(i, j int) bool {
+	ti, tj := f.heads[i][0].Time(), f.heads[j][0].Time()
+	if ti.Equal(tj) {
+		hi, hj := f.heads[i][0].Hash(), f.heads[j][0].Hash()
+		return bytes.Compare(hi[:], hj[:]) < 0
+	}
+	return ti.Before(tj)
+}
+func (f *fifoTxs) Swap(i, j int) { f.heads[i], f.heads[j] = f.heads[j], f.heads[i] }
+
+func (f *fifoTxs) Push(x interface{}) {
+	f.heads = append(f.heads, x.(types.Transactions))
+}
+
+func (f *fifoTxs) Pop() interface{} {
+	old := f.heads
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	f.heads = old[0 : n-1]
+	return x
+}
+
+func (f *fifoTxs) Next() *types.Transaction {
+	if len(f.heads) == 0 {
+		return nil
+	}
+	tx := f.heads[0][0]
+	if len(f.heads[0]) > 1 {
+		f.heads[0] = f.heads[0][1:]
+		heap.Fix(f, 0)
+	} else {
+		heap.Pop(f)
+	}
+	return tx
+}
+
+// EOF
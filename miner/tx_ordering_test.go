@@ -0,0 +1,86 @@
+// tx_ordering_test.go
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestTxOrderingPolicies(t *testing.T) {
+	var (
+		signer  = types.LatestSignerForChainID(common.Big1)
+		keys    = make([]*ecdsa.PrivateKey, 3)
+		senders = make([]common.Address, len(keys))
+		pending = make(map[common.Address]types.Transactions)
+		byHash  = make(map[common.Hash]int)
+	)
+	// sender i sends transactions with price 1+i, the j-th of which arrives
+	// as the (3*j+2-i)-th one
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		senders[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	for n := 0; n < 9; n++ {
+		i, j := 2-n%3, n/3
+		tx, _ := types.SignTx(types.NewTransaction(uint64(j), common.Address{}, common.Big0, 21000, big.NewInt(int64(1+i)), nil), signer, keys[i])
+		pending[senders[i]] = append(pending[senders[i]], tx)
+		byHash[tx.Hash()] = i
+		time.Sleep(time.Millisecond)
+	}
+	order := func(name string, senderCap int) []int {
+		policy, err := TxOrderingPolicyByName(name)
+		if err != nil {
+			t.Fatalf("policy %s: %v", name, err)
+		}
+		var (
+			order  []int
+			nonces = make(map[int]uint64)
+		)
+		for _, tx := range OrderTransactions(policy, signer, pending, nil, senderCap) {
+			i := byHash[tx.Hash()]
+			if tx.Nonce() != nonces[i] {
+				t.Fatalf("policy %s: sender %d nonce mismatch: have %d, want %d", name, i, tx.Nonce(), nonces[i])
+			}
+			nonces[i]++
+			order = append(order, i)
+		}
+		return order
+	}
+	check := func(name string, have, want []int) {
+		if len(have) != len(want) {
+			t.Fatalf("policy %s: length mismatch: have %v, want %v", name, have, want)
+		}
+		for i := range have {
+			if have[i] != want[i] {
+				t.Fatalf("policy %s: order mismatch: have %v, want %v", name, have, want)
+			}
+		}
+	}
+	check(TxOrderingPrice, order(TxOrderingPrice, 0), []int{2, 2, 2, 1, 1, 1, 0, 0, 0})
+	check(TxOrderingFIFO, order(TxOrderingFIFO, 0), []int{2, 1, 0, 2, 1, 0, 2, 1, 0})
+	check(TxOrderingPrice+" capped", order(TxOrderingPrice, 2), []int{2, 2, 1, 1, 0, 0})
+
+	// round-robin takes senders in map order, but one at a time
+	rr := order(TxOrderingRoundRobin, 0)
+	if len(rr) != 9 || rr[0] == rr[1] || rr[1] == rr[2] || rr[0] == rr[2] {
+		t.Fatalf("policy %s: not round-robin: %v", TxOrderingRoundRobin, rr)
+	}
+	if rr := order(TxOrderingRoundRobin, 1); len(rr) != 3 {
+		t.Fatalf("policy %s: cap not applied: %v", TxOrderingRoundRobin, rr)
+	}
+	for i := range pending {
+		if len(pending[i]) != 3 {
+			t.Fatalf("pending transactions modified")
+		}
+	}
+	if _, err := TxOrderingPolicyByName("random"); err == nil {
+		t.Fatalf("unknown policy accepted")
+	}
+}
@@ -4,20 +4,223 @@ package miner
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 var (
+	// tx hash -> *txAccess, nil while the transaction is being prefetched
 	doneTxs = lru.NewLruCache(5000, false)
+
+	// average time to prefetch a transaction in nanoseconds
+	prefetchCost int64 = int64(time.Millisecond)
+
+	prefetchTxMeter       = metrics.NewRegisteredMeter("miner/prefetch/txs", nil)
+	prefetchDeferMeter    = metrics.NewRegisteredMeter("miner/prefetch/defer", nil)
+	prefetchHitMeter      = metrics.NewRegisteredMeter("miner/prefetch/hit", nil)
+	prefetchMissMeter     = metrics.NewRegisteredMeter("miner/prefetch/miss", nil)
+	prefetchConflictMeter = metrics.NewRegisteredMeter("miner/prefetch/conflict", nil)
+	prefetchHitRateGauge  = metrics.NewRegisteredGauge("miner/prefetch/hitrate", nil)
+	prefetchWorkersGauge  = metrics.NewRegisteredGauge("miner/prefetch/workers", nil)
+	prefetchTimer         = metrics.NewRegisteredTimer("miner/prefetch/time", nil)
 )
 
-func tx_prefetch(w *worker, env *environment, to *TxOrderer, numWorkers int) {
+// txAccess is the set of accounts and storage slots touched by a transaction.
+type txAccess struct {
+	addrs map[common.Address]struct{}
+	slots map[common.Address]map[common.Hash]struct{}
+
+	// predicted tells that the access is predicted before execution, the
+	// storage of the addresses without slots being unknown, not untouched
+	predicted bool
+}
+
+func newTxAccess() *txAccess {
+	return &txAccess{
+		addrs: make(map[common.Address]struct{}),
+		slots: make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+// newTxAccessFromList builds a txAccess out of an EIP-2929 access list,
+// leaving out precompiles which every transaction has in its list.
+func newTxAccessFromList(list types.AccessList, precompiles []common.Address) *txAccess {
+	a := newTxAccess()
+	for _, tuple := range list {
+		a.addAddress(tuple.Address)
+		for _, slot := range tuple.StorageKeys {
+			a.addSlot(tuple.Address, slot)
+		}
+	}
+	for _, addr := range precompiles {
+		if len(a.slots[addr]) == 0 {
+			delete(a.addrs, addr)
+		}
+	}
+	return a
+}
+
+func (a *txAccess) addAddress(addr common.Address) {
+	a.addrs[addr] = struct{}{}
+}
+
+func (a *txAccess) addSlot(addr common.Address, slot common.Hash) {
+	a.addrs[addr] = struct{}{}
+	if a.slots[addr] == nil {
+		a.slots[addr] = make(map[common.Hash]struct{})
+	}
+	a.slots[addr][slot] = struct{}{}
+}
+
+// merge adds everything touched in b to a.
+func (a *txAccess) merge(b *txAccess) {
+	for addr := range b.addrs {
+		a.addAddress(addr)
+	}
+	for addr, slots := range b.slots {
+		for slot := range slots {
+			a.addSlot(addr, slot)
+		}
+	}
+}
+
+// conflicts tells if a and b touch the same state. Contracts whose storage is
+// accessed are compared slot by slot, so that transactions calling the same
+// contract on different slots, e.g. token transfers between different
+// holders, don't conflict. Plain accounts are compared by address. The unknown
+// storage access of predicted accesses is not taken as a conflict.
+func (a *txAccess) conflicts(b *txAccess) bool {
+	if len(a.addrs) > len(b.addrs) {
+		a, b = b, a
+	}
+	for addr := range a.addrs {
+		if _, ok := b.addrs[addr]; !ok {
+			continue
+		}
+		as, bs := a.slots[addr], b.slots[addr]
+		switch {
+		case len(as) == 0 && len(bs) == 0:
+			return true
+		case len(as) == 0 && a.predicted, len(bs) == 0 && b.predicted:
+			continue
+		case len(as) == 0 || len(bs) == 0:
+			return true
+		}
+		if len(as) > len(bs) {
+			as, bs = bs, as
+		}
+		for slot := range as {
+			if _, ok := bs[slot]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// covers tells if everything touched in b was touched in a as well.
+func (a *txAccess) covers(b *txAccess) bool {
+	for addr := range b.addrs {
+		if _, ok := a.addrs[addr]; !ok {
+			return false
+		}
+		for slot := range b.slots[addr] {
+			if _, ok := a.slots[addr][slot]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// txPredictedAccess returns the accounts and slots a transaction is known to
+// touch before it's executed, i.e. its sender, recipient and access list.
+func txPredictedAccess(signer types.Signer, tx *types.Transaction) *txAccess {
+	a := newTxAccess()
+	a.predicted = true
+	for _, tuple := range tx.AccessList() {
+		a.addAddress(tuple.Address)
+		for _, slot := range tuple.StorageKeys {
+			a.addSlot(tuple.Address, slot)
+		}
+	}
+	if from, err := types.Sender(signer, tx); err == nil {
+		a.addAddress(from)
+	}
+	if to := tx.To(); to != nil {
+		a.addAddress(*to)
+	}
+	return a
+}
+
+// updatePrefetchCost folds the latest per transaction prefetch time into the
+// running average.
+func updatePrefetchCost(d time.Duration) {
+	for {
+		old := atomic.LoadInt64(&prefetchCost)
+		if atomic.CompareAndSwapInt64(&prefetchCost, old, old-old/8+int64(d)/8) {
+			return
+		}
+	}
+}
+
+// prefetchWorkers sizes the prefetcher to the load: enough workers to get
+// through the pending transactions within half the block interval at the
+// observed prefetch cost, but no more than params.PrefetchCount.
+func prefetchWorkers(pending int, blockInterval int64) int {
+	if params.PrefetchCount <= 0 {
+		return 0
+	}
+	if blockInterval <= 0 {
+		blockInterval = params.BlockInterval
+	}
+	budget := int64(time.Duration(blockInterval)*time.Second) / 2
+	if budget <= 0 {
+		return params.PrefetchCount
+	}
+	n := int((int64(pending)*atomic.LoadInt64(&prefetchCost) + budget - 1) / budget)
+	if n < 1 {
+		n = 1
+	} else if n > params.PrefetchCount {
+		n = params.PrefetchCount
+	}
+	return n
+}
+
+// prefetchCommitted updates prefetch metrics and the orderer's view of the
+// committed state once a transaction is committed to the block. 'list' is
+// the access list of the committed transaction.
+func prefetchCommitted(to *TxOrderer, tx *types.Transaction, list types.AccessList, precompiles []common.Address) {
+	committed := newTxAccessFromList(list, precompiles)
+	switch prefetched, _ := doneTxs.Get(tx.Hash()).(*txAccess); {
+	case prefetched == nil:
+		prefetchMissMeter.Mark(1)
+		atomic.AddInt64(&to.misses, 1)
+	case prefetched.covers(committed):
+		prefetchHitMeter.Mark(1)
+		atomic.AddInt64(&to.hits, 1)
+	default:
+		// executed differently than prefetched, as state it read was
+		// changed by the transactions committed before it
+		prefetchConflictMeter.Mark(1)
+		atomic.AddInt64(&to.misses, 1)
+	}
+	to.MarkAccessed(committed)
+}
+
+func tx_prefetch(w *worker, env *environment, to *TxOrderer) {
+	numWorkers := prefetchWorkers(to.Len(), env.blockInterval)
+	prefetchWorkersGauge.Update(int64(numWorkers))
+
 	for i := 0; i < numWorkers; i++ {
 		go func() {
 			var (
@@ -32,16 +235,23 @@ func tx_prefetch(w *worker, env *environment, to *TxOrderer, numWorkers int) {
 			)
 
 			for {
-				tx := to.NextForPrefetch()
+				tx, deferred := to.NextForPrefetch()
 				if tx == nil {
 					break
 				} else if doneTxs.Exists(tx.Hash()) {
 					continue
-				} else {
-					doneTxs.Put(tx.Hash(), true)
 				}
 
-				_, err := types.Sender(signer, tx)
+				// Transactions touching accounts changed by committed ones
+				// would run on stale state, so do the others first.
+				if !deferred && to.Conflicts(txPredictedAccess(signer, tx)) {
+					to.Defer(tx)
+					prefetchDeferMeter.Mark(1)
+					continue
+				}
+				doneTxs.Put(tx.Hash(), (*txAccess)(nil))
+
+				from, err := types.Sender(signer, tx)
 				if err != nil {
 					log.Error("Prefetch", "tx -> sender", err)
 					continue
@@ -55,6 +265,11 @@ func tx_prefetch(w *worker, env *environment, to *TxOrderer, numWorkers int) {
 				statedb.Prepare(tx.Hash(), tix)
 				tix++
 
+				// Earlier transactions of the sender might have been run by
+				// other workers, align the nonce so that this one gets run.
+				statedb.SetNonce(from, tx.Nonce())
+
+				start := time.Now()
 				ictx, cancel := context.WithTimeout(ctx, time.Second)
 				evm.Reset(core.NewEVMTxContext(msg), statedb)
 				go func() {
@@ -66,6 +281,14 @@ func tx_prefetch(w *worker, env *environment, to *TxOrderer, numWorkers int) {
 					log.Error("Prefetch", "ApplyMessage failed", err)
 				}
 				cancel()
+
+				elapsed := time.Since(start)
+				updatePrefetchCost(elapsed)
+				prefetchTimer.Update(elapsed)
+				prefetchTxMeter.Mark(1)
+
+				rules := w.chainConfig.Rules(header.Number, false)
+				doneTxs.Put(tx.Hash(), newTxAccessFromList(statedb.AccessList(), vm.ActivePrecompiles(rules)))
 			}
 		}()
 	}
@@ -0,0 +1,109 @@
+// tx_prefetch_test.go
+
+package miner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestTxAccessConflicts(t *testing.T) {
+	var (
+		alice = common.HexToAddress("0xa")
+		bob   = common.HexToAddress("0xb")
+		token = common.HexToAddress("0xc")
+		pre   = common.BytesToAddress([]byte{1})
+	)
+	access := func(list types.AccessList) *txAccess {
+		return newTxAccessFromList(list, []common.Address{pre})
+	}
+	transfer := func(from common.Address, slots ...common.Hash) *txAccess {
+		return access(types.AccessList{{Address: from}, {Address: pre}, {Address: token, StorageKeys: slots}})
+	}
+
+	predicted := func(from, to common.Address, slots ...common.Hash) *txAccess {
+		key, _ := crypto.GenerateKey()
+		signer := types.LatestSignerForChainID(common.Big1)
+		tx := types.MustSignNewTx(key, signer, &types.AccessListTx{
+			ChainID:    common.Big1,
+			To:         &to,
+			AccessList: types.AccessList{{Address: to, StorageKeys: slots}},
+		})
+		a := txPredictedAccess(signer, tx)
+		// the sender is the generated key, stand in the given one
+		delete(a.addrs, crypto.PubkeyToAddress(key.PublicKey))
+		a.addAddress(from)
+		return a
+	}
+	tests := []struct {
+		a, b     *txAccess
+		conflict bool
+	}{
+		// same sender
+		{transfer(alice, common.Hash{1}), transfer(alice, common.Hash{2}), true},
+		// same contract, different slots
+		{transfer(alice, common.Hash{1}), transfer(bob, common.Hash{2}), false},
+		// same contract, same slot
+		{transfer(alice, common.Hash{1}), transfer(bob, common.Hash{2}, common.Hash{1}), true},
+		// contract accessed without storage
+		{transfer(alice, common.Hash{1}), access(types.AccessList{{Address: bob}, {Address: token}}), true},
+		// predicted access to a contract with unknown storage
+		{transfer(alice, common.Hash{1}), predicted(bob, token), false},
+		// predicted access to a contract with known storage
+		{transfer(alice, common.Hash{1}), predicted(bob, token, common.Hash{1}), true},
+		{transfer(alice, common.Hash{1}), predicted(bob, token, common.Hash{2}), false},
+		// predicted access to a touched account
+		{transfer(alice, common.Hash{1}), predicted(bob, alice), true},
+		// precompiles don't count
+		{access(types.AccessList{{Address: alice}, {Address: pre}}), access(types.AccessList{{Address: bob}, {Address: pre}}), false},
+	}
+	for i, tt := range tests {
+		if have := tt.a.conflicts(tt.b); have != tt.conflict {
+			t.Errorf("test %d: conflict mismatch: have %v, want %v", i, have, tt.conflict)
+		}
+		if have := tt.b.conflicts(tt.a); have != tt.conflict {
+			t.Errorf("test %d: reverse conflict mismatch: have %v, want %v", i, have, tt.conflict)
+		}
+	}
+	if !transfer(alice, common.Hash{1}, common.Hash{2}).covers(transfer(alice, common.Hash{2})) {
+		t.Errorf("superset doesn't cover subset")
+	}
+	if transfer(alice, common.Hash{2}).covers(transfer(alice, common.Hash{1}, common.Hash{2})) {
+		t.Errorf("subset covers superset")
+	}
+}
+
+func TestPrefetchWorkers(t *testing.T) {
+	defer func(count int, cost int64) {
+		params.PrefetchCount, prefetchCost = count, cost
+	}(params.PrefetchCount, prefetchCost)
+
+	params.PrefetchCount = 0
+	if n := prefetchWorkers(10000, 1); n != 0 {
+		t.Fatalf("prefetching disabled: have %d workers", n)
+	}
+
+	params.PrefetchCount, prefetchCost = 16, int64(time.Millisecond)
+	tests := []struct {
+		pending  int
+		interval int64
+		workers  int
+	}{
+		{0, 1, 1},
+		{500, 1, 1},
+		{501, 1, 2},
+		{2000, 1, 4},
+		{2000, 2, 2},
+		{100000, 1, 16},
+	}
+	for i, tt := range tests {
+		if have := prefetchWorkers(tt.pending, tt.interval); have != tt.workers {
+			t.Errorf("test %d: workers mismatch: have %d, want %d", i, have, tt.workers)
+		}
+	}
+}
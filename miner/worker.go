@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -294,6 +295,13 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		log.Warn("Sanitizing miner recommit interval", "provided", recommit, "updated", minRecommitInterval)
 		recommit = minRecommitInterval
 	}
+	// Sanitize transaction ordering policy
+	if ordering := worker.config.TxOrdering; ordering != "" {
+		if _, err := TxOrderingPolicyByName(ordering); err != nil {
+			log.Warn("Sanitizing miner transaction ordering", "provided", ordering, "err", err)
+			worker.config.TxOrdering = ""
+		}
+	}
 
 	worker.wg.Add(4)
 	go worker.mainLoop()
@@ -325,6 +333,18 @@ func (w *worker) setGasCeil(ceil uint64) {
 	w.config.GasCeil = ceil
 }
 
+func (w *worker) txOrdering() (string, int) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.config.TxOrdering, w.config.TxSenderCap
+}
+
+func (w *worker) setTxOrdering(policy string, senderCap int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.config.TxOrdering, w.config.TxSenderCap = policy, senderCap
+}
+
 // setExtra sets the content used to initialize the block extra field.
 func (w *worker) setExtra(extra []byte) {
 	w.mu.Lock()
@@ -1045,9 +1065,14 @@ func (w *worker) commitTransactionsSimple(env *environment, txs *TxOrderer, inte
 	}
 	var coalescedLogs []*types.Log
 
-	tx_prefetch(w, env, txs, params.PrefetchCount)
+	tx_prefetch(w, env, txs)
 	defer txs.Close()
 
+	precompiles := vm.ActivePrecompiles(w.chainConfig.Rules(env.header.Number, false))
+	defer func() {
+		prefetchHitRateGauge.Update(txs.HitRate())
+	}()
+
 	for {
 		// In the following three cases, we will interrupt the execution of the transaction.
 		// (1) new head block event arrival, the interrupt signal is 1
@@ -1123,6 +1148,7 @@ func (w *worker) commitTransactionsSimple(env *environment, txs *TxOrderer, inte
 			// Everything ok, collect the logs and shift in the next transaction from the same account
 			coalescedLogs = append(coalescedLogs, logs...)
 			env.tcount++
+			prefetchCommitted(txs, tx, env.state.AccessList(), precompiles)
 			txs.Shift()
 
 		case errors.Is(err, core.ErrTxTypeNotSupported):
@@ -1238,8 +1264,10 @@ func (w *worker) commitTransactionsEx(env *environment, interrupt *int32, tstart
 			continue
 		}
 
-		// using new simple round-robin ordering instead of old one.
-		if params.PrefetchCount == 0 {
+		// using new simple round-robin ordering instead of old one,
+		// unless an ordering policy is set.
+		ordering, senderCap := w.txOrdering()
+		if params.PrefetchCount == 0 && ordering == "" {
 			// remove processed txs from 'pending'
 			if len(committedTxs) > 0 {
 				for k, x := range pending {
@@ -1262,7 +1290,11 @@ func (w *worker) commitTransactionsEx(env *environment, interrupt *int32, tstart
 				return true
 			}
 		} else {
-			txs := NewTxOrderer(pending, committedTxs)
+			policy, err := TxOrderingPolicyByName(ordering)
+			if err != nil {
+				policy = roundRobinPolicy{}
+			}
+			txs := NewTxOrdererWithPolicy(policy, env.signer, env.header.BaseFee, pending, committedTxs, senderCap)
 			if w.commitTransactionsSimple(env, txs, interrupt, &tstart) {
 				return true
 			}
@@ -1441,8 +1473,14 @@ func (w *worker) refreshPending(locked bool) {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 
-	parent := w.chain.CurrentBlock()
+	if env, err := w.makePendingEnv(w.chain.CurrentBlock()); err == nil {
+		w.updateSnapshot(env)
+	}
+}
 
+// makePendingEnv creates an environment for the block following the given
+// parent, as a non-sealing node sees it. w.mu should be held by the caller.
+func (w *worker) makePendingEnv(parent *types.Block) (*environment, error) {
 	blockInterval, _, blockGasLimit, baseFeeMaxChangeRate, gasTargetPercentage, _ := wemixminer.GetBlockBuildParameters(parent.Number())
 	num := parent.Number()
 	num.Add(num, common.Big1)
@@ -1467,15 +1505,17 @@ func (w *worker) refreshPending(locked bool) {
 	}
 	if err := w.engine.Prepare(w.chain, header); err != nil {
 		log.Error("Failed to prepare header for mining", "err", err)
-		return
+		return nil, err
 	}
-	if env, err := w.makeEnv(parent, header, header.Coinbase); err == nil {
-		env.blockInterval = blockInterval
-		env.blockGasLimit = blockGasLimit
-		env.baseFeeMaxChangeRate = baseFeeMaxChangeRate
-		env.gasTargetPercentage = gasTargetPercentage
-		w.updateSnapshot(env)
+	env, err := w.makeEnv(parent, header, header.Coinbase)
+	if err != nil {
+		return nil, err
 	}
+	env.blockInterval = blockInterval
+	env.blockGasLimit = blockGasLimit
+	env.baseFeeMaxChangeRate = baseFeeMaxChangeRate
+	env.gasTargetPercentage = gasTargetPercentage
+	return env, nil
 }
 
 // generateWork generates a sealing block based on the given parameters.
@@ -138,6 +138,14 @@ type Config struct {
 	// HTTPPathPrefix specifies a path prefix on which http-rpc is to be served.
 	HTTPPathPrefix string `toml:",omitempty"`
 
+	// HTTPStream enables JSON-RPC streams over unencrypted HTTP/2 on the HTTP
+	// RPC interface, multiplexing calls and subscriptions over a connection.
+	HTTPStream bool `toml:",omitempty"`
+
+	// RPCLimits holds the API keys and rate limits of the HTTP and websocket RPC
+	// interfaces.
+	RPCLimits *rpc.LimitConfig `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string
@@ -28,10 +28,13 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/rs/cors"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 // httpConfig is the JSON-RPC/HTTP configuration.
@@ -39,6 +42,8 @@ type httpConfig struct {
 	Modules            []string
 	CorsAllowedOrigins []string
 	Vhosts             []string
+	Limits             *rpc.LimitConfig
+	Stream             bool   // serve JSON-RPC streams over unencrypted HTTP/2
 	prefix             string // path prefix on which to mount http handler
 }
 
@@ -46,6 +51,7 @@ type httpConfig struct {
 type wsConfig struct {
 	Origins []string
 	Modules []string
+	Limits  *rpc.LimitConfig
 	prefix  string // path prefix on which to mount ws handler
 }
 
@@ -131,6 +137,18 @@ func (h *httpServer) start() error {
 		h.server.WriteTimeout = h.timeouts.WriteTimeout
 		h.server.IdleTimeout = h.timeouts.IdleTimeout
 	}
+	if h.httpConfig.Stream {
+		// Serve HTTP/2 without TLS for JSON-RPC streams. The connections are
+		// hijacked from the HTTP/1 server, whose deadlines would end the streams
+		// they outlive: the idle timeout applies through the HTTP/2 server, and
+		// the write timeout to each write, the HTTP/2 server not supporting it.
+		h.server.Handler = h2c.NewHandler(h, &http2.Server{IdleTimeout: h.timeouts.IdleTimeout})
+		h.server.ConnState = func(conn net.Conn, state http.ConnState) {
+			if state == http.StateHijacked {
+				conn.(*streamConn).hijacked()
+			}
+		}
+	}
 
 	// Start the server.
 	listener, err := net.Listen("tcp", h.endpoint)
@@ -141,6 +159,9 @@ func (h *httpServer) start() error {
 		h.disableWS()
 		return err
 	}
+	if h.httpConfig.Stream {
+		listener = &streamListener{Listener: listener, writeTimeout: h.timeouts.WriteTimeout}
+	}
 	h.listener = listener
 	go h.server.Serve(listener)
 
@@ -161,6 +182,7 @@ func (h *httpServer) start() error {
 		"prefix", h.httpConfig.prefix,
 		"cors", strings.Join(h.httpConfig.CorsAllowedOrigins, ","),
 		"vhosts", strings.Join(h.httpConfig.Vhosts, ","),
+		"stream", h.httpConfig.Stream,
 	)
 
 	// Log all handlers mounted on server.
@@ -183,8 +205,8 @@ func (h *httpServer) start() error {
 func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// check if ws request and serve if ws enabled
 	ws := h.wsHandler.Load().(*rpcHandler)
-	if ws != nil && isWebsocket(r) {
-		if checkPath(r, h.wsConfig.prefix) {
+	if ws != nil && isWebsocket(r) && !h.isMuxWebsocket(r) {
+		if checkPath(r, h.wsConfig.prefix) || checkKeyPath(r, h.wsConfig.prefix, h.wsConfig.Limits) {
 			ws.ServeHTTP(w, r)
 		}
 		return
@@ -202,7 +224,7 @@ func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if checkPath(r, h.httpConfig.prefix) {
+		if checkPath(r, h.httpConfig.prefix) || checkKeyPath(r, h.httpConfig.prefix, h.httpConfig.Limits) {
 			rpc.ServeHTTP(w, r)
 			return
 		}
@@ -210,6 +232,16 @@ func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotFound)
 }
 
+// isMuxWebsocket checks whether a websocket request is for a handler registered
+// in the mux, like GraphQL subscriptions, rather than for the RPC server.
+func (h *httpServer) isMuxWebsocket(r *http.Request) bool {
+	if h.httpHandler.Load().(*rpcHandler) == nil {
+		return false
+	}
+	_, pattern := h.mux.Handler(r)
+	return pattern != ""
+}
+
 // checkPath checks whether a given request URL matches a given path prefix.
 func checkPath(r *http.Request, path string) bool {
 	// if no prefix has been specified, request URL must be on root
@@ -220,6 +252,16 @@ func checkPath(r *http.Request, path string) bool {
 	return len(r.URL.Path) >= len(path) && r.URL.Path[:len(path)] == path
 }
 
+// checkKeyPath checks whether a given request URL is a given path prefix followed
+// by an API key.
+func checkKeyPath(r *http.Request, path string, limits *rpc.LimitConfig) bool {
+	if !limits.HasAPIKey(r.URL.Path) {
+		return false
+	}
+	key := r.URL.Path[strings.LastIndexByte(r.URL.Path, '/')+1:]
+	return r.URL.Path == strings.TrimSuffix(path, "/")+"/"+key
+}
+
 // validatePrefix checks if 'path' is a valid configuration value for the RPC prefix option.
 func validatePrefix(what, path string) error {
 	if path == "" {
@@ -283,6 +325,9 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 	if err := RegisterApis(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
+	if err := srv.SetLimits(config.Limits); err != nil {
+		return err
+	}
 	h.httpConfig = config
 	h.httpHandler.Store(&rpcHandler{
 		Handler: NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts),
@@ -315,6 +360,9 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 	if err := RegisterApis(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
+	if err := srv.SetLimits(config.Limits); err != nil {
+		return err
+	}
 	h.wsConfig = config
 	h.wsHandler.Store(&rpcHandler{
 		Handler: srv.WebsocketHandler(config.Origins),
@@ -453,7 +501,7 @@ func (w *gzipResponseWriter) Write(b []byte) (int, error) {
 
 func newGzipHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || isWebsocket(r) || rpc.IsStreamRequest(r) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -515,6 +563,57 @@ func (is *ipcServer) stop() error {
 	return err
 }
 
+// streamListener accepts the connections of an HTTP server serving JSON-RPC
+// streams over HTTP/2.
+type streamListener struct {
+	net.Listener
+	writeTimeout time.Duration
+}
+
+func (l *streamListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &streamConn{Conn: conn, writeTimeout: l.writeTimeout}, nil
+}
+
+// streamConn is a connection of an HTTP server serving JSON-RPC streams. Once
+// hijacked to serve HTTP/2, the deadlines set by the HTTP/1 server are cleared
+// and the write deadline is renewed on each write, until the deadlines are set
+// by the new owner of the connection, like websockets.
+type streamConn struct {
+	net.Conn
+	writeTimeout time.Duration
+	renew        int32 // Whether the write deadline is renewed on each write, atomic
+}
+
+// hijacked clears the deadlines of the HTTP/1 server when it hands the
+// connection over.
+func (c *streamConn) hijacked() {
+	c.Conn.SetDeadline(time.Time{})
+	if c.writeTimeout > 0 {
+		atomic.StoreInt32(&c.renew, 1)
+	}
+}
+
+func (c *streamConn) Write(p []byte) (int, error) {
+	if atomic.LoadInt32(&c.renew) == 1 {
+		c.Conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+	return c.Conn.Write(p)
+}
+
+func (c *streamConn) SetDeadline(t time.Time) error {
+	atomic.StoreInt32(&c.renew, 0)
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *streamConn) SetWriteDeadline(t time.Time) error {
+	atomic.StoreInt32(&c.renew, 0)
+	return c.Conn.SetWriteDeadline(t)
+}
+
 // RegisterApis checks the given modules' availability, generates an allowlist based on the allowed modules,
 // and then registers all of the APIs exposed by the services.
 func RegisterApis(apis []rpc.API, modules []string, srv *rpc.Server, exposeAll bool) error {
@@ -18,12 +18,16 @@ package node
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/internal/testlog"
 	"github.com/ethereum/go-ethereum/log"
@@ -174,6 +178,57 @@ func TestIsWebsocket(t *testing.T) {
 	assert.True(t, isWebsocket(r))
 }
 
+// TestHTTPStream makes sure JSON-RPC streams are served over HTTP/2 when enabled.
+func TestHTTPStream(t *testing.T) {
+	srv := createAndStartServer(t, &httpConfig{Stream: true}, false, &wsConfig{})
+	defer srv.stop()
+	url := "http://" + srv.listenAddr()
+
+	client, err := rpc.DialStream(context.Background(), url)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	var modules map[string]string
+	assert.NoError(t, client.Call(&modules, "rpc_modules"))
+
+	// Plain HTTP requests are still served
+	resp := rpcRequest(t, url)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+// TestHTTPStreamTimeouts makes sure JSON-RPC streams outlive the deadlines of
+// the HTTP/1 server, and hijacked connections time out stalled writes.
+func TestHTTPStreamTimeouts(t *testing.T) {
+	timeouts := rpc.HTTPTimeouts{ReadTimeout: time.Second, WriteTimeout: time.Second, IdleTimeout: time.Second}
+	srv := newHTTPServer(testlog.Logger(t, log.LvlDebug), timeouts)
+	assert.NoError(t, srv.enableRPC(nil, httpConfig{Stream: true}))
+	assert.NoError(t, srv.setListenAddr("localhost", 0))
+	assert.NoError(t, srv.start())
+	defer srv.stop()
+
+	client, err := rpc.DialStream(context.Background(), "http://"+srv.listenAddr())
+	assert.NoError(t, err)
+	defer client.Close()
+
+	var modules map[string]string
+	assert.NoError(t, client.Call(&modules, "rpc_modules"))
+	time.Sleep(1500 * time.Millisecond)
+	assert.NoError(t, client.Call(&modules, "rpc_modules"))
+
+	// Writes to a peer not reading time out, until the deadlines are set by
+	// the new owner of the connection
+	local, remote := net.Pipe()
+	defer remote.Close()
+	conn := &streamConn{Conn: local, writeTimeout: 50 * time.Millisecond}
+	conn.hijacked()
+	_, err = conn.Write([]byte("stalled"))
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	assert.NoError(t, conn.SetWriteDeadline(time.Time{}))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&conn.renew))
+}
+
 func Test_checkPath(t *testing.T) {
 	tests := []struct {
 		req      *http.Request
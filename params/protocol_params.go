@@ -188,11 +188,15 @@ var (
 	BlocksPerTurn        uint64 = 100
 	DropUnderPriced      bool   = true // drop underpriced transactions
 
-	NonceLimit     uint64 = 0    // nonce limit for non-governing accounts
-	UseRocksDb     int    = 1    // LevelDB (0) or RocksDB (1)
-	PrefetchCount  int    = 0    // Transaction Prefetch count for faster db read
-	MaxTxsPerBlock int    = 5000 // Max # of transactions in a block
-	Hub            string = ""   // Hub's id
+	NonceLimit        uint64 = 0    // nonce limit for non-governing accounts
+	UseRocksDb        int    = 1    // LevelDB (0) or RocksDB (1)
+	PrefetchCount     int    = 0    // Transaction Prefetch count for faster db read
+	ParallelExecCount int    = 0    // # of workers executing transactions in parallel on block import, 0 to disable
+	MaxTxsPerBlock    int    = 5000 // Max # of transactions in a block
+	Hub               string = ""   // Hub's id
+
+	PartnerTxExGossip     bool    = false // send transactions to partners in batches signed with the node key, with senders
+	PartnerTxExSampleRate float64 = 0.05  // fraction of the senders in partner batches to re-verify
 
 	BlockInterval        int64 = 1    // Block generation interval in seconds
 	BlockTimeAdjBlocks   int64 = 120  // Block interval to adjust timestamp
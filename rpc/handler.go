@@ -19,6 +19,7 @@ package rpc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"reflect"
 	"strconv"
 	"strings"
@@ -60,6 +61,7 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	limiter        *limiter // limits of the calls, nil if unlimited
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -68,6 +70,7 @@ type handler struct {
 type callProc struct {
 	ctx       context.Context
 	notifiers []*Notifier
+	respSize  int // size of the responses so far, checked against the limits
 }
 
 func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry) *handler {
@@ -100,6 +103,14 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	if h.limiter != nil {
+		if err := h.limiter.checkBatch(len(msgs)); err != nil {
+			h.startCallProc(func(cp *callProc) {
+				h.conn.writeJSON(cp.ctx, errorMessage(err))
+			})
+			return
+		}
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -294,7 +305,10 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 		h.log.Debug("Served "+msg.Method, "duration", time.Since(start))
 		return nil
 	case msg.isCall():
-		resp := h.handleCall(ctx, msg)
+		if h.limiter != nil && h.limiter.responseExhausted(ctx.respSize) {
+			return msg.errorResponse(&responseTooLargeError{h.limiter.config.MaxResponseSize})
+		}
+		resp := h.limitResponse(ctx, msg, h.handleCall(ctx, msg))
 		var ctx []interface{}
 		ctx = append(ctx, "reqid", idForLog{msg.ID}, "duration", time.Since(start))
 		if resp.Error != nil {
@@ -328,12 +342,30 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 	if callb == nil {
 		return msg.errorResponse(&methodNotFoundError{method: msg.Method})
 	}
+	if callb != h.unsubscribeCb {
+		if err := h.checkLimits(cp.ctx, msg); err != nil {
+			return msg.errorResponse(err)
+		}
+	}
 	args, err := parsePositionalArguments(msg.Params, callb.argTypes)
 	if err != nil {
 		return msg.errorResponse(&invalidParamsError{err.Error()})
 	}
 	start := time.Now()
-	answer := h.runMethod(cp.ctx, msg, callb, args)
+	ctx, timeout := cp.ctx, time.Duration(0)
+	if h.limiter != nil {
+		ctx = h.limiter.withResponseBudget(ctx, cp.respSize)
+		if timeout = h.limiter.timeout(msg.Method); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+	}
+	answer := h.runMethod(ctx, msg, callb, args)
+	if timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		rpcLimitTimeoutMeter.Mark(1)
+		answer = msg.errorResponse(&timeoutError{timeout})
+	}
 
 	// Collect the statistics for RPC calls if metrics is enabled.
 	// We only care about pure rpc call. Filter out subscription.
@@ -366,6 +398,9 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 	if callb == nil {
 		return msg.errorResponse(&subscriptionNotFoundError{namespace, name})
 	}
+	if err := h.checkLimits(cp.ctx, msg); err != nil {
+		return msg.errorResponse(err)
+	}
 
 	// Parse subscription name arg too, but remove it before calling the callback.
 	argTypes := append([]reflect.Type{stringType}, callb.argTypes...)
@@ -383,6 +418,26 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 	return h.runMethod(ctx, msg, callb, args)
 }
 
+// checkLimits checks the rate limits of a call, if any.
+func (h *handler) checkLimits(ctx context.Context, msg *jsonrpcMessage) error {
+	if h.limiter == nil {
+		return nil
+	}
+	return h.limiter.allow(PeerInfoFromContext(ctx), msg.Method)
+}
+
+// limitResponse replaces the response to a call by an error if the responses
+// to the request exceed the response size limit.
+func (h *handler) limitResponse(cp *callProc, msg, resp *jsonrpcMessage) *jsonrpcMessage {
+	if h.limiter == nil || resp.Error != nil {
+		return resp
+	}
+	if err := h.limiter.checkResponse(&cp.respSize, len(resp.Result)); err != nil {
+		return msg.errorResponse(err)
+	}
+	return resp
+}
+
 // runMethod runs the Go callback for an RPC method.
 func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value) *jsonrpcMessage {
 	result, err := callb.call(ctx, msg.Method, args)
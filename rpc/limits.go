@@ -0,0 +1,380 @@
+// limits.go
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// APIKeyHeader is the HTTP header carrying the API key of a client. The
+	// key can also be passed as the last segment of the URL path.
+	APIKeyHeader = "X-Api-Key"
+
+	// AnyMethod is the limit rule matching all methods.
+	AnyMethod = "*"
+
+	limitSweepInterval = time.Minute
+)
+
+// RateLimit is a token bucket limit, refilled with Rate requests per second up
+// to Burst requests. The burst defaults to the rate, at least one request.
+type RateLimit struct {
+	Rate  float64
+	Burst int `toml:",omitempty"`
+}
+
+// LimitConfig holds the limits of the requests served to HTTP and WebSocket
+// clients.
+//
+// Rate limits are keyed by method ("eth_getLogs"), namespace ("debug") or
+// AnyMethod, and a call consumes a request of each matching limit. Clients
+// with a valid API key are limited per key, the others per IP address.
+type LimitConfig struct {
+	APIKeys       map[string]string    `toml:",omitempty"` // API keys, mapped to the names of their clients
+	RequireAPIKey bool                 `toml:",omitempty"` // Whether clients without API key are rejected
+	IPLimits      map[string]RateLimit `toml:",omitempty"` // Rate limits of each IP address
+	KeyLimits     map[string]RateLimit `toml:",omitempty"` // Rate limits of each API key
+	MaxBatchSize  int                  `toml:",omitempty"` // Max number of requests in a batch, 0 for unlimited
+
+	// MaxResponseSize is the max size in bytes of the responses to a request
+	// or batch, 0 for unlimited. The calls of a batch after the limit is
+	// reached aren't executed.
+	MaxResponseSize int `toml:",omitempty"`
+
+	// MethodTimeouts are the max execution times of methods, namespaces or
+	// AnyMethod, the most specific one applying. Calls exceeding them are
+	// canceled through their context.
+	MethodTimeouts map[string]time.Duration `toml:",omitempty"`
+}
+
+// enabled returns whether the config limits anything.
+func (c *LimitConfig) enabled() bool {
+	return c != nil && (len(c.APIKeys) > 0 || c.RequireAPIKey || len(c.IPLimits) > 0 || len(c.KeyLimits) > 0 ||
+		c.MaxBatchSize > 0 || c.MaxResponseSize > 0 || len(c.MethodTimeouts) > 0)
+}
+
+// HasAPIKey returns whether the last segment of a URL path is an API key.
+func (c *LimitConfig) HasAPIKey(urlPath string) bool {
+	if c == nil || len(c.APIKeys) == 0 {
+		return false
+	}
+	_, ok := c.APIKeys[path.Base(urlPath)]
+	return ok
+}
+
+// limitExceededError is returned for the requests rejected by the limits.
+type limitExceededError struct{ message string }
+
+func (e *limitExceededError) ErrorCode() int { return -32005 }
+
+func (e *limitExceededError) Error() string { return e.message }
+
+// timeoutError is returned for the calls exceeding their execution time.
+type timeoutError struct{ timeout time.Duration }
+
+func (e *timeoutError) ErrorCode() int { return -32002 }
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("request timed out (timeout = %v)", e.timeout)
+}
+
+// responseTooLargeError is returned for the calls exceeding the response size
+// budget of a request.
+type responseTooLargeError struct{ limit int }
+
+func (e *responseTooLargeError) ErrorCode() int { return -32003 }
+
+func (e *responseTooLargeError) Error() string {
+	return fmt.Sprintf("response too large (limit = %d bytes)", e.limit)
+}
+
+// limitBucket is the token bucket of a client for a limit rule.
+type limitBucket struct {
+	limiter *rate.Limiter
+	idle    time.Duration // Time to refill the bucket, after which it can be dropped
+	used    time.Time
+}
+
+// limiter enforces the limits of a server.
+type limiter struct {
+	config LimitConfig
+
+	mu      sync.Mutex
+	buckets map[string]*limitBucket // Buckets by client and rule
+	swept   time.Time
+}
+
+// newLimiter creates a limiter, or returns nil if the config limits nothing.
+func newLimiter(config *LimitConfig) (*limiter, error) {
+	if !config.enabled() {
+		return nil, nil
+	}
+	for _, limits := range []map[string]RateLimit{config.IPLimits, config.KeyLimits} {
+		for rule, limit := range limits {
+			if rule == "" || limit.Rate < 0 || limit.Burst < 0 || math.IsNaN(limit.Rate) || math.IsInf(limit.Rate, 0) {
+				return nil, fmt.Errorf("invalid rate limit %q: %+v", rule, limit)
+			}
+		}
+	}
+	for rule, timeout := range config.MethodTimeouts {
+		if rule == "" || timeout < 0 {
+			return nil, fmt.Errorf("invalid method timeout %q: %v", rule, timeout)
+		}
+	}
+	if config.MaxBatchSize < 0 || config.MaxResponseSize < 0 {
+		return nil, fmt.Errorf("invalid batch or response size limit")
+	}
+	for key := range config.APIKeys {
+		if key == "" || strings.ContainsAny(key, "/?#") {
+			return nil, fmt.Errorf("invalid API key %q", key)
+		}
+	}
+	return &limiter{
+		config:  *config,
+		buckets: make(map[string]*limitBucket),
+		swept:   time.Now(),
+	}, nil
+}
+
+// apiKey returns the API key sent with a request, in the header or as the
+// last segment of the URL path.
+func (l *limiter) apiKey(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	if l.config.HasAPIKey(r.URL.Path) {
+		return path.Base(r.URL.Path)
+	}
+	return ""
+}
+
+// authorize checks the API key of a request, returning the key if valid.
+func (l *limiter) authorize(r *http.Request) (string, error) {
+	key := l.apiKey(r)
+	if key == "" {
+		if l.config.RequireAPIKey {
+			rpcLimitKeyRejectedMeter.Mark(1)
+			return "", fmt.Errorf("missing API key")
+		}
+		return "", nil
+	}
+	if _, ok := l.config.APIKeys[key]; !ok {
+		rpcLimitKeyRejectedMeter.Mark(1)
+		return "", fmt.Errorf("invalid API key")
+	}
+	return key, nil
+}
+
+// checkBatch checks the size of a batch.
+func (l *limiter) checkBatch(size int) error {
+	if l.config.MaxBatchSize > 0 && size > l.config.MaxBatchSize {
+		rpcLimitBatchRejectedMeter.Mark(1)
+		return &limitExceededError{fmt.Sprintf("batch too large: %d requests, max %d", size, l.config.MaxBatchSize)}
+	}
+	return nil
+}
+
+// allow checks the rate limits of a call by the client of a connection,
+// consuming a request of each limit matching the method.
+func (l *limiter) allow(info PeerInfo, method string) error {
+	var (
+		client string
+		limits map[string]RateLimit
+	)
+	if name, ok := l.config.APIKeys[info.HTTP.APIKey]; ok && info.HTTP.APIKey != "" {
+		client, limits = "key:"+name, l.config.KeyLimits
+	} else {
+		host, _, err := net.SplitHostPort(info.RemoteAddr)
+		if err != nil {
+			host = info.RemoteAddr
+		}
+		client, limits = "ip:"+host, l.config.IPLimits
+	}
+	if len(limits) == 0 {
+		return nil
+	}
+	rules := limitRules(method)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.swept) > limitSweepInterval {
+		l.sweep(now)
+	}
+	var reservations []*rate.Reservation
+	for _, rule := range rules {
+		limit, ok := limits[rule]
+		if !ok || limit.Rate == 0 {
+			continue
+		}
+		bucket := l.bucket(client+"|"+rule, limit)
+		bucket.used = now
+
+		r := bucket.limiter.ReserveN(now, 1)
+		if !r.OK() || r.DelayFrom(now) > 0 {
+			r.CancelAt(now)
+			for _, prev := range reservations {
+				prev.CancelAt(now)
+			}
+			rpcLimitRateRejectedMeter.Mark(1)
+			newRPCLimitRejectedMeter(method).Mark(1)
+			return &limitExceededError{fmt.Sprintf("rate limit exceeded for %s (%s)", method, rule)}
+		}
+		reservations = append(reservations, r)
+	}
+	return nil
+}
+
+// timeout returns the max execution time of a method, 0 if unlimited.
+func (l *limiter) timeout(method string) time.Duration {
+	for _, rule := range limitRules(method) {
+		if timeout, ok := l.config.MethodTimeouts[rule]; ok {
+			return timeout
+		}
+	}
+	return 0
+}
+
+// checkResponse adds the size of a response to the responses to a request,
+// checking the total against the response size budget.
+func (l *limiter) checkResponse(total *int, size int) error {
+	if l.config.MaxResponseSize == 0 {
+		return nil
+	}
+	if *total += size; *total > l.config.MaxResponseSize {
+		rpcLimitResponseRejectedMeter.Mark(1)
+		return &responseTooLargeError{l.config.MaxResponseSize}
+	}
+	return nil
+}
+
+// responseExhausted returns whether the responses to a request already reached
+// the response size limit, the remaining calls being rejected.
+func (l *limiter) responseExhausted(total int) bool {
+	return l.config.MaxResponseSize > 0 && total >= l.config.MaxResponseSize
+}
+
+// ResponseBudget is the response size left to a call by the response size
+// limit. Calls returning large results, like eth_getLogs, consume it while
+// collecting them to stop as soon as the limit is exceeded, instead of
+// building a response that is rejected once marshalled.
+type ResponseBudget struct {
+	limit int
+	left  int64
+}
+
+type responseBudgetKey struct{}
+
+// ResponseBudgetFromContext returns the response size budget of a call, nil if
+// the response size is unlimited.
+func ResponseBudgetFromContext(ctx context.Context) *ResponseBudget {
+	budget, _ := ctx.Value(responseBudgetKey{}).(*ResponseBudget)
+	return budget
+}
+
+// Consume takes size bytes of the budget, returning an error once the budget is
+// exceeded. Consuming a nil budget always succeeds.
+func (b *ResponseBudget) Consume(size int) error {
+	if b == nil {
+		return nil
+	}
+	if atomic.AddInt64(&b.left, -int64(size)) < 0 {
+		return &responseTooLargeError{b.limit}
+	}
+	return nil
+}
+
+// withResponseBudget adds the response size left to a request to the context
+// of a call.
+func (l *limiter) withResponseBudget(ctx context.Context, total int) context.Context {
+	if l.config.MaxResponseSize == 0 {
+		return ctx
+	}
+	budget := &ResponseBudget{limit: l.config.MaxResponseSize, left: int64(l.config.MaxResponseSize - total)}
+	return context.WithValue(ctx, responseBudgetKey{}, budget)
+}
+
+// Limiter enforces the API keys and rate limits of a LimitConfig for the
+// services served next to the RPC server, like GraphQL. A nil limiter limits
+// nothing.
+type Limiter struct {
+	limiter *limiter
+}
+
+// NewLimiter creates a limiter, or returns nil if the config limits nothing.
+func NewLimiter(config *LimitConfig) (*Limiter, error) {
+	l, err := newLimiter(config)
+	if l == nil || err != nil {
+		return nil, err
+	}
+	return &Limiter{limiter: l}, nil
+}
+
+// Authorize checks the API key of a request, returning the key if valid.
+func (l *Limiter) Authorize(r *http.Request) (string, error) {
+	if l == nil {
+		return "", nil
+	}
+	return l.limiter.authorize(r)
+}
+
+// Allow checks the rate limits of a call by a client, consuming a request of
+// each limit matching the method.
+func (l *Limiter) Allow(info PeerInfo, method string) error {
+	if l == nil {
+		return nil
+	}
+	return l.limiter.allow(info, method)
+}
+
+// limitRules returns the limit rules matching a method, from the most to the
+// least specific.
+func limitRules(method string) []string {
+	if i := strings.Index(method, serviceMethodSeparator); i > 0 {
+		return []string{method, method[:i], AnyMethod}
+	}
+	return []string{method, AnyMethod}
+}
+
+// bucket returns the bucket with the given id, creating it if missing.
+func (l *limiter) bucket(id string, limit RateLimit) *limitBucket {
+	if bucket, ok := l.buckets[id]; ok {
+		return bucket
+	}
+	burst := limit.Burst
+	if burst == 0 {
+		burst = int(math.Ceil(limit.Rate))
+	}
+	bucket := &limitBucket{
+		limiter: rate.NewLimiter(rate.Limit(limit.Rate), burst),
+		idle:    time.Duration(float64(burst) / limit.Rate * float64(time.Second)),
+	}
+	l.buckets[id] = bucket
+	return bucket
+}
+
+// sweep drops the buckets idle for long enough to be full again, recreating
+// them being equivalent.
+func (l *limiter) sweep(now time.Time) {
+	for id, bucket := range l.buckets {
+		if now.Sub(bucket.used) > bucket.idle {
+			delete(l.buckets, id)
+		}
+	}
+	l.swept = now
+}
+
+// EOF
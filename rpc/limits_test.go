@@ -0,0 +1,224 @@
+// limits_test.go
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newLimitedTestServer(t *testing.T, config *LimitConfig) (*Server, *httptest.Server) {
+	s := newTestServer()
+	if err := s.SetLimits(config); err != nil {
+		t.Fatal(err)
+	}
+	return s, httptest.NewServer(s)
+}
+
+func TestHTTPAPIKeys(t *testing.T) {
+	s, ts := newLimitedTestServer(t, &LimitConfig{
+		APIKeys:       map[string]string{"secret": "partner"},
+		RequireAPIKey: true,
+	})
+	defer s.Stop()
+	defer ts.Close()
+
+	// Requests without or with an invalid key are rejected
+	for _, header := range []string{"", "wrong"} {
+		c, err := Dial(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header != "" {
+			c.SetHeader(APIKeyHeader, header)
+		}
+		var httpErr HTTPError
+		if err := c.Call(nil, "test_noArgsRets"); !errors.As(err, &httpErr) || httpErr.StatusCode != 401 {
+			t.Errorf("key %q: expected 401 error, got %v", header, err)
+		}
+		c.Close()
+	}
+	// The key is accepted in the header and in the URL path
+	for _, url := range []string{ts.URL, ts.URL + "/secret"} {
+		c, err := Dial(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if url == ts.URL {
+			c.SetHeader(APIKeyHeader, "secret")
+		}
+		var info PeerInfo
+		if err := c.Call(&info, "test_peerInfo"); err != nil {
+			t.Fatalf("url %s: %v", url, err)
+		}
+		if info.HTTP.APIKey != "secret" {
+			t.Errorf("url %s: wrong HTTP.APIKey %q", url, info.HTTP.APIKey)
+		}
+		c.Close()
+	}
+}
+
+func TestHTTPRateLimits(t *testing.T) {
+	s, ts := newLimitedTestServer(t, &LimitConfig{
+		APIKeys:   map[string]string{"secret": "partner"},
+		IPLimits:  map[string]RateLimit{"test_noArgsRets": {Rate: 0.001, Burst: 2}, "test": {Rate: 0.001, Burst: 3}},
+		KeyLimits: map[string]RateLimit{AnyMethod: {Rate: 0.001, Burst: 5}},
+	})
+	defer s.Stop()
+	defer ts.Close()
+
+	c, err := Dial(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// The method limit applies, then the namespace one, which the method
+	// calls consumed
+	for i := 0; i < 2; i++ {
+		if err := c.Call(nil, "test_noArgsRets"); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	var rpcErr Error
+	if err := c.Call(nil, "test_noArgsRets"); !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != -32005 {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+	if err := c.Call(nil, "test_echo", "x", 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Call(nil, "test_echo", "x", 1, nil); !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != -32005 {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+	// Clients with an API key are limited separately
+	c.SetHeader(APIKeyHeader, "secret")
+	for i := 0; i < 5; i++ {
+		if err := c.Call(nil, "test_noArgsRets"); err != nil {
+			t.Fatalf("key call %d: %v", i, err)
+		}
+	}
+	if err := c.Call(nil, "test_noArgsRets"); !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != -32005 {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+}
+
+func TestHTTPBatchLimit(t *testing.T) {
+	s, ts := newLimitedTestServer(t, &LimitConfig{MaxBatchSize: 2})
+	defer s.Stop()
+	defer ts.Close()
+
+	c, err := Dial(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	batch := []BatchElem{{Method: "test_noArgsRets", Result: new(interface{})}, {Method: "test_noArgsRets", Result: new(interface{})}}
+	if err := c.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	for i, elem := range batch {
+		if elem.Error != nil {
+			t.Fatalf("batch element %d: %v", i, elem.Error)
+		}
+	}
+	batch = append(batch, BatchElem{Method: "test_noArgsRets", Result: new(interface{})})
+	if err := c.BatchCall(batch); err == nil {
+		t.Fatal("expected error for too large batch")
+	}
+}
+
+func TestHTTPMethodTimeouts(t *testing.T) {
+	s, ts := newLimitedTestServer(t, &LimitConfig{
+		MethodTimeouts: map[string]time.Duration{"test_block": 50 * time.Millisecond, AnyMethod: time.Minute},
+	})
+	defer s.Stop()
+	defer ts.Close()
+
+	c, err := Dial(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var rpcErr Error
+	if err := c.Call(nil, "test_block"); !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != -32002 {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if err := c.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPResponseLimit(t *testing.T) {
+	s, ts := newLimitedTestServer(t, &LimitConfig{MaxResponseSize: 100})
+	defer s.Stop()
+	defer ts.Close()
+
+	c, err := Dial(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// Each response is about 40 bytes, the third one exceeding the limit and
+	// the last one not being executed
+	batch := make([]BatchElem, 4)
+	for i := range batch {
+		batch[i] = BatchElem{Method: "test_echo", Args: []interface{}{"hello world", i, nil}, Result: new(echoResult)}
+	}
+	if err := c.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	for i, elem := range batch {
+		var rpcErr Error
+		switch {
+		case i < 2 && elem.Error != nil:
+			t.Errorf("batch element %d: %v", i, elem.Error)
+		case i >= 2 && (!errors.As(elem.Error, &rpcErr) || rpcErr.ErrorCode() != -32003):
+			t.Errorf("batch element %d: expected response too large error, got %v", i, elem.Error)
+		}
+	}
+}
+
+func TestResponseBudget(t *testing.T) {
+	s, ts := newLimitedTestServer(t, &LimitConfig{MaxResponseSize: 100})
+	defer s.Stop()
+	defer ts.Close()
+
+	c, err := Dial(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// The first call fits in the budget, the last one consumes more than the
+	// budget left by the response of about 40 bytes to the echo
+	batch := []BatchElem{
+		{Method: "test_consumeBudget", Args: []interface{}{80}, Result: new(interface{})},
+		{Method: "test_echo", Args: []interface{}{"hello world", 0, nil}, Result: new(echoResult)},
+		{Method: "test_consumeBudget", Args: []interface{}{80}, Result: new(interface{})},
+	}
+	if err := c.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if batch[i].Error != nil {
+			t.Errorf("batch element %d: %v", i, batch[i].Error)
+		}
+	}
+	var rpcErr Error
+	if !errors.As(batch[2].Error, &rpcErr) || rpcErr.ErrorCode() != -32003 {
+		t.Errorf("batch element 2: expected response too large error, got %v", batch[2].Error)
+	}
+
+	// Calls without response size limit have no budget
+	if err := ResponseBudgetFromContext(context.Background()).Consume(1 << 30); err != nil {
+		t.Errorf("nil budget: %v", err)
+	}
+}
+
+// EOF
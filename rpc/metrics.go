@@ -37,3 +37,16 @@ func newRPCServingTimer(method string, valid bool) metrics.Timer {
 	m := fmt.Sprintf("rpc/duration/%s/%s", method, flag)
 	return metrics.GetOrRegisterTimer(m, nil)
 }
+
+var (
+	rpcLimitRateRejectedMeter  = metrics.NewRegisteredMeter("rpc/limits/rejected/rate", nil)
+	rpcLimitBatchRejectedMeter = metrics.NewRegisteredMeter("rpc/limits/rejected/batch", nil)
+	rpcLimitKeyRejectedMeter   = metrics.NewRegisteredMeter("rpc/limits/rejected/apikey", nil)
+
+	rpcLimitResponseRejectedMeter = metrics.NewRegisteredMeter("rpc/limits/rejected/response", nil)
+	rpcLimitTimeoutMeter          = metrics.NewRegisteredMeter("rpc/limits/timeout", nil)
+)
+
+func newRPCLimitRejectedMeter(method string) metrics.Meter {
+	return metrics.GetOrRegisterMeter("rpc/limits/rejected/rate/"+method, nil)
+}
@@ -0,0 +1,326 @@
+// stream.go
+
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// StreamContentType is the content type of JSON-RPC streams over HTTP/2. The
+// request body streams the messages of the client, and the response body those
+// of the server, as newline delimited JSON values.
+const StreamContentType = "application/x-ndjson"
+
+// maxStreamMessageSize is the max size of a message read from a stream, like
+// the max content length of HTTP requests.
+const maxStreamMessageSize = maxRequestContentLength
+
+var (
+	errStreamClosed          = errors.New("stream closed")
+	errStreamMessageTooLarge = errors.New("stream message too large")
+)
+
+// IsStreamRequest returns whether an HTTP request opens a JSON-RPC stream.
+func IsStreamRequest(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	mt, _, err := mime.ParseMediaType(r.Header.Get("content-type"))
+	return err == nil && mt == StreamContentType
+}
+
+// serveStream serves a JSON-RPC stream over an HTTP/2 request, in both
+// directions until either side closes it. Like websocket connections, streams
+// support batches, subscriptions and any number of concurrent calls, many
+// streams sharing the same HTTP/2 connection.
+func (s *Server) serveStream(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor < 2 {
+		http.Error(w, "JSON-RPC streams require HTTP/2", http.StatusHTTPVersionNotSupported)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	var apiKey string
+	if s.limiter != nil {
+		key, err := s.limiter.authorize(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		apiKey = key
+	}
+	w.Header().Set("content-type", StreamContentType)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	codec := newStreamServerCodec(r, w, flusher)
+	codec.info.HTTP.APIKey = apiKey
+	conn := codec.conn.(*streamServerConn)
+	go func() {
+		select {
+		case <-conn.aborted:
+			codec.close()
+		case <-codec.closed():
+		}
+	}()
+	s.ServeCodec(codec, 0)
+
+	// The response writer must not be used after the handler returns: wait for
+	// the write in progress, unless it timed out. Then the write is blocked by
+	// the client, and the stream is reset by aborting the handler.
+	select {
+	case conn.lock <- struct{}{}:
+		conn.finished = true
+		<-conn.lock
+	case <-conn.aborted:
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// streamServerConn is the server side of a JSON-RPC stream.
+//
+// A write blocked past its deadline by a client not reading the stream can't
+// be interrupted, the stream is aborted instead: the codec is closed and the
+// handler resets the stream, failing the write.
+type streamServerConn struct {
+	body io.ReadCloser
+
+	lock     chan struct{} // Held while writing
+	w        http.ResponseWriter
+	flusher  http.Flusher
+	deadline time.Time
+	finished bool // Whether the handler returned
+
+	aborted   chan struct{} // Closed when a write times out
+	abortOnce sync.Once
+}
+
+// Write writes and flushes a message, unless the handler returned: the response
+// writer must not be used after the handler returns.
+func (c *streamServerConn) Write(p []byte) (int, error) {
+	select {
+	case c.lock <- struct{}{}:
+	case <-c.aborted:
+		return 0, errStreamClosed
+	}
+	defer func() { <-c.lock }()
+
+	if c.finished {
+		return 0, errStreamClosed
+	}
+	if !c.deadline.IsZero() {
+		timer := time.AfterFunc(time.Until(c.deadline), c.abort)
+		defer timer.Stop()
+	}
+	n, err := c.w.Write(p)
+	if err == nil {
+		c.flusher.Flush()
+	}
+	return n, err
+}
+
+// abort aborts the stream after a write timed out.
+func (c *streamServerConn) abort() {
+	c.abortOnce.Do(func() { close(c.aborted) })
+}
+
+func (c *streamServerConn) Close() error {
+	return c.body.Close()
+}
+
+// SetWriteDeadline sets the deadline of the next writes, the codec setting it
+// before each message.
+func (c *streamServerConn) SetWriteDeadline(deadline time.Time) error {
+	select {
+	case c.lock <- struct{}{}:
+	case <-c.aborted:
+		return errStreamClosed
+	}
+	c.deadline = deadline
+	<-c.lock
+	return nil
+}
+
+// messageLimitReader limits the size of the messages decoded from a stream,
+// the limit being reset before each message.
+type messageLimitReader struct {
+	r    io.Reader
+	left int64
+}
+
+func (l *messageLimitReader) Read(p []byte) (int, error) {
+	if l.left <= 0 {
+		return 0, errStreamMessageTooLarge
+	}
+	if int64(len(p)) > l.left {
+		p = p[:l.left]
+	}
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	return n, err
+}
+
+// streamCodec is a JSON-RPC codec over an HTTP/2 stream.
+type streamCodec struct {
+	*jsonCodec
+	info PeerInfo
+}
+
+func newStreamServerCodec(r *http.Request, w http.ResponseWriter, flusher http.Flusher) *streamCodec {
+	conn := &streamServerConn{
+		body:    r.Body,
+		lock:    make(chan struct{}, 1),
+		w:       w,
+		flusher: flusher,
+		aborted: make(chan struct{}),
+	}
+	enc := json.NewEncoder(conn)
+	body := &messageLimitReader{r: r.Body}
+	dec := json.NewDecoder(body)
+	dec.UseNumber()
+	decode := func(v interface{}) error {
+		body.left = maxStreamMessageSize
+		return dec.Decode(v)
+	}
+
+	codec := &streamCodec{
+		jsonCodec: NewFuncCodec(conn, enc.Encode, decode).(*jsonCodec),
+		info:      PeerInfo{Transport: "http2", RemoteAddr: r.RemoteAddr},
+	}
+	codec.remote = r.RemoteAddr
+	codec.info.HTTP.Version = r.Proto
+	codec.info.HTTP.Host = r.Host
+	codec.info.HTTP.Origin = r.Header.Get("Origin")
+	codec.info.HTTP.UserAgent = r.Header.Get("User-Agent")
+	return codec
+}
+
+func (c *streamCodec) peerInfo() PeerInfo {
+	return c.info
+}
+
+// streamClientConn is the client side of a JSON-RPC stream.
+type streamClientConn struct {
+	body   *io.PipeWriter
+	resp   io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *streamClientConn) Close() error {
+	c.body.Close()
+	c.cancel()
+	return c.resp.Close()
+}
+
+func (c *streamClientConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// h2cTransport is the transport of the JSON-RPC streams over unencrypted
+// HTTP/2, shared so that the streams to a server share its connection.
+var h2cTransport = &http2.Transport{
+	AllowHTTP:          true,
+	DisableCompression: true,
+	DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+		return net.Dial(network, addr)
+	},
+}
+
+// h2Transport is the transport of the JSON-RPC streams over HTTP/2 with TLS.
+var h2Transport = &http2.Transport{DisableCompression: true}
+
+// DialStream creates a new RPC client streaming JSON-RPC messages to the server
+// at the given endpoint over a HTTP/2 request, like over a websocket connection.
+// The streams of all clients to the same server share one HTTP/2 connection,
+// over TLS for https endpoints, and unencrypted for http endpoints.
+//
+// The context is used for the initial connection establishment. It does not
+// affect subsequent interactions with the client.
+func DialStream(ctx context.Context, endpoint string) (*Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	transport := h2Transport
+	if u.Scheme == "http" {
+		transport = h2cTransport
+	}
+	return DialStreamWithTransport(ctx, endpoint, transport)
+}
+
+// DialStreamWithTransport creates a new RPC client streaming JSON-RPC messages
+// over a HTTP/2 request to the given endpoint, using the provided transport.
+func DialStreamWithTransport(ctx context.Context, endpoint string, transport http.RoundTripper) (*Client, error) {
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, err
+	}
+	return newClient(ctx, func(ctx context.Context) (ServerCodec, error) {
+		return dialStream(ctx, endpoint, transport)
+	})
+}
+
+func dialStream(ctx context.Context, endpoint string, transport http.RoundTripper) (ServerCodec, error) {
+	// The request lives as long as the stream, the dial context only bounding
+	// the wait for the response headers
+	streamCtx, cancel := context.WithCancel(context.Background())
+	body, pipe := io.Pipe()
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodPost, endpoint, body)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("accept", StreamContentType)
+	req.Header.Set("content-type", StreamContentType)
+
+	dialed := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-dialed:
+		}
+	}()
+	resp, err := transport.RoundTrip(req)
+	close(dialed)
+	if err != nil {
+		cancel()
+		pipe.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		defer cancel()
+		pipe.Close()
+
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: respBody}
+	}
+	conn := &streamClientConn{body: pipe, resp: resp.Body, cancel: cancel}
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+
+	codec := &streamCodec{
+		jsonCodec: NewFuncCodec(conn, json.NewEncoder(pipe).Encode, dec.Decode).(*jsonCodec),
+		info:      PeerInfo{Transport: "http2", RemoteAddr: endpoint},
+	}
+	codec.remote = endpoint
+	codec.info.HTTP.Version = resp.Proto
+	return codec, nil
+}
+
+// EOF
@@ -0,0 +1,175 @@
+// stream_test.go
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func newStreamTestServer() (*Server, *httptest.Server) {
+	s := newTestServer()
+	return s, httptest.NewServer(h2c.NewHandler(s, &http2.Server{}))
+}
+
+func TestStreamCalls(t *testing.T) {
+	s, ts := newStreamTestServer()
+	defer s.Stop()
+	defer ts.Close()
+
+	client, err := DialStream(context.Background(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// Concurrent calls are multiplexed over the stream
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var result echoResult
+			if err := client.Call(&result, "test_echo", "hello", i, &echoArgs{"world"}); err != nil {
+				t.Error(err)
+				return
+			}
+			if result.Int != i {
+				t.Errorf("result mismatch: have %d, want %d", result.Int, i)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var info PeerInfo
+	if err := client.Call(&info, "test_peerInfo"); err != nil {
+		t.Fatal(err)
+	}
+	if info.Transport != "http2" || info.HTTP.Version != "HTTP/2.0" {
+		t.Errorf("wrong peer info: transport %q, version %q", info.Transport, info.HTTP.Version)
+	}
+}
+
+func TestStreamSubscription(t *testing.T) {
+	s, ts := newStreamTestServer()
+	defer s.Stop()
+	defer ts.Close()
+
+	client, err := DialStream(context.Background(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var (
+		count = 10
+		nc    = make(chan int, count)
+	)
+	sub, err := client.Subscribe(context.Background(), "nftest", nc, "someSubscription", count, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	for i := 0; i < count; i++ {
+		select {
+		case n := <-nc:
+			if n != i {
+				t.Fatalf("wrong notification: have %d, want %d", n, i)
+			}
+		case err := <-sub.Err():
+			t.Fatal(err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for notification %d", i)
+		}
+	}
+}
+
+func TestStreamRequiresHTTP2(t *testing.T) {
+	s, ts := newStreamTestServer()
+	defer s.Stop()
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"test_echo","params":[]}`))
+	req.Header.Set("content-type", StreamContentType)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusHTTPVersionNotSupported {
+		t.Fatalf("wrong status: have %d, want %d", resp.StatusCode, http.StatusHTTPVersionNotSupported)
+	}
+}
+
+func TestStreamMessageLimit(t *testing.T) {
+	s, ts := newStreamTestServer()
+	defer s.Stop()
+	defer ts.Close()
+
+	client, err := DialStream(context.Background(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// Messages up to the limit are read, the stream is closed by larger ones
+	var result echoResult
+	if err := client.Call(&result, "test_echo", strings.Repeat("x", maxStreamMessageSize-200), 1, nil); err != nil {
+		t.Fatalf("message below the limit failed: %v", err)
+	}
+	if err := client.Call(&result, "test_echo", strings.Repeat("x", maxStreamMessageSize), 1, nil); err == nil {
+		t.Fatal("message above the limit succeeded")
+	}
+}
+
+// blockingResponseWriter is a response writer whose writes block until released.
+type blockingResponseWriter struct {
+	http.ResponseWriter
+	release chan struct{}
+}
+
+func (w *blockingResponseWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return 0, errStreamClosed
+}
+
+func (w *blockingResponseWriter) Flush() {}
+
+func TestStreamWriteTimeout(t *testing.T) {
+	w := &blockingResponseWriter{release: make(chan struct{})}
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(""))
+	codec := newStreamServerCodec(r, w, w)
+	conn := codec.conn.(*streamServerConn)
+
+	// A write blocked past its deadline aborts the stream
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	written := make(chan error, 1)
+	go func() {
+		written <- codec.writeJSON(ctx, map[string]int{"id": 1})
+	}()
+	select {
+	case <-conn.aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream not aborted")
+	}
+	// The next writes fail right away
+	if _, err := conn.Write([]byte("{}")); err != errStreamClosed {
+		t.Errorf("write after abort: have %v, want %v", err, errStreamClosed)
+	}
+	close(w.release)
+	if err := <-written; err == nil {
+		t.Error("blocked write succeeded")
+	}
+}
+
+// EOF
@@ -84,6 +84,10 @@ func (s *testService) PeerInfo(ctx context.Context) PeerInfo {
 	return PeerInfoFromContext(ctx)
 }
 
+func (s *testService) ConsumeBudget(ctx context.Context, size int) error {
+	return ResponseBudgetFromContext(ctx).Consume(size)
+}
+
 func (s *testService) Sleep(ctx context.Context, duration time.Duration) {
 	time.Sleep(duration)
 }
@@ -121,9 +121,10 @@ func (bn BlockNumber) Int64() int64 {
 }
 
 type BlockNumberOrHash struct {
-	BlockNumber      *BlockNumber `json:"blockNumber,omitempty"`
-	BlockHash        *common.Hash `json:"blockHash,omitempty"`
-	RequireCanonical bool         `json:"requireCanonical,omitempty"`
+	BlockNumber      *BlockNumber    `json:"blockNumber,omitempty"`
+	BlockHash        *common.Hash    `json:"blockHash,omitempty"`
+	RequireCanonical bool            `json:"requireCanonical,omitempty"`
+	BlockTimestamp   *hexutil.Uint64 `json:"blockTimestamp,omitempty"` // Latest canonical block at or before the timestamp
 }
 
 func (bnh *BlockNumberOrHash) UnmarshalJSON(data []byte) error {
@@ -134,9 +135,13 @@ func (bnh *BlockNumberOrHash) UnmarshalJSON(data []byte) error {
 		if e.BlockNumber != nil && e.BlockHash != nil {
 			return fmt.Errorf("cannot specify both BlockHash and BlockNumber, choose one or the other")
 		}
+		if e.BlockTimestamp != nil && (e.BlockNumber != nil || e.BlockHash != nil) {
+			return fmt.Errorf("cannot specify BlockTimestamp with BlockHash or BlockNumber, choose one or the other")
+		}
 		bnh.BlockNumber = e.BlockNumber
 		bnh.BlockHash = e.BlockHash
 		bnh.RequireCanonical = e.RequireCanonical
+		bnh.BlockTimestamp = e.BlockTimestamp
 		return nil
 	}
 	var input string
@@ -195,6 +200,9 @@ func (bnh *BlockNumberOrHash) String() string {
 	if bnh.BlockHash != nil {
 		return bnh.BlockHash.String()
 	}
+	if bnh.BlockTimestamp != nil {
+		return "@" + strconv.FormatUint(uint64(*bnh.BlockTimestamp), 10)
+	}
 	return "nil"
 }
 
@@ -205,6 +213,15 @@ func (bnh *BlockNumberOrHash) Hash() (common.Hash, bool) {
 	return common.Hash{}, false
 }
 
+// Timestamp returns the timestamp whose latest block at or before it is
+// selected, if any.
+func (bnh *BlockNumberOrHash) Timestamp() (uint64, bool) {
+	if bnh.BlockTimestamp != nil {
+		return uint64(*bnh.BlockTimestamp), true
+	}
+	return 0, false
+}
+
 func BlockNumberOrHashWithNumber(blockNr BlockNumber) BlockNumberOrHash {
 	return BlockNumberOrHash{
 		BlockNumber:      &blockNr,
@@ -221,6 +238,13 @@ func BlockNumberOrHashWithHash(hash common.Hash, canonical bool) BlockNumberOrHa
 	}
 }
 
+// BlockNumberOrHashWithTimestamp selects the latest canonical block with a
+// timestamp at or before the given one.
+func BlockNumberOrHashWithTimestamp(timestamp uint64) BlockNumberOrHash {
+	ts := hexutil.Uint64(timestamp)
+	return BlockNumberOrHash{BlockTimestamp: &ts}
+}
+
 // DecimalOrHex unmarshals a non-negative decimal or hex parameter into a uint64.
 type DecimalOrHex uint64
 
@@ -99,6 +99,8 @@ func TestBlockNumberOrHash_UnmarshalJSON(t *testing.T) {
 		23: {`{"blockNumber":"latest"}`, false, BlockNumberOrHashWithNumber(LatestBlockNumber)},
 		24: {`{"blockNumber":"earliest"}`, false, BlockNumberOrHashWithNumber(EarliestBlockNumber)},
 		25: {`{"blockNumber":"0x1", "blockHash":"0x0000000000000000000000000000000000000000000000000000000000000000"}`, true, BlockNumberOrHash{}},
+		26: {`{"blockTimestamp":"0x6543ab00"}`, false, BlockNumberOrHashWithTimestamp(0x6543ab00)},
+		27: {`{"blockTimestamp":"0x6543ab00", "blockNumber":"0x1"}`, true, BlockNumberOrHash{}},
 	}
 
 	for i, test := range tests {
@@ -116,9 +118,12 @@ func TestBlockNumberOrHash_UnmarshalJSON(t *testing.T) {
 		expectedHash, expectedHashOk := test.expected.Hash()
 		num, numOk := bnh.Number()
 		expectedNum, expectedNumOk := test.expected.Number()
+		ts, tsOk := bnh.Timestamp()
+		expectedTs, expectedTsOk := test.expected.Timestamp()
 		if bnh.RequireCanonical != test.expected.RequireCanonical ||
 			hash != expectedHash || hashOk != expectedHashOk ||
-			num != expectedNum || numOk != expectedNumOk {
+			num != expectedNum || numOk != expectedNumOk ||
+			ts != expectedTs || tsOk != expectedTsOk {
 			t.Errorf("Test %d got unexpected value, want %v, got %v", i, test.expected, bnh)
 		}
 	}
@@ -55,16 +55,32 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 		CheckOrigin:     wsHandshakeValidator(allowedOrigins),
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var apiKey string
+		if s.limiter != nil {
+			key, err := s.limiter.authorize(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			apiKey = key
+		}
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			log.Debug("WebSocket upgrade failed", "err", err)
 			return
 		}
 		codec := newWebsocketCodec(conn, r.Host, r.Header)
+		codec.(*websocketCodec).info.HTTP.APIKey = apiKey
 		s.ServeCodec(codec, 0)
 	})
 }
 
+// WebsocketOriginValidator returns the origin check of the WebSocket handler
+// accepting the given origins, for the services served next to the RPC server.
+func WebsocketOriginValidator(allowedOrigins []string) func(*http.Request) bool {
+	return wsHandshakeValidator(allowedOrigins)
+}
+
 // wsHandshakeValidator returns a handler that verifies the origin during the
 // websocket upgrade process. When a '*' is specified as an allowed origins all
 // connections are accepted.
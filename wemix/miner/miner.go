@@ -27,6 +27,7 @@ var (
 	AcquireMiningTokenFunc      func(height *big.Int, parentHash common.Hash) (bool, error)
 	ReleaseMiningTokenFunc      func(height *big.Int, hash, parentHash common.Hash) error
 	HasMiningTokenFunc          func() bool
+	GetMinerNodeFunc            func(height *big.Int, coinbase common.Address, nodeId []byte) (id []byte, name, enode string, err error)
 )
 
 func IsPartner(id string) bool {
@@ -144,4 +145,15 @@ func GetBlockBuildParameters(height *big.Int) (blockInterval int64, maxBaseFee,
 	}
 }
 
+// GetMinerNode returns the governance node that mined the block at given
+// height, identified by the block's minerNodeId or else by its coinbase.
+// It returns a nil id if the node isn't in governance.
+func GetMinerNode(height *big.Int, coinbase common.Address, nodeId []byte) (id []byte, name, enode string, err error) {
+	if GetMinerNodeFunc == nil {
+		return nil, "", "", ErrNotInitialized
+	} else {
+		return GetMinerNodeFunc(height, coinbase, nodeId)
+	}
+}
+
 // EOF
@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"fmt"
 	"math/big"
 	"sort"
 	"time"
@@ -85,6 +86,8 @@ func getCoinbaseEnodeCache(ctx context.Context, height *big.Int, gov *metclient.
 			Name:  string(name),
 			Enode: string(enode), // note that this is not in hex unlike wemixAdmin
 			Id:    idv4,
+			Ip:    string(ip),
+			Port:  int(port.Int64()),
 			Addr:  addr,
 		})
 		e.coinbase2enode[string(addr[:])] = enode
@@ -120,6 +123,36 @@ func enodeExists(ctx context.Context, height *big.Int, gov *metclient.RemoteCont
 	return ix >= 1, nil
 }
 
+// returns the id, name and enode url of the governance node at given
+// height-1 that mined the block at given height, from its node id if given,
+// otherwise from its coinbase
+func getMinerNode(height *big.Int, coinbase common.Address, nodeId []byte) ([]byte, string, string, error) {
+	if admin == nil {
+		return nil, "", "", wemixminer.ErrNotInitialized
+	}
+	ctx := context.Background()
+	num := new(big.Int).Sub(height, common.Big1)
+	_, gov, _, err := admin.getRegGovEnvContracts(ctx, num)
+	if err != nil {
+		return nil, "", "", err
+	}
+	e, err := getCoinbaseEnodeCache(ctx, num, gov)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if len(nodeId) == 0 {
+		if nodeId = e.coinbase2enode[string(coinbase[:])]; len(nodeId) == 0 {
+			return nil, "", "", nil
+		}
+	}
+	ix, ok := e.enode2index[string(nodeId)]
+	if !ok || ix < 1 || ix > len(e.nodes) {
+		return nil, "", "", nil
+	}
+	n := e.nodes[ix-1]
+	return nodeId, n.Name, fmt.Sprintf("enode://%x@%s:%d", nodeId, n.Ip, n.Port), nil
+}
+
 // returns wemix nodes at given height
 func getNodesAt(height *big.Int) ([]*wemixNode, error) {
 	ctx := context.Background()